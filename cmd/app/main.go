@@ -2,6 +2,7 @@ package main
 
 import (
 	_ "github.com/finlleyl/cp_database/docs"
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/config"
 	"github.com/finlleyl/cp_database/internal/domain"
 	"github.com/finlleyl/cp_database/internal/httpserver"
@@ -34,6 +35,7 @@ func main() {
 		logger.Module,
 		config.Module,
 		repository.Module,
+		auth.Module,
 
 		domain.Module,
 
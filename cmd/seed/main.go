@@ -185,12 +185,13 @@ FROM generate_series(1, $1) gs
 
 	// accounts (1 master account for each master/both; 1 investor account for each investor/both)
 	if _, err := tx.Exec(ctx, `
-INSERT INTO accounts(user_id, name, account_type, currency, created_at, updated_at)
+INSERT INTO accounts(user_id, name, account_type, currency, equity, created_at, updated_at)
 SELECT
   u.id,
   'ACC-' || u.id::text || '-M',
   'master',
   (ARRAY['USD','EUR','RUB'])[1 + floor(random()*3)::int],
+  round((1000 + random()*99000)::numeric, 2),
   u.created_at + (random() * interval '30 days'),
   now()
 FROM users u
@@ -200,12 +201,13 @@ WHERE u.role IN ('master','both')
 		os.Exit(1)
 	}
 	if _, err := tx.Exec(ctx, `
-INSERT INTO accounts(user_id, name, account_type, currency, created_at, updated_at)
+INSERT INTO accounts(user_id, name, account_type, currency, equity, created_at, updated_at)
 SELECT
   u.id,
   'ACC-' || u.id::text || '-I',
   'investor',
   (ARRAY['USD','EUR','RUB'])[1 + floor(random()*3)::int],
+  round((1000 + random()*99000)::numeric, 2),
   u.created_at + (random() * interval '30 days'),
   now()
 FROM users u
@@ -279,6 +281,7 @@ SELECT
 FROM generate_series(1, $1) gs
 JOIN LATERAL (SELECT * FROM investor_accounts ORDER BY random() LIMIT 1) ia ON true
 JOIN LATERAL (SELECT * FROM offs ORDER BY random() LIMIT 1) o ON true
+ON CONFLICT DO NOTHING
 `, opt.subscriptions); err != nil {
 		fmt.Fprintf(os.Stderr, "insert subscriptions: %v\n", err)
 		os.Exit(1)
@@ -371,24 +374,48 @@ SELECT
 FROM generate_series(1, $1) gs
 JOIN LATERAL (SELECT * FROM trs ORDER BY random() LIMIT 1) t ON true
 JOIN LATERAL (SELECT * FROM subs ORDER BY random() LIMIT 1) s ON true
+ON CONFLICT (trade_id, subscription_id) DO NOTHING
 `, opt.copiedTrades); err != nil {
 		fmt.Fprintf(os.Stderr, "insert copied_trades: %v\n", err)
 		os.Exit(1)
 	}
 
-	// commissions
+	// fx rates: commissions between accounts in different currencies are converted when posted
+	// to the ledger, so every commission date needs a rate
+	if _, err := tx.Exec(ctx, `
+INSERT INTO fx_rates(base_currency, quote_currency, rate_date, rate)
+SELECT p.base, p.quote, d::date, round((p.rate * (1 + (random() - 0.5) * 0.04))::numeric, 6)
+FROM (VALUES ('EUR', 'USD', 1.08), ('USD', 'RUB', 90.0)) AS p(base, quote, rate)
+CROSS JOIN generate_series(date_trunc('day', now()) - interval '400 days', date_trunc('day', now()), interval '1 day') d
+ON CONFLICT DO NOTHING
+`); err != nil {
+		fmt.Fprintf(os.Stderr, "insert fx rates: %v\n", err)
+		os.Exit(1)
+	}
+
+	// commissions: registration is charged once per subscription, periodic fees once per
+	// (subscription, type, period start), so colliding rows are skipped
 	if _, err := tx.Exec(ctx, `
-WITH subs AS (SELECT id FROM subscriptions)
+WITH subs AS (SELECT id FROM subscriptions),
+gen AS (
+  SELECT
+    s.id AS subscription_id,
+    (ARRAY['performance','management','registration'])[1 + floor(random()*3)::int]::commission_type AS type,
+    round((random()*200)::numeric, 2) AS amount,
+    date_trunc('day', now()) - (floor(random()*12)::int * interval '30 days') AS period_start
+  FROM generate_series(1, $1) gs
+  JOIN LATERAL (SELECT * FROM subs ORDER BY random() LIMIT 1) s ON true
+)
 INSERT INTO commissions(subscription_id, type, amount, period_from, period_to, created_at)
 SELECT
-  s.id,
-  (ARRAY['performance','management','registration'])[1 + floor(random()*3)::int]::commission_type,
-  round((random()*200)::numeric, 2),
-  now() - (floor(random()*12)::int * interval '30 days'),
-  now(),
-  now() - (random() * interval '180 days')
-FROM generate_series(1, $1) gs
-JOIN LATERAL (SELECT * FROM subs ORDER BY random() LIMIT 1) s ON true
+  subscription_id,
+  type,
+  amount,
+  period_start,
+  CASE WHEN type = 'registration' THEN period_start ELSE period_start + interval '30 days' END,
+  LEAST(CASE WHEN type = 'registration' THEN period_start ELSE period_start + interval '30 days' END, now())
+FROM gen
+ON CONFLICT DO NOTHING
 `, opt.commissions); err != nil {
 		fmt.Fprintf(os.Stderr, "insert commissions: %v\n", err)
 		os.Exit(1)
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+var (
+	ErrUnauthorized = common.NewError(http.StatusUnauthorized, "unauthorized", "authentication required")
+	ErrForbidden    = common.NewError(http.StatusForbidden, "forbidden", "operation is not allowed for current user")
+)
+
+type actorKey struct{}
+
+// Actor описывает пользователя, от имени которого выполняется запрос
+type Actor struct {
+	UserID int64
+	Role   common.UserRole
+	System bool
+}
+
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) (*Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(*Actor)
+	return actor, ok && actor != nil
+}
+
+// WithSystemActor помечает контекст фоновых задач, которым разрешены все операции
+func WithSystemActor(ctx context.Context) context.Context {
+	return WithActor(ctx, &Actor{System: true})
+}
+
+func (a *Actor) HasRole(role common.UserRole) bool {
+	if a.System || a.Role == role {
+		return true
+	}
+	// Роль both объединяет мастера и инвестора, но не даёт прав администратора
+	return a.Role == common.UserRoleBoth && (role == common.UserRoleMaster || role == common.UserRoleInvestor)
+}
+
+// RequireRole проверяет, что запрос выполняет аутентифицированный пользователь с нужной ролью
+func RequireRole(ctx context.Context, role common.UserRole) (*Actor, error) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if !actor.HasRole(role) {
+		return nil, ErrForbidden
+	}
+	return actor, nil
+}
+
+// RequireUser проверяет, что запрос выполняет владелец ресурса
+func RequireUser(ctx context.Context, userID int64) (*Actor, error) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if !actor.System && actor.UserID != userID {
+		return nil, ErrForbidden
+	}
+	return actor, nil
+}
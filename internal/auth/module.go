@@ -0,0 +1,7 @@
+package auth
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Provide(NewTokenManager)
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/config"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+var (
+	errMalformedToken   = errors.New("malformed token")
+	errInvalidSignature = errors.New("invalid token signature")
+	errTokenExpired     = errors.New("token expired")
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type Claims struct {
+	Subject   string          `json:"sub"`
+	Role      common.UserRole `json:"role"`
+	Issuer    string          `json:"iss,omitempty"`
+	IssuedAt  int64           `json:"iat"`
+	ExpiresAt int64           `json:"exp"`
+}
+
+// TokenManager выпускает и проверяет JWT, подписанные локальным ключом из конфигурации
+type TokenManager struct {
+	algorithm  string
+	secret     []byte
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+	issuer     string
+	ttl        time.Duration
+}
+
+func NewTokenManager(cfg *config.Config) (*TokenManager, error) {
+	tm := &TokenManager{
+		algorithm: strings.ToUpper(cfg.JWT_ALGORITHM),
+		issuer:    cfg.JWT_ISSUER,
+		ttl:       cfg.JWT_TTL,
+	}
+	if tm.ttl <= 0 {
+		tm.ttl = 24 * time.Hour
+	}
+
+	switch tm.algorithm {
+	case AlgorithmHS256:
+		if cfg.JWT_SECRET == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for %s", AlgorithmHS256)
+		}
+		tm.secret = []byte(cfg.JWT_SECRET)
+	case AlgorithmRS256:
+		if cfg.JWT_PRIVATE_KEY_PATH != "" {
+			key, err := loadPrivateKey(cfg.JWT_PRIVATE_KEY_PATH)
+			if err != nil {
+				return nil, err
+			}
+			tm.privateKey = key
+			tm.publicKey = &key.PublicKey
+		}
+		if cfg.JWT_PUBLIC_KEY_PATH != "" {
+			key, err := loadPublicKey(cfg.JWT_PUBLIC_KEY_PATH)
+			if err != nil {
+				return nil, err
+			}
+			tm.publicKey = key
+		}
+		if tm.publicKey == nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_PATH or JWT_PUBLIC_KEY_PATH is required for %s", AlgorithmRS256)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm: %q", cfg.JWT_ALGORITHM)
+	}
+
+	return tm, nil
+}
+
+func (tm *TokenManager) Issue(userID int64, role common.UserRole) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(tm.ttl)
+
+	headerJSON, err := json.Marshal(header{Alg: tm.algorithm, Typ: "JWT"})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal header: %w", err)
+	}
+	claimsJSON, err := json.Marshal(Claims{
+		Subject:   strconv.FormatInt(userID, 10),
+		Role:      role,
+		Issuer:    tm.issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal claims: %w", err)
+	}
+
+	signingInput := encodeSegment(headerJSON) + "." + encodeSegment(claimsJSON)
+	signature, err := tm.sign([]byte(signingInput))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signingInput + "." + encodeSegment(signature), expiresAt, nil
+}
+
+func (tm *TokenManager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedToken
+	}
+
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, errMalformedToken
+	}
+	if h.Alg != tm.algorithm {
+		return nil, fmt.Errorf("unexpected signing algorithm: %q", h.Alg)
+	}
+
+	signature, err := decodeSegment(parts[2])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	if err := tm.verify([]byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claimsJSON, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, errMalformedToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, errMalformedToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errTokenExpired
+	}
+	if tm.issuer != "" && claims.Issuer != tm.issuer {
+		return nil, fmt.Errorf("unexpected token issuer: %q", claims.Issuer)
+	}
+
+	return &claims, nil
+}
+
+func (c *Claims) UserID() (int64, error) {
+	return strconv.ParseInt(c.Subject, 10, 64)
+}
+
+func (tm *TokenManager) sign(input []byte) ([]byte, error) {
+	switch tm.algorithm {
+	case AlgorithmHS256:
+		mac := hmac.New(sha256.New, tm.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case AlgorithmRS256:
+		if tm.privateKey == nil {
+			return nil, errors.New("private key is not configured")
+		}
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, tm.privateKey, crypto.SHA256, digest[:])
+	}
+	return nil, fmt.Errorf("unsupported JWT algorithm: %q", tm.algorithm)
+}
+
+func (tm *TokenManager) verify(input, signature []byte) error {
+	switch tm.algorithm {
+	case AlgorithmHS256:
+		expected, _ := tm.sign(input)
+		if !hmac.Equal(expected, signature) {
+			return errInvalidSignature
+		}
+		return nil
+	case AlgorithmRS256:
+		digest := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(tm.publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return errInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported JWT algorithm: %q", tm.algorithm)
+}
+
+func encodeSegment(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(segment)
+}
+
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 	"go.uber.org/fx"
 )
@@ -10,6 +12,13 @@ type Config struct {
 	POSTGRES_DB       string `env:"POSTGRES_DB" default:"postgres"`
 	POSTGRES_USER     string `env:"POSTGRES_USER" default:"postgres"`
 	POSTGRES_PASSWORD string `env:"POSTGRES_PASSWORD" default:"postgres"`
+
+	JWT_ALGORITHM        string        `env:"JWT_ALGORITHM" default:"HS256"`
+	JWT_SECRET           string        `env:"JWT_SECRET"`
+	JWT_PRIVATE_KEY_PATH string        `env:"JWT_PRIVATE_KEY_PATH"`
+	JWT_PUBLIC_KEY_PATH  string        `env:"JWT_PUBLIC_KEY_PATH"`
+	JWT_ISSUER           string        `env:"JWT_ISSUER" default:"cp_database"`
+	JWT_TTL              time.Duration `env:"JWT_TTL" default:"24h"`
 }
 
 func loadConfig() (*Config, error) {
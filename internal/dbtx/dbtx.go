@@ -0,0 +1,188 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Querier — общий набор методов *sqlx.DB и *sqlx.Tx, которым пользуются репозитории
+type Querier interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+}
+
+// Переменные транзакции, которые читает fn_audit_trigger
+const (
+	SettingUserID       = "app.current_user_id"
+	SettingRequestIP    = "app.request_ip"
+	SettingUserAgent    = "app.user_agent"
+	SettingSource       = "app.source"
+	SettingStatusReason = "app.status_reason"
+	SettingRestoredFrom = "app.restored_from_id"
+)
+
+type txKey struct{}
+
+type settingsKey struct{}
+
+type requestTx struct {
+	tx          *sqlx.Tx
+	afterCommit []func()
+}
+
+// WithTx привязывает транзакцию к контексту: все репозитории, получившие этот
+// контекст, выполняют запросы внутри неё
+func WithTx(ctx context.Context, tx *sqlx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, &requestTx{tx: tx})
+}
+
+func fromContext(ctx context.Context) (*requestTx, bool) {
+	rt, ok := ctx.Value(txKey{}).(*requestTx)
+	return rt, ok && rt != nil
+}
+
+// Conn возвращает транзакцию запроса, если она есть, иначе пул соединений
+func Conn(ctx context.Context, db *sqlx.DB) Querier {
+	if rt, ok := fromContext(ctx); ok {
+		return rt.tx
+	}
+	return db
+}
+
+// AfterCommit откладывает fn до успешного коммита транзакции запроса.
+// Без транзакции в контексте fn выполняется сразу.
+func AfterCommit(ctx context.Context, fn func()) {
+	if rt, ok := fromContext(ctx); ok {
+		rt.afterCommit = append(rt.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// RunAfterCommit выполняет отложенные через AfterCommit функции
+func RunAfterCommit(ctx context.Context) {
+	rt, ok := fromContext(ctx)
+	if !ok {
+		return
+	}
+	callbacks := rt.afterCommit
+	rt.afterCommit = nil
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// WithSettings задаёт переменные, которые Begin выставит в каждой новой транзакции,
+// открытой вне транзакции запроса (фоновые воркеры, импорт)
+func WithSettings(ctx context.Context, settings map[string]string) context.Context {
+	merged := make(map[string]string)
+	if parent, ok := ctx.Value(settingsKey{}).(map[string]string); ok {
+		for name, value := range parent {
+			merged[name] = value
+		}
+	}
+	for name, value := range settings {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, settingsKey{}, merged)
+}
+
+// SetLocal выставляет переменную до конца текущей транзакции
+func SetLocal(ctx context.Context, q Querier, name, value string) error {
+	if _, err := q.ExecContext(ctx, "SELECT set_config($1, $2, true)", name, value); err != nil {
+		return fmt.Errorf("set %s: %w", name, err)
+	}
+	return nil
+}
+
+var savepointSeq atomic.Uint64
+
+// Tx — транзакция репозитория. Внутри транзакции запроса она становится
+// точкой сохранения, чтобы Commit/Rollback не завершали внешнюю транзакцию.
+type Tx struct {
+	*sqlx.Tx
+	ctx       context.Context
+	savepoint string
+	done      bool
+}
+
+func Begin(ctx context.Context, db *sqlx.DB) (*Tx, error) {
+	rt, ok := fromContext(ctx)
+	if !ok {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		settings, _ := ctx.Value(settingsKey{}).(map[string]string)
+		for name, value := range settings {
+			if err := SetLocal(ctx, tx, name, value); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+		return &Tx{Tx: tx, ctx: ctx}, nil
+	}
+
+	name := fmt.Sprintf("repo_tx_%d", savepointSeq.Add(1))
+	if _, err := rt.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, fmt.Errorf("create savepoint: %w", err)
+	}
+	return &Tx{Tx: rt.tx, ctx: ctx, savepoint: name}, nil
+}
+
+func (t *Tx) Commit() error {
+	if t.savepoint == "" {
+		return t.Tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "RELEASE SAVEPOINT "+t.savepoint)
+	return err
+}
+
+func (t *Tx) Rollback() error {
+	if t.savepoint == "" {
+		return t.Tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.Tx.ExecContext(t.ctx, "ROLLBACK TO SAVEPOINT "+t.savepoint)
+	return err
+}
+
+// RunInTx выполняет fn в транзакции, привязанной к контексту: репозитории, получившие
+// контекст fn, работают внутри неё, и ошибка fn откатывает все их изменения. Внутри
+// транзакции запроса fn выполняется в ней без новой транзакции.
+func RunInTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := fromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := Begin(ctx, db)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	txCtx := WithTx(ctx, tx.Tx)
+	if err := fn(txCtx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	RunAfterCommit(txCtx)
+	return nil
+}
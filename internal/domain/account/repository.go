@@ -7,6 +7,7 @@ import (
 	"math"
 	"strings"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -32,17 +33,18 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 
 func (r *repository) Create(ctx context.Context, req *CreateAccountRequest) (*Account, error) {
 	query := `
-		INSERT INTO accounts (user_id, name, account_type, currency)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, name, account_type, currency, created_at, updated_at
+		INSERT INTO accounts (user_id, name, account_type, currency, equity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, name, account_type, currency, equity, created_at, updated_at
 	`
 
 	var account Account
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.UserID,
 		req.Name,
 		req.AccountType,
 		req.Currency,
+		req.Equity,
 	).StructScan(&account)
 	if err != nil {
 		r.logger.Error("Failed to create account",
@@ -60,13 +62,13 @@ func (r *repository) Create(ctx context.Context, req *CreateAccountRequest) (*Ac
 
 func (r *repository) GetByID(ctx context.Context, id int64) (*Account, error) {
 	query := `
-		SELECT id, user_id, name, account_type, currency, created_at, updated_at
+		SELECT id, user_id, name, account_type, currency, equity, created_at, updated_at
 		FROM accounts
 		WHERE id = $1
 	`
 
 	var account Account
-	err := r.db.GetContext(ctx, &account, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &account, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -108,14 +110,14 @@ func (r *repository) List(ctx context.Context, filter *AccountFilter) (*common.P
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM accounts %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count accounts", zap.Error(err))
 		return nil, fmt.Errorf("count accounts: %w", err)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, user_id, name, account_type, currency, created_at, updated_at
+		SELECT id, user_id, name, account_type, currency, equity, created_at, updated_at
 		FROM accounts
 		%s
 		ORDER BY created_at DESC
@@ -125,7 +127,7 @@ func (r *repository) List(ctx context.Context, filter *AccountFilter) (*common.P
 	args = append(args, filter.Limit, filter.Offset)
 
 	var accounts []Account
-	err = r.db.SelectContext(ctx, &accounts, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &accounts, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list accounts", zap.Error(err))
 		return nil, fmt.Errorf("list accounts: %w", err)
@@ -165,6 +167,12 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateAccountReq
 		argIndex++
 	}
 
+	if req.Equity != nil {
+		setClauses = append(setClauses, fmt.Sprintf("equity = $%d", argIndex))
+		args = append(args, *req.Equity)
+		argIndex++
+	}
+
 	if len(setClauses) == 0 {
 		return r.GetByID(ctx, id)
 	}
@@ -176,11 +184,11 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateAccountReq
 		UPDATE accounts
 		SET %s
 		WHERE id = $%d
-		RETURNING id, user_id, name, account_type, currency, created_at, updated_at
+		RETURNING id, user_id, name, account_type, currency, equity, created_at, updated_at
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var account Account
-	err := r.db.QueryRowxContext(ctx, query, args...).StructScan(&account)
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query, args...).StructScan(&account)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("account not found: %d", id)
@@ -199,7 +207,7 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateAccountReq
 func (r *repository) Delete(ctx context.Context, id int64) error {
 
 	var strategyCount int
-	err := r.db.GetContext(ctx, &strategyCount,
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &strategyCount,
 		"SELECT COUNT(*) FROM strategies WHERE master_account_id = $1", id)
 	if err != nil {
 		r.logger.Error("Failed to check account dependencies",
@@ -214,7 +222,7 @@ func (r *repository) Delete(ctx context.Context, id int64) error {
 
 	query := `DELETE FROM accounts WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		r.logger.Error("Failed to delete account",
 			zap.Int64("id", id),
@@ -234,14 +242,14 @@ func (r *repository) Delete(ctx context.Context, id int64) error {
 
 func (r *repository) GetByUserID(ctx context.Context, userID int64) ([]*Account, error) {
 	query := `
-		SELECT id, user_id, name, account_type, currency, created_at, updated_at
+		SELECT id, user_id, name, account_type, currency, equity, created_at, updated_at
 		FROM accounts
 		WHERE user_id = $1
 		ORDER BY created_at DESC
 	`
 
 	var accounts []*Account
-	err := r.db.SelectContext(ctx, &accounts, query, userID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &accounts, query, userID)
 	if err != nil {
 		r.logger.Error("Failed to get accounts by user ID",
 			zap.Int64("user_id", userID),
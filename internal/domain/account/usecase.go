@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"go.uber.org/zap"
 )
@@ -18,13 +17,12 @@ type UseCase interface {
 }
 
 type useCase struct {
-	repo      Repository
-	auditRepo audit.Repository
-	logger    *zap.Logger
+	repo   Repository
+	logger *zap.Logger
 }
 
-func NewUseCase(repo Repository, auditRepo audit.Repository, logger *zap.Logger) UseCase {
-	return &useCase{repo: repo, auditRepo: auditRepo, logger: logger}
+func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, logger: logger}
 }
 
 func (u *useCase) Create(ctx context.Context, req *CreateAccountRequest) (*Account, error) {
@@ -38,13 +36,6 @@ func (u *useCase) Create(ctx context.Context, req *CreateAccountRequest) (*Accou
 		return nil, fmt.Errorf("create account: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeAccount,
-		EntityID:   account.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   account,
-	})
-
 	return account, nil
 }
 
@@ -64,24 +55,11 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateAccountReques
 
 	u.logger.Info("UseCase: Updating account", zap.Int64("id", id))
 
-	oldAccount, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get account: %w", err)
-	}
-
 	account, err := u.repo.Update(ctx, id, req)
 	if err != nil {
 		return nil, fmt.Errorf("update account: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeAccount,
-		EntityID:   id,
-		Action:     audit.AuditActionUpdate,
-		OldValue:   oldAccount,
-		NewValue:   account,
-	})
-
 	return account, nil
 }
 
@@ -89,21 +67,9 @@ func (u *useCase) Delete(ctx context.Context, id int64) error {
 
 	u.logger.Info("UseCase: Deleting account", zap.Int64("id", id))
 
-	oldAccount, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("get account: %w", err)
-	}
-
 	if err := u.repo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("delete account: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeAccount,
-		EntityID:   id,
-		Action:     audit.AuditActionDelete,
-		OldValue:   oldAccount,
-	})
-
 	return nil
 }
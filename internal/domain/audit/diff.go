@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+func decodeRow(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return nil, fmt.Errorf("decode audit row: %w", err)
+	}
+	return row, nil
+}
+
+// parseDiff разворачивает колонку diff, которую заполняет fn_audit_diff, в список
+// изменённых полей, упорядоченный по имени поля
+func parseDiff(raw json.RawMessage) ([]FieldChange, error) {
+	changes := make([]FieldChange, 0)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return changes, nil
+	}
+
+	var fields map[string]struct {
+		Old json.RawMessage `json:"old"`
+		New json.RawMessage `json:"new"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("decode audit diff: %w", err)
+	}
+
+	for field, change := range fields {
+		changes = append(changes, FieldChange{Field: field, Old: change.Old, New: change.New})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// replay последовательно применяет записи журнала (от старых к новым) и
+// возвращает итоговое состояние строки; nil — строка удалена или ещё не создана
+func replay(logs []*AuditLog) (map[string]json.RawMessage, error) {
+	var state map[string]json.RawMessage
+	for _, entry := range logs {
+		switch entry.Operation {
+		case common.AuditOperationDelete:
+			state = nil
+		default:
+			row, err := decodeRow(entry.NewRow)
+			if err != nil {
+				return nil, err
+			}
+			if state == nil {
+				state = make(map[string]json.RawMessage, len(row))
+			}
+			for field, value := range row {
+				state[field] = value
+			}
+		}
+	}
+	return state, nil
+}
@@ -2,11 +2,22 @@ package audit
 
 import (
 	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+var (
+	ErrInvalidEntityName = common.NewError(http.StatusBadRequest, "invalid_entity_name", "unknown audit entity name")
+	ErrNoAuditHistory    = common.NewError(http.StatusNotFound, "no_audit_history", "no audit history for entity at requested time")
+
+	ErrAuditEntryNotFound      = common.NewError(http.StatusNotFound, "audit_entry_not_found", "audit entry not found for entity")
+	ErrNothingToRestore        = common.NewError(http.StatusConflict, "nothing_to_restore", "audit entry has no previous state to restore")
+	ErrRestoreReferenceMissing = common.NewError(http.StatusConflict, "restore_reference_missing", "referenced entities no longer exist")
+	ErrRestoreConflict         = common.NewError(http.StatusConflict, "restore_conflict", "restored state conflicts with existing data")
+)
+
 type AuditLog struct {
 	ID         int64                 `json:"id" db:"id"`
 	EntityName string                `json:"entity_name" db:"entity_name"`
@@ -16,8 +27,28 @@ type AuditLog struct {
 	ChangedAt  time.Time             `json:"changed_at" db:"changed_at"`
 	OldRow     json.RawMessage       `json:"old_row,omitempty" db:"old_row" swaggertype:"object"`
 	NewRow     json.RawMessage       `json:"new_row,omitempty" db:"new_row" swaggertype:"object"`
+	IPAddress  *string               `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent  *string               `json:"user_agent,omitempty" db:"user_agent"`
+	Source     Source                `json:"source" db:"source"`
+	// Diff — изменённые поля в виде {"field": {"old": ..., "new": ...}}
+	Diff         json.RawMessage `json:"diff,omitempty" db:"diff" swaggertype:"object"`
+	StatusReason *string         `json:"status_reason,omitempty" db:"status_reason"`
+	// RestoredFromID — запись журнала, из которой восстановлено состояние (для source=restore)
+	RestoredFromID *int64 `json:"restored_from_id,omitempty" db:"restored_from_id"`
 }
 
+// Source — откуда пришло изменение; записывается триггером из app.source
+type Source string
+
+const (
+	SourceAPI     Source = "api"
+	SourceWorker  Source = "worker"
+	SourceImport  Source = "import"
+	SourceRestore Source = "restore"
+	SourceSystem  Source = "system"
+	SourceLegacy  Source = "legacy"
+)
+
 const (
 	EntityNameUsers         = "users"
 	EntityNameAccounts      = "accounts"
@@ -27,45 +58,52 @@ const (
 	EntityNameTrades        = "trades"
 )
 
-type EntityType string
+func isValidEntityName(entityName string) bool {
+	switch entityName {
+	case EntityNameUsers, EntityNameAccounts, EntityNameStrategies,
+		EntityNameOffers, EntityNameSubscriptions, EntityNameTrades:
+		return true
+	}
+	return false
+}
 
-const (
-	EntityTypeUser         EntityType = "users"
-	EntityTypeAccount      EntityType = "accounts"
-	EntityTypeStrategy     EntityType = "strategies"
-	EntityTypeOffer        EntityType = "offers"
-	EntityTypeSubscription EntityType = "subscriptions"
-	EntityTypeTrade        EntityType = "trades"
-)
+type AuditFilter struct {
+	EntityName string                `form:"entity_name" binding:"omitempty,oneof=users accounts strategies offers subscriptions trades"`
+	EntityPK   string                `form:"entity_pk"`
+	Operation  common.AuditOperation `form:"operation" binding:"omitempty,oneof=insert update delete"`
+	ChangedBy  *int64                `form:"changed_by"`
+	Source     Source                `form:"source" binding:"omitempty,oneof=api worker import restore system legacy"`
+	common.TimeRange
+	common.Pagination
+}
 
-type AuditAction string
+type ExportFormat string
 
 const (
-	AuditActionCreate       AuditAction = "insert"
-	AuditActionUpdate       AuditAction = "update"
-	AuditActionDelete       AuditAction = "delete"
-	AuditActionStatusChange AuditAction = "update"
+	ExportFormatCSV    ExportFormat = "csv"
+	ExportFormatNDJSON ExportFormat = "ndjson"
 )
 
-type AuditCreateRequest struct {
-	EntityType EntityType
-	EntityID   int64
-	Action     AuditAction
-	UserID     *int64
-	OldValue   interface{}
-	NewValue   interface{}
-	Changes    interface{}
-	IPAddress  string
-	UserAgent  string
-}
-
-type AuditFilter struct {
+// AuditExportRequest — фильтры как у списка, но без пагинации: выгружается вся выборка
+type AuditExportRequest struct {
+	Format     ExportFormat          `form:"format" binding:"required,oneof=csv ndjson"`
 	EntityName string                `form:"entity_name" binding:"omitempty,oneof=users accounts strategies offers subscriptions trades"`
 	EntityPK   string                `form:"entity_pk"`
 	Operation  common.AuditOperation `form:"operation" binding:"omitempty,oneof=insert update delete"`
 	ChangedBy  *int64                `form:"changed_by"`
+	Source     Source                `form:"source" binding:"omitempty,oneof=api worker import restore system legacy"`
 	common.TimeRange
-	common.Pagination
+}
+
+func (r *AuditExportRequest) Filter() *AuditFilter {
+	return &AuditFilter{
+		EntityName: r.EntityName,
+		EntityPK:   r.EntityPK,
+		Operation:  r.Operation,
+		ChangedBy:  r.ChangedBy,
+		Source:     r.Source,
+		TimeRange:  r.TimeRange,
+	}
 }
 
 type AuditStats struct {
@@ -79,6 +117,60 @@ type AuditStatsFilter struct {
 	common.TimeRange
 }
 
+// FieldChange — изменение одного поля между двумя версиями строки
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old" swaggertype:"object"`
+	New   json.RawMessage `json:"new" swaggertype:"object"`
+}
+
+// AuditDiffEntry — запись журнала с изменениями полей из колонки diff
+type AuditDiffEntry struct {
+	AuditID      int64                 `json:"audit_id"`
+	Operation    common.AuditOperation `json:"operation"`
+	ChangedBy    *int64                `json:"changed_by,omitempty"`
+	ChangedAt    time.Time             `json:"changed_at"`
+	Source       Source                `json:"source"`
+	StatusReason *string               `json:"status_reason,omitempty"`
+	Changes      []FieldChange         `json:"changes"`
+}
+
+type AsOfRequest struct {
+	At time.Time `form:"at" binding:"required"`
+}
+
+// EntityStateResponse — состояние сущности на момент времени, восстановленное по audit_log
+type EntityStateResponse struct {
+	EntityName string          `json:"entity_name"`
+	EntityPK   string          `json:"entity_pk"`
+	At         time.Time       `json:"at"`
+	Exists     bool            `json:"exists"`
+	State      json.RawMessage `json:"state" swaggertype:"object"`
+	// LastAuditID и LastChangedAt указывают на последнюю запись журнала до момента At
+	LastAuditID   int64     `json:"last_audit_id"`
+	LastChangedAt time.Time `json:"last_changed_at"`
+}
+
+type RestoreRequest struct {
+	AuditID int64 `json:"audit_id" binding:"required"`
+}
+
+// MissingReference — внешний ключ восстанавливаемой строки, который больше ни на что не указывает
+type MissingReference struct {
+	Column          string          `json:"column" db:"column_name"`
+	ReferencedTable string          `json:"referenced_table" db:"ref_table"`
+	ReferencedField string          `json:"referenced_column" db:"ref_column"`
+	Value           json.RawMessage `json:"value" swaggertype:"object"`
+}
+
+type RestoreResponse struct {
+	EntityName          string                `json:"entity_name"`
+	EntityPK            string                `json:"entity_pk"`
+	RestoredFromAuditID int64                 `json:"restored_from_audit_id"`
+	Operation           common.AuditOperation `json:"operation"`
+	State               json.RawMessage       `json:"state" swaggertype:"object"`
+}
+
 // AuditListResponse представляет пагинированный ответ со списком аудит-логов
 type AuditListResponse struct {
 	Data       []AuditLog `json:"data"`
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportBatchSize — сколько строк читается из курсора и отправляется клиенту за раз
+const exportBatchSize = 500
+
+// ExportSink принимает выгрузку; Flush вызывается после каждой пачки строк,
+// чтобы клиент получал данные по мере чтения курсора
+type ExportSink interface {
+	io.Writer
+	Flush() error
+}
+
+type exportEncoder interface {
+	Encode(log *AuditLog) error
+	Flush() error
+}
+
+func newExportEncoder(format ExportFormat, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVEncoder(w)
+	case ExportFormatNDJSON:
+		return &ndjsonEncoder{encoder: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format: %s", format)
+}
+
+type ndjsonEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonEncoder) Encode(log *AuditLog) error {
+	return e.encoder.Encode(log)
+}
+
+func (e *ndjsonEncoder) Flush() error {
+	return nil
+}
+
+var csvHeader = []string{
+	"id", "entity_name", "entity_pk", "operation", "changed_by", "changed_at",
+	"source", "status_reason", "ip_address", "user_agent", "restored_from_id",
+	"diff", "old_row", "new_row",
+}
+
+type csvEncoder struct {
+	writer *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer) (*csvEncoder, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("write csv header: %w", err)
+	}
+	return &csvEncoder{writer: writer}, nil
+}
+
+func (e *csvEncoder) Encode(log *AuditLog) error {
+	return e.writer.Write([]string{
+		strconv.FormatInt(log.ID, 10),
+		log.EntityName,
+		log.EntityPK,
+		string(log.Operation),
+		formatOptionalInt(log.ChangedBy),
+		log.ChangedAt.Format(time.RFC3339Nano),
+		string(log.Source),
+		formatOptionalString(log.StatusReason),
+		formatOptionalString(log.IPAddress),
+		formatOptionalString(log.UserAgent),
+		formatOptionalInt(log.RestoredFromID),
+		string(log.Diff),
+		string(log.OldRow),
+		string(log.NewRow),
+	})
+}
+
+func (e *csvEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func formatOptionalInt(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+func formatOptionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
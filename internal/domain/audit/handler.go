@@ -1,8 +1,12 @@
 package audit
 
 import (
+	"compress/gzip"
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -18,20 +22,24 @@ func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
 
 // List godoc
 // @Summary      Список аудит-логов
-// @Description  Возвращает список записей аудита с фильтрами
+// @Description  Возвращает список записей аудита с фильтрами (только для администратора)
 // @Tags         audit
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        entity_name query string false "Фильтр по имени сущности (users/accounts/strategies/offers/subscriptions/trades)"
 // @Param        entity_pk query string false "Фильтр по первичному ключу сущности"
 // @Param        operation query string false "Фильтр по операции (insert/update/delete)"
 // @Param        changed_by query int false "Фильтр по ID пользователя"
+// @Param        source query string false "Фильтр по источнику изменения (api/worker/import/restore/system/legacy)"
 // @Param        from query string false "Начало периода (RFC3339)"
 // @Param        to query string false "Конец периода (RFC3339)"
 // @Param        page query int false "Номер страницы" default(1)
 // @Param        limit query int false "Количество записей на странице" default(20)
 // @Success      200 {object} AuditListResponse
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /audit [get]
 func (h *Handler) List(c *gin.Context) {
@@ -43,6 +51,10 @@ func (h *Handler) List(c *gin.Context) {
 
 	result, err := h.useCase.List(c.Request.Context(), &filter)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to list audit logs", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -53,14 +65,17 @@ func (h *Handler) List(c *gin.Context) {
 
 // GetByEntity godoc
 // @Summary      История изменений сущности
-// @Description  Возвращает историю изменений для конкретной сущности
+// @Description  Возвращает историю изменений для конкретной сущности (только для администратора)
 // @Tags         audit
 // @Accept       json
 // @Produce      json
+// @Security     BearerAuth
 // @Param        entity_name path string true "Имя сущности (users/accounts/strategies/offers/subscriptions/trades)"
 // @Param        entity_pk path string true "Первичный ключ сущности"
 // @Success      200 {array} AuditLog
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /audit/{entity_name}/{entity_pk} [get]
 func (h *Handler) GetByEntity(c *gin.Context) {
@@ -74,6 +89,10 @@ func (h *Handler) GetByEntity(c *gin.Context) {
 
 	logs, err := h.useCase.GetByEntity(c.Request.Context(), entityName, entityPK)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get audit logs by entity",
 			zap.String("entity_name", entityName),
 			zap.String("entity_pk", entityPK),
@@ -114,3 +133,238 @@ func (h *Handler) GetStats(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// GetDiff godoc
+// @Summary      Изменения полей сущности
+// @Description  Возвращает историю изменений сущности с изменениями по каждому полю, которые триггер аудита записал в колонку diff (только для администратора)
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        entity_name path string true "Имя сущности (users/accounts/strategies/offers/subscriptions/trades)"
+// @Param        entity_pk path string true "Первичный ключ сущности"
+// @Success      200 {array} AuditDiffEntry
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /audit/{entity_name}/{entity_pk}/diff [get]
+func (h *Handler) GetDiff(c *gin.Context) {
+	entityName := c.Param("entity_name")
+	entityPK := c.Param("entity_pk")
+
+	entries, err := h.useCase.GetDiff(c.Request.Context(), entityName, entityPK)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get audit diff",
+			zap.String("entity_name", entityName),
+			zap.String("entity_pk", entityPK),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetAsOf godoc
+// @Summary      Состояние сущности на момент времени
+// @Description  Восстанавливает состояние сущности на указанный момент, последовательно применяя записи audit_log (только для администратора)
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        entity_name path string true "Имя сущности (users/accounts/strategies/offers/subscriptions/trades)"
+// @Param        entity_pk path string true "Первичный ключ сущности"
+// @Param        at query string true "Момент времени (RFC3339)"
+// @Success      200 {object} EntityStateResponse
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /audit/{entity_name}/{entity_pk}/as-of [get]
+func (h *Handler) GetAsOf(c *gin.Context) {
+	entityName := c.Param("entity_name")
+	entityPK := c.Param("entity_pk")
+
+	var req AsOfRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	state, err := h.useCase.GetAsOf(c.Request.Context(), entityName, entityPK, req.At)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to reconstruct entity state",
+			zap.String("entity_name", entityName),
+			zap.String("entity_pk", entityPK),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// Restore godoc
+// @Summary      Восстановить сущность из аудита
+// @Description  Возвращает сущность в состояние old_row указанной записи аудита (только для администратора). Удалённая строка вставляется заново, внешние ключи проверяются заранее
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        entity_name path string true "Имя сущности (users/accounts/strategies/offers/subscriptions/trades)"
+// @Param        entity_pk path string true "Первичный ключ сущности"
+// @Param        request body RestoreRequest true "ID записи аудита"
+// @Success      200 {object} RestoreResponse
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /audit/{entity_name}/{entity_pk}/restore [post]
+func (h *Handler) Restore(c *gin.Context) {
+	entityName := c.Param("entity_name")
+	entityPK := c.Param("entity_pk")
+
+	var req RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.Restore(c.Request.Context(), entityName, entityPK, &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to restore entity from audit",
+			zap.String("entity_name", entityName),
+			zap.String("entity_pk", entityPK),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Export godoc
+// @Summary      Выгрузка аудит-логов
+// @Description  Потоково выгружает все записи аудита по фильтрам в CSV или NDJSON (только для администратора). Поддерживает gzip через Accept-Encoding
+// @Tags         audit
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Security     BearerAuth
+// @Param        format query string true "Формат выгрузки (csv/ndjson)"
+// @Param        entity_name query string false "Фильтр по имени сущности (users/accounts/strategies/offers/subscriptions/trades)"
+// @Param        entity_pk query string false "Фильтр по первичному ключу сущности"
+// @Param        operation query string false "Фильтр по операции (insert/update/delete)"
+// @Param        changed_by query int false "Фильтр по ID пользователя"
+// @Param        source query string false "Фильтр по источнику изменения (api/worker/import/restore/system/legacy)"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Success      200 {string} string "Файл выгрузки"
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /audit/export [get]
+func (h *Handler) Export(c *gin.Context) {
+	var req AuditExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sink := &httpExportSink{
+		c:           c,
+		contentType: exportContentTypes[req.Format],
+		fileName:    fmt.Sprintf("audit_export.%s", req.Format),
+		gzip:        strings.Contains(c.GetHeader("Accept-Encoding"), "gzip"),
+	}
+
+	err := h.useCase.Export(c.Request.Context(), &req, sink)
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok && !sink.started {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to export audit logs", zap.Error(err))
+		if !sink.started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+var exportContentTypes = map[ExportFormat]string{
+	ExportFormatCSV:    "text/csv; charset=utf-8",
+	ExportFormatNDJSON: "application/x-ndjson",
+}
+
+// httpExportSink отправляет заголовки ответа только при первой записи, поэтому
+// ошибка до начала выгрузки отдаётся клиенту обычным JSON
+type httpExportSink struct {
+	c           *gin.Context
+	contentType string
+	fileName    string
+	gzip        bool
+
+	started bool
+	gz      *gzip.Writer
+}
+
+func (s *httpExportSink) start() {
+	s.started = true
+	header := s.c.Writer.Header()
+	header.Set("Content-Type", s.contentType)
+	header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", s.fileName))
+	if s.gzip {
+		header.Set("Content-Encoding", "gzip")
+		header.Add("Vary", "Accept-Encoding")
+		s.gz = gzip.NewWriter(s.c.Writer)
+	}
+	s.c.Status(http.StatusOK)
+}
+
+func (s *httpExportSink) Write(data []byte) (int, error) {
+	if !s.started {
+		s.start()
+	}
+	if s.gz != nil {
+		return s.gz.Write(data)
+	}
+	return s.c.Writer.Write(data)
+}
+
+func (s *httpExportSink) Flush() error {
+	if s.gz != nil {
+		if err := s.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	if s.started {
+		s.c.Writer.Flush()
+	}
+	return nil
+}
+
+func (s *httpExportSink) Close() error {
+	if s.gz != nil {
+		return s.gz.Close()
+	}
+	return nil
+}
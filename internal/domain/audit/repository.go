@@ -2,26 +2,41 @@ package audit
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
 
-	Create(ctx context.Context, req *AuditCreateRequest) (*AuditLog, error)
-
 	List(ctx context.Context, filter *AuditFilter) (*common.PaginatedResult[AuditLog], error)
 
 	GetByEntity(ctx context.Context, entityName string, entityPK string) ([]*AuditLog, error)
 
+	GetByEntityUntil(ctx context.Context, entityName string, entityPK string, at time.Time) ([]*AuditLog, error)
+
 	GetStats(ctx context.Context, filter *AuditStatsFilter) ([]*AuditStats, error)
 
 	CountByEntity(ctx context.Context, entityName string) (int64, error)
+
+	GetByID(ctx context.Context, id int64) (*AuditLog, error)
+
+	Export(ctx context.Context, filter *AuditFilter, batchSize int, fn func(batch []*AuditLog) error) error
+
+	FindMissingReferences(ctx context.Context, entityName string, row json.RawMessage) ([]MissingReference, error)
+
+	Restore(ctx context.Context, entityName string, entityPK string, row json.RawMessage, auditID int64) (common.AuditOperation, json.RawMessage, error)
 }
 
 type repository struct {
@@ -33,63 +48,8 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 	return &repository{db: db, logger: logger}
 }
 
-func (r *repository) Create(ctx context.Context, req *AuditCreateRequest) (*AuditLog, error) {
-	var oldRowJSON, newRowJSON []byte
-	var err error
-
-	if req.OldValue != nil {
-		oldRowJSON, err = json.Marshal(req.OldValue)
-		if err != nil {
-			r.logger.Warn("Failed to marshal old value for audit",
-				zap.Error(err))
-			oldRowJSON = nil
-		}
-	}
-
-	if req.NewValue != nil {
-		newRowJSON, err = json.Marshal(req.NewValue)
-		if err != nil {
-			r.logger.Warn("Failed to marshal new value for audit",
-				zap.Error(err))
-			newRowJSON = nil
-		}
-	}
-
-	query := `
-		INSERT INTO audit_log (entity_name, entity_pk, operation, changed_by, old_row, new_row)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row
-	`
-
-	var result AuditLog
-	err = r.db.QueryRowxContext(ctx, query,
-		string(req.EntityType),
-		fmt.Sprintf("%d", req.EntityID),
-		string(req.Action),
-		req.UserID,
-		oldRowJSON,
-		newRowJSON,
-	).StructScan(&result)
-
-	if err != nil {
-		r.logger.Error("Failed to create audit log",
-			zap.String("entity_type", string(req.EntityType)),
-			zap.Int64("entity_id", req.EntityID),
-			zap.Error(err))
-		return nil, fmt.Errorf("create audit log: %w", err)
-	}
-
-	r.logger.Debug("Audit log created",
-		zap.Int64("id", result.ID),
-		zap.String("entity_name", result.EntityName),
-		zap.String("entity_pk", result.EntityPK))
-
-	return &result, nil
-}
-
-func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.PaginatedResult[AuditLog], error) {
-	filter.SetDefaults()
-
+// buildAuditWhere собирает условия AuditFilter для List и Export
+func buildAuditWhere(filter *AuditFilter) (string, []interface{}) {
 	var conditions []string
 	var args []interface{}
 	argIndex := 1
@@ -118,6 +78,12 @@ func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.Pag
 		argIndex++
 	}
 
+	if filter.Source != "" {
+		conditions = append(conditions, fmt.Sprintf("source = $%d", argIndex))
+		args = append(args, filter.Source)
+		argIndex++
+	}
+
 	if !filter.From.IsZero() {
 		conditions = append(conditions, fmt.Sprintf("changed_at >= $%d", argIndex))
 		args = append(args, filter.From)
@@ -135,16 +101,25 @@ func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.Pag
 		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
 
+	return whereClause, args
+}
+
+func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.PaginatedResult[AuditLog], error) {
+	filter.SetDefaults()
+
+	whereClause, args := buildAuditWhere(filter)
+	argIndex := len(args) + 1
+
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM audit_log %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count audit logs", zap.Error(err))
 		return nil, fmt.Errorf("count audit logs: %w", err)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row
+		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row, ip_address, user_agent, source, diff, status_reason, restored_from_id
 		FROM audit_log
 		%s
 		ORDER BY changed_at DESC
@@ -154,7 +129,7 @@ func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.Pag
 	args = append(args, filter.Limit, filter.Offset)
 
 	var logs []AuditLog
-	err = r.db.SelectContext(ctx, &logs, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &logs, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list audit logs", zap.Error(err))
 		return nil, fmt.Errorf("list audit logs: %w", err)
@@ -176,14 +151,14 @@ func (r *repository) List(ctx context.Context, filter *AuditFilter) (*common.Pag
 
 func (r *repository) GetByEntity(ctx context.Context, entityName string, entityPK string) ([]*AuditLog, error) {
 	query := `
-		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row
+		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row, ip_address, user_agent, source, diff, status_reason, restored_from_id
 		FROM audit_log
 		WHERE entity_name = $1 AND entity_pk = $2
 		ORDER BY changed_at DESC
 	`
 
 	var logs []*AuditLog
-	err := r.db.SelectContext(ctx, &logs, query, entityName, entityPK)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &logs, query, entityName, entityPK)
 	if err != nil {
 		r.logger.Error("Failed to get audit logs by entity",
 			zap.String("entity_name", entityName),
@@ -195,6 +170,28 @@ func (r *repository) GetByEntity(ctx context.Context, entityName string, entityP
 	return logs, nil
 }
 
+func (r *repository) GetByEntityUntil(ctx context.Context, entityName string, entityPK string, at time.Time) ([]*AuditLog, error) {
+	query := `
+		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row, ip_address, user_agent, source, diff, status_reason, restored_from_id
+		FROM audit_log
+		WHERE entity_name = $1 AND entity_pk = $2 AND changed_at <= $3
+		ORDER BY changed_at, id
+	`
+
+	var logs []*AuditLog
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &logs, query, entityName, entityPK, at)
+	if err != nil {
+		r.logger.Error("Failed to get audit logs by entity until time",
+			zap.String("entity_name", entityName),
+			zap.String("entity_pk", entityPK),
+			zap.Time("at", at),
+			zap.Error(err))
+		return nil, fmt.Errorf("get audit logs by entity until: %w", err)
+	}
+
+	return logs, nil
+}
+
 func (r *repository) GetStats(ctx context.Context, filter *AuditStatsFilter) ([]*AuditStats, error) {
 	var conditions []string
 	var args []interface{}
@@ -232,7 +229,7 @@ func (r *repository) GetStats(ctx context.Context, filter *AuditStatsFilter) ([]
 	`, whereClause)
 
 	var stats []*AuditStats
-	err := r.db.SelectContext(ctx, &stats, query, args...)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &stats, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to get audit stats", zap.Error(err))
 		return nil, fmt.Errorf("get audit stats: %w", err)
@@ -245,7 +242,7 @@ func (r *repository) CountByEntity(ctx context.Context, entityName string) (int6
 	query := `SELECT COUNT(*) FROM audit_log WHERE entity_name = $1`
 
 	var count int64
-	err := r.db.GetContext(ctx, &count, query, entityName)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &count, query, entityName)
 	if err != nil {
 		r.logger.Error("Failed to count audit logs by entity",
 			zap.String("entity_name", entityName),
@@ -255,3 +252,257 @@ func (r *repository) CountByEntity(ctx context.Context, entityName string) (int6
 
 	return count, nil
 }
+
+// Export читает audit_log через серверный курсор пачками по batchSize строк,
+// не загружая всю выборку в память
+func (r *repository) Export(ctx context.Context, filter *AuditFilter, batchSize int, fn func(batch []*AuditLog) error) error {
+	whereClause, args := buildAuditWhere(filter)
+
+	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	declareQuery := fmt.Sprintf(`
+		DECLARE audit_export NO SCROLL CURSOR FOR
+		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row, ip_address, user_agent, source, diff, status_reason, restored_from_id
+		FROM audit_log
+		%s
+		ORDER BY changed_at, id
+	`, whereClause)
+	if _, err := tx.ExecContext(ctx, declareQuery, args...); err != nil {
+		r.logger.Error("Failed to declare audit export cursor", zap.Error(err))
+		return fmt.Errorf("declare audit export cursor: %w", err)
+	}
+
+	fetchQuery := fmt.Sprintf("FETCH FORWARD %d FROM audit_export", batchSize)
+	for {
+		var batch []*AuditLog
+		if err := tx.SelectContext(ctx, &batch, fetchQuery); err != nil {
+			r.logger.Error("Failed to fetch audit export batch", zap.Error(err))
+			return fmt.Errorf("fetch audit export batch: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "CLOSE audit_export"); err != nil {
+		return fmt.Errorf("close audit export cursor: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *repository) GetByID(ctx context.Context, id int64) (*AuditLog, error) {
+	query := `
+		SELECT id, entity_name, entity_pk, operation, changed_by, changed_at, old_row, new_row, ip_address, user_agent, source, diff, status_reason, restored_from_id
+		FROM audit_log
+		WHERE id = $1
+	`
+
+	var log AuditLog
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &log, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get audit log by ID", zap.Int64("id", id), zap.Error(err))
+		return nil, fmt.Errorf("get audit log by id: %w", err)
+	}
+
+	return &log, nil
+}
+
+// FindMissingReferences проверяет одноколоночные внешние ключи таблицы и
+// возвращает те, значения которых в row больше не существуют
+func (r *repository) FindMissingReferences(ctx context.Context, entityName string, row json.RawMessage) ([]MissingReference, error) {
+	fkQuery := `
+		SELECT a.attname AS column_name, rt.relname AS ref_table, ra.attname AS ref_column
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = c.conkey[1]
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = c.confkey[1]
+		WHERE c.contype = 'f'
+		  AND t.relname = $1
+		  AND n.nspname = current_schema()
+		  AND array_length(c.conkey, 1) = 1
+		ORDER BY a.attname
+	`
+
+	conn := dbtx.Conn(ctx, r.db)
+
+	var refs []MissingReference
+	if err := conn.SelectContext(ctx, &refs, fkQuery, entityName); err != nil {
+		r.logger.Error("Failed to load foreign keys", zap.String("entity_name", entityName), zap.Error(err))
+		return nil, fmt.Errorf("load foreign keys: %w", err)
+	}
+
+	values, err := decodeRow(row)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]MissingReference, 0)
+	for _, ref := range refs {
+		value, ok := values[ref.Column]
+		if !ok || string(value) == "null" {
+			continue
+		}
+
+		// Значение приводится к типу колонки через jsonb_populate_record
+		existsQuery := fmt.Sprintf(`
+			SELECT EXISTS (
+				SELECT 1
+				FROM %s ref, jsonb_populate_record(NULL::%s, $1::jsonb) src
+				WHERE ref.%s = src.%s
+			)`,
+			pgx.Identifier{ref.ReferencedTable}.Sanitize(),
+			pgx.Identifier{entityName}.Sanitize(),
+			pgx.Identifier{ref.ReferencedField}.Sanitize(),
+			pgx.Identifier{ref.Column}.Sanitize(),
+		)
+
+		var exists bool
+		if err := conn.GetContext(ctx, &exists, existsQuery, string(row)); err != nil {
+			r.logger.Error("Failed to check reference",
+				zap.String("entity_name", entityName),
+				zap.String("column", ref.Column),
+				zap.Error(err))
+			return nil, fmt.Errorf("check reference %s: %w", ref.Column, err)
+		}
+		if !exists {
+			ref.Value = value
+			missing = append(missing, ref)
+		}
+	}
+
+	return missing, nil
+}
+
+// Restore записывает row в таблицу сущности: обновляет существующую строку или
+// вставляет удалённую заново. Триггер аудита пишет запись с source=restore.
+func (r *repository) Restore(ctx context.Context, entityName string, entityPK string, row json.RawMessage, auditID int64) (common.AuditOperation, json.RawMessage, error) {
+	id, err := strconv.ParseInt(entityPK, 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse entity pk: %w", err)
+	}
+
+	values, err := decodeRow(row)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return "", nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingSource, string(SourceRestore)); err != nil {
+		return "", nil, err
+	}
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingRestoredFrom, strconv.FormatInt(auditID, 10)); err != nil {
+		return "", nil, err
+	}
+
+	var tableColumns []string
+	err = tx.SelectContext(ctx, &tableColumns, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position
+	`, entityName)
+	if err != nil {
+		return "", nil, fmt.Errorf("load table columns: %w", err)
+	}
+
+	// Колонки, добавленные после записи журнала, получают значения по умолчанию
+	// (при вставке) или сохраняют текущие (при обновлении)
+	var columns []string
+	hasUpdatedAt := false
+	for _, column := range tableColumns {
+		if column == "updated_at" {
+			hasUpdatedAt = true
+			continue
+		}
+		if _, ok := values[column]; ok {
+			columns = append(columns, pgx.Identifier{column}.Sanitize())
+		}
+	}
+
+	table := pgx.Identifier{entityName}.Sanitize()
+
+	var exists bool
+	existsQuery := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)", table)
+	if err := tx.GetContext(ctx, &exists, existsQuery, id); err != nil {
+		return "", nil, fmt.Errorf("check entity exists: %w", err)
+	}
+
+	selectColumns := make([]string, len(columns))
+	for i, column := range columns {
+		selectColumns[i] = "src." + column
+	}
+
+	var (
+		operation common.AuditOperation
+		query     string
+		args      = []interface{}{string(row)}
+	)
+	if exists {
+		operation = common.AuditOperationUpdate
+		setUpdatedAt := ""
+		if hasUpdatedAt {
+			setUpdatedAt = ", updated_at = now()"
+		}
+		query = fmt.Sprintf(`
+			UPDATE %s t
+			SET (%s) = (SELECT %s FROM jsonb_populate_record(NULL::%s, $1::jsonb) src)%s
+			WHERE t.id = $2
+			RETURNING to_jsonb(t.*)
+		`, table, strings.Join(columns, ", "), strings.Join(selectColumns, ", "), table, setUpdatedAt)
+		args = append(args, id)
+	} else {
+		operation = common.AuditOperationInsert
+		query = fmt.Sprintf(`
+			INSERT INTO %s AS t (%s)
+			SELECT %s FROM jsonb_populate_record(NULL::%s, $1::jsonb) src
+			RETURNING to_jsonb(t.*)
+		`, table, strings.Join(columns, ", "), strings.Join(selectColumns, ", "), table)
+	}
+
+	var state []byte
+	if err := tx.GetContext(ctx, &state, query, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "23503" || pgErr.Code == "23514") {
+			return "", nil, ErrRestoreConflict.WithDetails(pgErr.Message)
+		}
+		r.logger.Error("Failed to restore entity",
+			zap.String("entity_name", entityName),
+			zap.String("entity_pk", entityPK),
+			zap.Int64("audit_id", auditID),
+			zap.Error(err))
+		return "", nil, fmt.Errorf("restore entity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	r.logger.Info("Entity restored from audit",
+		zap.String("entity_name", entityName),
+		zap.String("entity_pk", entityPK),
+		zap.Int64("audit_id", auditID),
+		zap.String("operation", string(operation)))
+
+	return operation, state, nil
+}
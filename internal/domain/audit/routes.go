@@ -12,6 +12,14 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 
 		auditGroup.GET("/stats", h.GetStats)
 
+		auditGroup.GET("/export", h.Export)
+
 		auditGroup.GET("/:entity_name/:entity_pk", h.GetByEntity)
+
+		auditGroup.GET("/:entity_name/:entity_pk/diff", h.GetDiff)
+
+		auditGroup.GET("/:entity_name/:entity_pk/as-of", h.GetAsOf)
+
+		auditGroup.POST("/:entity_name/:entity_pk/restore", h.Restore)
 	}
 }
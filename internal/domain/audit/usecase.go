@@ -2,8 +2,11 @@ package audit
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"go.uber.org/zap"
 )
@@ -15,6 +18,14 @@ type UseCase interface {
 	GetByEntity(ctx context.Context, entityName string, entityPK string) ([]*AuditLog, error)
 
 	GetStats(ctx context.Context, filter *AuditStatsFilter) ([]*AuditStats, error)
+
+	GetDiff(ctx context.Context, entityName string, entityPK string) ([]*AuditDiffEntry, error)
+
+	GetAsOf(ctx context.Context, entityName string, entityPK string, at time.Time) (*EntityStateResponse, error)
+
+	Restore(ctx context.Context, entityName string, entityPK string, req *RestoreRequest) (*RestoreResponse, error)
+
+	Export(ctx context.Context, req *AuditExportRequest, sink ExportSink) error
 }
 
 type useCase struct {
@@ -26,7 +37,12 @@ func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
 	return &useCase{repo: repo, logger: logger}
 }
 
+// List, GetByEntity, GetDiff и GetAsOf отдают IP, User-Agent и полные версии строк,
+// поэтому, как и выгрузка, доступны только администратору
 func (u *useCase) List(ctx context.Context, filter *AuditFilter) (*common.PaginatedResult[AuditLog], error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
 	filter.SetDefaults()
 
 	u.logger.Debug("Listing audit logs",
@@ -43,18 +59,11 @@ func (u *useCase) List(ctx context.Context, filter *AuditFilter) (*common.Pagina
 }
 
 func (u *useCase) GetByEntity(ctx context.Context, entityName string, entityPK string) ([]*AuditLog, error) {
-
-	validEntities := map[string]bool{
-		EntityNameUsers:         true,
-		EntityNameAccounts:      true,
-		EntityNameStrategies:    true,
-		EntityNameOffers:        true,
-		EntityNameSubscriptions: true,
-		EntityNameTrades:        true,
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
 	}
-
-	if !validEntities[entityName] {
-		return nil, fmt.Errorf("invalid entity name: %s", entityName)
+	if !isValidEntityName(entityName) {
+		return nil, ErrInvalidEntityName
 	}
 
 	u.logger.Debug("Getting audit logs by entity",
@@ -82,3 +91,190 @@ func (u *useCase) GetStats(ctx context.Context, filter *AuditStatsFilter) ([]*Au
 
 	return stats, nil
 }
+
+func (u *useCase) GetDiff(ctx context.Context, entityName string, entityPK string) ([]*AuditDiffEntry, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+	if !isValidEntityName(entityName) {
+		return nil, ErrInvalidEntityName
+	}
+
+	u.logger.Debug("Getting audit diff by entity",
+		zap.String("entity_name", entityName),
+		zap.String("entity_pk", entityPK))
+
+	logs, err := u.repo.GetByEntity(ctx, entityName, entityPK)
+	if err != nil {
+		return nil, fmt.Errorf("get audit logs by entity: %w", err)
+	}
+
+	entries := make([]*AuditDiffEntry, 0, len(logs))
+	for _, log := range logs {
+		changes, err := parseDiff(log.Diff)
+		if err != nil {
+			return nil, fmt.Errorf("parse diff for audit %d: %w", log.ID, err)
+		}
+		entries = append(entries, &AuditDiffEntry{
+			AuditID:      log.ID,
+			Operation:    log.Operation,
+			ChangedBy:    log.ChangedBy,
+			ChangedAt:    log.ChangedAt,
+			Source:       log.Source,
+			StatusReason: log.StatusReason,
+			Changes:      changes,
+		})
+	}
+
+	return entries, nil
+}
+
+func (u *useCase) GetAsOf(ctx context.Context, entityName string, entityPK string, at time.Time) (*EntityStateResponse, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+	if !isValidEntityName(entityName) {
+		return nil, ErrInvalidEntityName
+	}
+
+	u.logger.Debug("Reconstructing entity state from audit",
+		zap.String("entity_name", entityName),
+		zap.String("entity_pk", entityPK),
+		zap.Time("at", at))
+
+	logs, err := u.repo.GetByEntityUntil(ctx, entityName, entityPK, at)
+	if err != nil {
+		return nil, fmt.Errorf("get audit logs by entity: %w", err)
+	}
+	if len(logs) == 0 {
+		return nil, ErrNoAuditHistory
+	}
+
+	state, err := replay(logs)
+	if err != nil {
+		return nil, fmt.Errorf("replay audit logs: %w", err)
+	}
+
+	last := logs[len(logs)-1]
+	response := &EntityStateResponse{
+		EntityName:    entityName,
+		EntityPK:      entityPK,
+		At:            at,
+		Exists:        state != nil,
+		LastAuditID:   last.ID,
+		LastChangedAt: last.ChangedAt,
+	}
+	if state != nil {
+		response.State, err = json.Marshal(state)
+		if err != nil {
+			return nil, fmt.Errorf("encode entity state: %w", err)
+		}
+	}
+
+	return response, nil
+}
+
+func (u *useCase) Restore(ctx context.Context, entityName string, entityPK string, req *RestoreRequest) (*RestoreResponse, error) {
+	u.logger.Info("UseCase: Restoring entity from audit",
+		zap.String("entity_name", entityName),
+		zap.String("entity_pk", entityPK),
+		zap.Int64("audit_id", req.AuditID))
+
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+	if !isValidEntityName(entityName) {
+		return nil, ErrInvalidEntityName
+	}
+
+	entry, err := u.repo.GetByID(ctx, req.AuditID)
+	if err != nil {
+		return nil, fmt.Errorf("get audit entry: %w", err)
+	}
+	if entry == nil || entry.EntityName != entityName || entry.EntityPK != entityPK {
+		return nil, ErrAuditEntryNotFound
+	}
+
+	row, err := decodeRow(entry.OldRow)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrNothingToRestore
+	}
+
+	missing, err := u.repo.FindMissingReferences(ctx, entityName, entry.OldRow)
+	if err != nil {
+		return nil, fmt.Errorf("check references: %w", err)
+	}
+	if len(missing) > 0 {
+		return nil, ErrRestoreReferenceMissing.WithDetails(missing)
+	}
+
+	operation, state, err := u.repo.Restore(ctx, entityName, entityPK, entry.OldRow, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestoreResponse{
+		EntityName:          entityName,
+		EntityPK:            entityPK,
+		RestoredFromAuditID: entry.ID,
+		Operation:           operation,
+		State:               state,
+	}, nil
+}
+
+func (u *useCase) Export(ctx context.Context, req *AuditExportRequest, sink ExportSink) error {
+	u.logger.Info("UseCase: Exporting audit logs",
+		zap.String("format", string(req.Format)),
+		zap.String("entity_name", req.EntityName),
+		zap.Time("from", req.From),
+		zap.Time("to", req.To))
+
+	// Выгрузка содержит IP, User-Agent и полные версии строк, поэтому доступна только администратору
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return err
+	}
+
+	var (
+		encoder exportEncoder
+		total   int
+	)
+	err := u.repo.Export(ctx, req.Filter(), exportBatchSize, func(batch []*AuditLog) error {
+		// Заголовок CSV пишется только когда курсор уже открыт, чтобы ошибку
+		// запроса ещё можно было вернуть обычным JSON-ответом
+		if encoder == nil {
+			var err error
+			if encoder, err = newExportEncoder(req.Format, sink); err != nil {
+				return err
+			}
+		}
+		for _, log := range batch {
+			if err := encoder.Encode(log); err != nil {
+				return fmt.Errorf("encode audit log %d: %w", log.ID, err)
+			}
+		}
+		total += len(batch)
+		if err := encoder.Flush(); err != nil {
+			return fmt.Errorf("flush audit export: %w", err)
+		}
+		return sink.Flush()
+	})
+	if err != nil {
+		return fmt.Errorf("export audit logs: %w", err)
+	}
+
+	// Пустая выборка: CSV всё равно получает строку заголовка
+	if encoder == nil {
+		if encoder, err = newExportEncoder(req.Format, sink); err != nil {
+			return err
+		}
+		if err := encoder.Flush(); err != nil {
+			return fmt.Errorf("flush audit export: %w", err)
+		}
+	}
+
+	u.logger.Info("Audit export finished", zap.Int("rows", total))
+	return nil
+}
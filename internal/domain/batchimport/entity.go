@@ -26,6 +26,7 @@ const (
 	ImportJobTypeTrades     ImportJobType = "trades"
 	ImportJobTypeAccounts   ImportJobType = "accounts"
 	ImportJobTypeStatistics ImportJobType = "statistics"
+	ImportJobTypeFXRates    ImportJobType = "fx_rates"
 )
 
 type ImportJobError struct {
@@ -46,7 +47,7 @@ type ImportJobSummary struct {
 }
 
 type CreateImportJobRequest struct {
-	Type     ImportJobType `json:"type" binding:"required,oneof=trades accounts statistics"`
+	Type     ImportJobType `json:"type" binding:"required,oneof=trades accounts statistics fx_rates"`
 	FileName string        `json:"file_name"`
 }
 
@@ -56,6 +57,11 @@ type ImportTradesRequest struct {
 	FileFormat string `form:"file_format" binding:"required,oneof=csv json"`
 }
 
+// ImportFXRatesRequest — файл курсов валют: строки base_currency, quote_currency, rate_date, rate
+type ImportFXRatesRequest struct {
+	FileFormat string `form:"file_format" binding:"required,oneof=csv json"`
+}
+
 type ImportTradesParameters struct {
 	StrategyID int64  `json:"strategy_id"`
 	AccountID  int64  `json:"account_id"`
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -55,13 +56,55 @@ func (h *Handler) ImportTrades(c *gin.Context) {
 	c.JSON(http.StatusAccepted, job)
 }
 
+// ImportFXRates godoc
+// @Summary      Импортировать курсы валют
+// @Description  Загружает файл с курсами валют по датам (только для администратора). Колонки: base_currency, quote_currency, rate_date, rate; курс на существующую дату перезаписывается
+// @Tags         import
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file formData file true "Файл с курсами (CSV или JSON)"
+// @Param        file_format formData string true "Формат файла (csv/json)"
+// @Success      202 {object} ImportJob
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /import/fx-rates [post]
+func (h *Handler) ImportFXRates(c *gin.Context) {
+	var req ImportFXRatesRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	defer file.Close()
+
+	job, err := h.useCase.ImportFXRates(c.Request.Context(), &req, file, header.Filename)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to import fx rates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
 // ListJobs godoc
 // @Summary      Список задач импорта
 // @Description  Возвращает список задач импорта с фильтрами
 // @Tags         import
 // @Accept       json
 // @Produce      json
-// @Param        type query string false "Фильтр по типу (trades/accounts/statistics)"
+// @Param        type query string false "Фильтр по типу (trades/accounts/statistics/fx_rates)"
 // @Param        status query string false "Фильтр по статусу"
 // @Param        page query int false "Номер страницы" default(1)
 // @Param        limit query int false "Количество записей на странице" default(20)
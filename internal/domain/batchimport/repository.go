@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -45,7 +46,7 @@ func (r *repository) CreateJob(ctx context.Context, job *ImportJob) (*ImportJob,
 	`
 
 	var result ImportJob
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		job.Type,
 		common.ImportJobStatusPending,
 		job.FileName,
@@ -73,7 +74,7 @@ func (r *repository) GetJobByID(ctx context.Context, id int64) (*ImportJob, erro
 	`
 
 	var job ImportJob
-	err := r.db.GetContext(ctx, &job, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &job, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -113,7 +114,7 @@ func (r *repository) ListJobs(ctx context.Context, filter *JobFilter) (*common.P
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM import_jobs %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count import jobs", zap.Error(err))
 		return nil, fmt.Errorf("count import jobs: %w", err)
@@ -130,7 +131,7 @@ func (r *repository) ListJobs(ctx context.Context, filter *JobFilter) (*common.P
 	args = append(args, filter.Limit, filter.Offset)
 
 	var jobs []ImportJob
-	err = r.db.SelectContext(ctx, &jobs, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &jobs, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list import jobs", zap.Error(err))
 		return nil, fmt.Errorf("list import jobs: %w", err)
@@ -153,7 +154,7 @@ func (r *repository) ListJobs(ctx context.Context, filter *JobFilter) (*common.P
 func (r *repository) UpdateJobStatus(ctx context.Context, id int64, status common.ImportJobStatus) error {
 	query := `UPDATE import_jobs SET status = $1 WHERE id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, status, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, status, id)
 	if err != nil {
 		r.logger.Error("Failed to update import job status",
 			zap.Int64("id", id),
@@ -181,7 +182,7 @@ func (r *repository) UpdateJobProgress(ctx context.Context, id int64, processedR
 		WHERE id = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, processedRows, errorRows, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, processedRows, errorRows, id)
 	if err != nil {
 		r.logger.Error("Failed to update import job progress",
 			zap.Int64("id", id),
@@ -207,7 +208,7 @@ func (r *repository) StartJob(ctx context.Context, id int64, totalRows int) erro
 	`
 
 	now := time.Now()
-	result, err := r.db.ExecContext(ctx, query, common.ImportJobStatusRunning, now, totalRows, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, common.ImportJobStatusRunning, now, totalRows, id)
 	if err != nil {
 		r.logger.Error("Failed to start import job",
 			zap.Int64("id", id),
@@ -236,7 +237,7 @@ func (r *repository) CompleteJob(ctx context.Context, id int64, status common.Im
 	`
 
 	now := time.Now()
-	result, err := r.db.ExecContext(ctx, query, status, now, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, status, now, id)
 	if err != nil {
 		r.logger.Error("Failed to complete import job",
 			zap.Int64("id", id),
@@ -265,7 +266,7 @@ func (r *repository) CreateError(ctx context.Context, jobError *ImportJobError)
 	`
 
 	var result ImportJobError
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		jobError.JobID,
 		jobError.RowNumber,
 		jobError.RawData,
@@ -292,7 +293,7 @@ func (r *repository) CreateErrorsBatch(ctx context.Context, jobErrors []*ImportJ
 		VALUES ($1, $2, $3, $4)
 	`
 
-	tx, err := r.db.BeginTxx(ctx, nil)
+	tx, err := dbtx.Begin(ctx, r.db)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
@@ -334,7 +335,7 @@ func (r *repository) GetJobErrors(ctx context.Context, jobID int64, filter *Erro
 
 	countQuery := `SELECT COUNT(*) FROM import_job_errors WHERE job_id = $1`
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, jobID)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, jobID)
 	if err != nil {
 		r.logger.Error("Failed to count import job errors",
 			zap.Int64("job_id", jobID),
@@ -351,7 +352,7 @@ func (r *repository) GetJobErrors(ctx context.Context, jobID int64, filter *Erro
 	`
 
 	var errors []ImportJobError
-	err = r.db.SelectContext(ctx, &errors, query, jobID, filter.Limit, filter.Offset)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &errors, query, jobID, filter.Limit, filter.Offset)
 	if err != nil {
 		r.logger.Error("Failed to get import job errors",
 			zap.Int64("job_id", jobID),
@@ -377,7 +378,7 @@ func (r *repository) CountJobErrors(ctx context.Context, jobID int64) (int64, er
 	query := `SELECT COUNT(*) FROM import_job_errors WHERE job_id = $1`
 
 	var count int64
-	err := r.db.GetContext(ctx, &count, query, jobID)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &count, query, jobID)
 	if err != nil {
 		r.logger.Error("Failed to count import job errors",
 			zap.Int64("job_id", jobID),
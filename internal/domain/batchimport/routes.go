@@ -10,6 +10,8 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 
 		importGroup.POST("/trades", h.ImportTrades)
 
+		importGroup.POST("/fx-rates", h.ImportFXRates)
+
 		importGroup.GET("", h.ListJobs)
 
 		importGroup.GET("/:id", h.GetJobByID)
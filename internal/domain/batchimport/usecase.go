@@ -10,7 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
 	"github.com/finlleyl/cp_database/internal/domain/trade"
 	"go.uber.org/zap"
 )
@@ -21,6 +25,8 @@ type UseCase interface {
 
 	ImportTrades(ctx context.Context, req *ImportTradesRequest, file io.Reader, fileName string) (*ImportJob, error)
 
+	ImportFXRates(ctx context.Context, req *ImportFXRatesRequest, file io.Reader, fileName string) (*ImportJob, error)
+
 	GetJobByID(ctx context.Context, id int64) (*ImportJob, error)
 
 	ListJobs(ctx context.Context, filter *JobFilter) (*common.PaginatedResult[ImportJob], error)
@@ -31,13 +37,14 @@ type UseCase interface {
 }
 
 type useCase struct {
-	repo      Repository
-	tradeRepo trade.Repository
-	logger    *zap.Logger
+	repo         Repository
+	tradeRepo    trade.Repository
+	currencyRepo currency.Repository
+	logger       *zap.Logger
 }
 
-func NewUseCase(repo Repository, tradeRepo trade.Repository, logger *zap.Logger) UseCase {
-	return &useCase{repo: repo, tradeRepo: tradeRepo, logger: logger}
+func NewUseCase(repo Repository, tradeRepo trade.Repository, currencyRepo currency.Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, tradeRepo: tradeRepo, currencyRepo: currencyRepo, logger: logger}
 }
 
 func (u *useCase) CreateJob(ctx context.Context, req *CreateImportJobRequest) (*ImportJob, error) {
@@ -81,32 +88,122 @@ func (u *useCase) ImportTrades(ctx context.Context, req *ImportTradesRequest, fi
 		return createdJob, nil
 	}
 
-	go u.processTradeImport(context.Background(), createdJob.ID, req, data)
+	// Задача должна быть видна фоновой обработке, поэтому запускаем её после коммита запроса
+	dbtx.AfterCommit(ctx, func() {
+		go u.processTradeImport(importContext(ctx), createdJob.ID, req, data)
+	})
+
+	return createdJob, nil
+}
+
+// ImportFXRates загружает курсы валют; справочник общий, поэтому импорт доступен только администратору
+func (u *useCase) ImportFXRates(ctx context.Context, req *ImportFXRatesRequest, file io.Reader, fileName string) (*ImportJob, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	job := &ImportJob{
+		Type:     ImportJobTypeFXRates,
+		FileName: &fileName,
+	}
+
+	createdJob, err := u.repo.CreateJob(ctx, job)
+	if err != nil {
+		return nil, fmt.Errorf("create import job: %w", err)
+	}
+
+	u.logger.Info("FX rates import job created",
+		zap.Int64("job_id", createdJob.ID),
+		zap.String("file_format", req.FileFormat))
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		u.completeJobWithError(ctx, createdJob.ID, "Failed to read file: "+err.Error())
+		return createdJob, nil
+	}
+
+	dbtx.AfterCommit(ctx, func() {
+		go u.processFXRateImport(importContext(ctx), createdJob.ID, req, data)
+	})
 
 	return createdJob, nil
 }
 
+// importContext переносит автора запроса в контекст фоновой обработки для аудита
+func importContext(ctx context.Context) context.Context {
+	settings := map[string]string{dbtx.SettingSource: string(audit.SourceImport)}
+	if actor, ok := auth.ActorFromContext(ctx); ok && !actor.System {
+		settings[dbtx.SettingUserID] = strconv.FormatInt(actor.UserID, 10)
+	}
+	return dbtx.WithSettings(context.Background(), settings)
+}
+
 func (u *useCase) processTradeImport(ctx context.Context, jobID int64, req *ImportTradesRequest, data []byte) {
-	startTime := time.Now()
+	records, ok := u.parseRecords(ctx, jobID, req.FileFormat, data)
+	if !ok {
+		return
+	}
+
+	u.processRecords(ctx, jobID, ImportJobTypeTrades, records, func(record map[string]string) error {
+		createReq, err := u.mapRecordToTradeRequest(record, req.StrategyID, req.AccountID)
+		if err != nil {
+			return err
+		}
 
+		if _, err := u.tradeRepo.Create(ctx, createReq); err != nil {
+			return fmt.Errorf("Failed to create trade: %w", err)
+		}
+		return nil
+	})
+}
+
+func (u *useCase) processFXRateImport(ctx context.Context, jobID int64, req *ImportFXRatesRequest, data []byte) {
+	records, ok := u.parseRecords(ctx, jobID, req.FileFormat, data)
+	if !ok {
+		return
+	}
+
+	u.processRecords(ctx, jobID, ImportJobTypeFXRates, records, func(record map[string]string) error {
+		rate, err := u.mapRecordToRate(record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := u.currencyRepo.Upsert(ctx, rate); err != nil {
+			return fmt.Errorf("Failed to save fx rate: %w", err)
+		}
+		return nil
+	})
+}
+
+// parseRecords разбирает файл; при ошибке задача завершается со статусом failed
+func (u *useCase) parseRecords(ctx context.Context, jobID int64, fileFormat string, data []byte) ([]map[string]string, bool) {
 	var records []map[string]string
 	var err error
 
-	switch req.FileFormat {
+	switch fileFormat {
 	case "csv":
 		records, err = u.parseCSV(data)
 	case "json":
 		records, err = u.parseJSON(data)
 	default:
-		u.completeJobWithError(ctx, jobID, "Unsupported file format: "+req.FileFormat)
-		return
+		u.completeJobWithError(ctx, jobID, "Unsupported file format: "+fileFormat)
+		return nil, false
 	}
 
 	if err != nil {
 		u.completeJobWithError(ctx, jobID, "Failed to parse file: "+err.Error())
-		return
+		return nil, false
 	}
 
+	return records, true
+}
+
+// processRecords импортирует строки по одной: ошибочные строки попадают в import_job_errors,
+// остальные продолжают загружаться
+func (u *useCase) processRecords(ctx context.Context, jobID int64, jobType ImportJobType, records []map[string]string, importRow func(record map[string]string) error) {
+	startTime := time.Now()
+
 	totalRows := len(records)
 
 	if err := u.repo.StartJob(ctx, jobID, totalRows); err != nil {
@@ -122,8 +219,7 @@ func (u *useCase) processTradeImport(ctx context.Context, jobID int64, req *Impo
 	for i, record := range records {
 		rowNumber := i + 1
 
-		createReq, err := u.mapRecordToTradeRequest(record, req.StrategyID, req.AccountID)
-		if err != nil {
+		if err := importRow(record); err != nil {
 			errorRows++
 			rawData, _ := json.Marshal(record)
 			jobErrors = append(jobErrors, &ImportJobError{
@@ -135,19 +231,6 @@ func (u *useCase) processTradeImport(ctx context.Context, jobID int64, req *Impo
 			continue
 		}
 
-		_, err = u.tradeRepo.Create(ctx, createReq)
-		if err != nil {
-			errorRows++
-			rawData, _ := json.Marshal(record)
-			jobErrors = append(jobErrors, &ImportJobError{
-				JobID:        jobID,
-				RowNumber:    &rowNumber,
-				RawData:      rawData,
-				ErrorMessage: "Failed to create trade: " + err.Error(),
-			})
-			continue
-		}
-
 		processedRows++
 
 		if processedRows%100 == 0 {
@@ -176,8 +259,9 @@ func (u *useCase) processTradeImport(ctx context.Context, jobID int64, req *Impo
 			zap.Error(err))
 	}
 
-	u.logger.Info("Trade import completed",
+	u.logger.Info("Import completed",
 		zap.Int64("job_id", jobID),
+		zap.String("type", string(jobType)),
 		zap.Int("total_rows", totalRows),
 		zap.Int("processed", processedRows),
 		zap.Int("errors", errorRows),
@@ -300,6 +384,67 @@ func (u *useCase) mapRecordToTradeRequest(record map[string]string, strategyID i
 	}, nil
 }
 
+// mapRecordToRate читает строку курса; дата принимается как YYYY-MM-DD или RFC3339
+func (u *useCase) mapRecordToRate(record map[string]string) (*currency.Rate, error) {
+	base := firstField(record, "base_currency", "base")
+	if len(base) != 3 {
+		return nil, fmt.Errorf("invalid base_currency: %q", base)
+	}
+
+	quote := firstField(record, "quote_currency", "quote")
+	if len(quote) != 3 {
+		return nil, fmt.Errorf("invalid quote_currency: %q", quote)
+	}
+
+	if strings.EqualFold(base, quote) {
+		return nil, fmt.Errorf("base_currency and quote_currency must differ")
+	}
+
+	dateStr := firstField(record, "rate_date", "date")
+	if dateStr == "" {
+		return nil, fmt.Errorf("missing required field: rate_date")
+	}
+
+	date, err := time.Parse(time.DateOnly, dateStr)
+	if err != nil {
+		parsed, rfcErr := time.Parse(time.RFC3339, dateStr)
+		if rfcErr != nil {
+			return nil, fmt.Errorf("invalid rate_date format: %w", err)
+		}
+		date = parsed.UTC().Truncate(24 * time.Hour)
+	}
+
+	rateStr := firstField(record, "rate")
+	if rateStr == "" {
+		return nil, fmt.Errorf("missing required field: rate")
+	}
+
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate: %w", err)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+
+	return &currency.Rate{
+		Base:  strings.ToUpper(base),
+		Quote: strings.ToUpper(quote),
+		Date:  date,
+		Rate:  rate,
+	}, nil
+}
+
+// firstField возвращает первое непустое значение среди допустимых названий колонки
+func firstField(record map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := strings.TrimSpace(record[name]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 func (u *useCase) completeJobWithError(ctx context.Context, jobID int64, errorMsg string) {
 
 	jobError := &ImportJobError{
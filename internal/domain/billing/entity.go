@@ -0,0 +1,101 @@
+package billing
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/statistics"
+)
+
+var (
+	ErrSubscriptionNotFound = common.NewError(http.StatusNotFound, "subscription_not_found", "subscription not found")
+	ErrInvoiceNotFound      = common.NewError(http.StatusNotFound, "invoice_not_found", "invoice not found")
+)
+
+// Terms — условия оффера и состояние биллинга подписки
+type Terms struct {
+	SubscriptionID        int64                     `db:"subscription_id"`
+	Status                common.SubscriptionStatus `db:"status"`
+	FeeInterval           common.FeeInterval        `db:"fee_interval"`
+	PerformanceFeePercent *float64                  `db:"performance_fee_percent"`
+	ManagementFeePercent  *float64                  `db:"management_fee_percent"`
+	RegistrationFeeAmount *float64                  `db:"registration_fee_amount"`
+	Equity                float64                   `db:"equity"`
+	ActivatedAt           *time.Time                `db:"activated_at"`
+	BilledUntil           *time.Time                `db:"billed_until"`
+	HighWaterMark         float64                   `db:"high_water_mark"`
+}
+
+// Charge — комиссия, которую нужно списать за период
+type Charge struct {
+	Type       statistics.CommissionType
+	Amount     float64
+	PeriodFrom time.Time
+	PeriodTo   time.Time
+}
+
+// HighWaterMark — состояние high-water mark подписки для комиссии за результат
+type HighWaterMark struct {
+	SubscriptionID        int64      `json:"subscription_id"`
+	HighWaterMark         float64    `json:"high_water_mark"`
+	CumulativeProfit      float64    `json:"cumulative_profit"`
+	UnchargedProfit       float64    `json:"uncharged_profit"`
+	PerformanceFeePercent *float64   `json:"performance_fee_percent"`
+	AccruedPerformanceFee float64    `json:"accrued_performance_fee"`
+	BilledUntil           *time.Time `json:"billed_until"`
+}
+
+// RunResult — итог прогона биллинга
+type RunResult struct {
+	ProcessedSubscriptions int                      `json:"processed_subscriptions"`
+	FailedSubscriptions    int                      `json:"failed_subscriptions"`
+	BilledPeriods          int                      `json:"billed_periods"`
+	TotalAmount            float64                  `json:"total_amount"`
+	Commissions            []*statistics.Commission `json:"commissions"`
+}
+
+// Invoice — нумерованный счёт, объединяющий комиссии подписки за период биллинга
+type Invoice struct {
+	ID             int64                    `json:"id" db:"id"`
+	Number         string                   `json:"number" db:"number"`
+	SubscriptionID int64                    `json:"subscription_id" db:"subscription_id"`
+	InvestorUserID int64                    `json:"investor_user_id" db:"investor_user_id"`
+	StrategyID     int64                    `json:"strategy_id" db:"strategy_id"`
+	StrategyTitle  string                   `json:"strategy_title" db:"strategy_title"`
+	OfferName      string                   `json:"offer_name" db:"offer_name"`
+	Currency       string                   `json:"currency" db:"currency"`
+	PeriodFrom     time.Time                `json:"period_from" db:"period_from"`
+	PeriodTo       time.Time                `json:"period_to" db:"period_to"`
+	TotalAmount    float64                  `json:"total_amount" db:"total_amount"`
+	CreatedAt      time.Time                `json:"created_at" db:"created_at"`
+	Items          []*statistics.Commission `json:"items,omitempty" db:"-"`
+}
+
+type InvoiceFilter struct {
+	SubscriptionID int64 `form:"subscription_id"`
+	StrategyID     int64 `form:"strategy_id"`
+	common.TimeRange
+	common.Pagination
+}
+
+type InvoiceFormat string
+
+const (
+	InvoiceFormatJSON InvoiceFormat = "json"
+	InvoiceFormatCSV  InvoiceFormat = "csv"
+	InvoiceFormatPDF  InvoiceFormat = "pdf"
+)
+
+type InvoiceRenderRequest struct {
+	Format InvoiceFormat `form:"format" binding:"omitempty,oneof=json csv pdf"`
+}
+
+// InvoiceListResponse представляет пагинированный ответ со списком счетов
+type InvoiceListResponse struct {
+	Data       []Invoice `json:"data"`
+	Total      int64     `json:"total"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+	TotalPages int       `json:"total_pages"`
+}
@@ -0,0 +1,214 @@
+package billing
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// Run godoc
+// @Summary      Запустить биллинг
+// @Description  Начисляет комиссии за обслуживание и за результат по всем закончившимся периодам (только для администратора)
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} RunResult
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /billing/run [post]
+func (h *Handler) Run(c *gin.Context) {
+	result, err := h.useCase.RunDue(c.Request.Context(), time.Now())
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to run billing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetHighWaterMark godoc
+// @Summary      High-water mark подписки
+// @Description  Возвращает пик накопленной прибыли, с которого уже взята комиссия за результат, и прибыль сверх него
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID подписки"
+// @Success      200 {object} HighWaterMark
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /subscriptions/{id}/high-water-mark [get]
+func (h *Handler) GetHighWaterMark(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	result, err := h.useCase.GetHighWaterMark(c.Request.Context(), id)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get subscription high-water mark", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListInvoices godoc
+// @Summary      Список счетов
+// @Description  Возвращает счета за периоды биллинга с фильтрами по подписке, стратегии и периоду
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        subscription_id query int false "Фильтр по ID подписки"
+// @Param        strategy_id query int false "Фильтр по ID стратегии"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} InvoiceListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /invoices [get]
+func (h *Handler) ListInvoices(c *gin.Context) {
+	var filter InvoiceFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.listInvoices(c, &filter)
+}
+
+// ListSubscriptionInvoices godoc
+// @Summary      Счета подписки
+// @Description  Возвращает счета подписки за периоды биллинга
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID подписки"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} InvoiceListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /subscriptions/{id}/invoices [get]
+func (h *Handler) ListSubscriptionInvoices(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	var filter InvoiceFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.SubscriptionID = id
+
+	h.listInvoices(c, &filter)
+}
+
+func (h *Handler) listInvoices(c *gin.Context, filter *InvoiceFilter) {
+	result, err := h.useCase.ListInvoices(c.Request.Context(), filter)
+	if err != nil {
+		h.logger.Error("Failed to list invoices", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetInvoice godoc
+// @Summary      Получить счёт
+// @Description  Возвращает счёт со списком комиссий в формате JSON, CSV или PDF
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Produce      text/csv
+// @Produce      application/pdf
+// @Param        id path int true "ID счёта"
+// @Param        format query string false "Формат документа" Enums(json, csv, pdf) default(json)
+// @Success      200 {object} Invoice
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /invoices/{id} [get]
+func (h *Handler) GetInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invoice id"})
+		return
+	}
+
+	var req InvoiceRenderRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoice, err := h.useCase.GetInvoice(c.Request.Context(), id)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get invoice", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var (
+		buf         bytes.Buffer
+		contentType string
+	)
+	switch req.Format {
+	case InvoiceFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = renderInvoiceCSV(&buf, invoice)
+	case InvoiceFormatPDF:
+		contentType = "application/pdf"
+		err = renderInvoicePDF(&buf, invoice)
+	default:
+		c.JSON(http.StatusOK, invoice)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to render invoice", zap.Int64("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, invoice.Number, req.Format))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
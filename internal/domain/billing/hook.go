@@ -0,0 +1,25 @@
+package billing
+
+import (
+	"context"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/subscription"
+)
+
+// activationHook запускает биллинг, когда подписка становится активной
+type activationHook struct {
+	useCase UseCase
+}
+
+func NewActivationHook(useCase UseCase) subscription.StatusHook {
+	return &activationHook{useCase: useCase}
+}
+
+func (h *activationHook) OnStatusChange(ctx context.Context, sub *subscription.Subscription, from common.SubscriptionStatus) error {
+	if sub.Status != common.SubscriptionStatusActive || from == common.SubscriptionStatusActive {
+		return nil
+	}
+	_, err := h.useCase.Activate(ctx, sub.ID)
+	return err
+}
@@ -0,0 +1,19 @@
+package billing
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewUseCase,
+		NewHandler,
+		NewWorker,
+		fx.Annotate(
+			NewActivationHook,
+			fx.ResultTags(`group:"subscription_status_hooks"`),
+		),
+	),
+	fx.Invoke(func(*Worker) {}),
+)
@@ -0,0 +1,76 @@
+package billing
+
+import (
+	"math"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/statistics"
+)
+
+const hoursPerYear = 365 * 24
+
+// periodEnd возвращает конец календарного периода начисления (UTC), в который попадает from.
+// Недели начинаются с понедельника, как date_trunc('week') в PostgreSQL.
+func periodEnd(from time.Time, interval common.FeeInterval) time.Time {
+	t := from.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch interval {
+	case common.FeeIntervalDaily:
+		return day.AddDate(0, 0, 1)
+	case common.FeeIntervalWeekly:
+		sinceMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-sinceMonday)
+	default:
+		return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// newProfit возвращает прибыль выше high-water mark и новое значение пика.
+// После просадки комиссия не берётся, пока накопленная прибыль не превысит прежний пик.
+func newProfit(highWaterMark, cumulativeProfit float64) (float64, float64) {
+	if cumulativeProfit <= highWaterMark {
+		return 0, highWaterMark
+	}
+	return cumulativeProfit - highWaterMark, cumulativeProfit
+}
+
+// calculateCharges считает комиссии за период [from, to).
+// management_fee_percent — годовой процент от эквити инвестора, начисляется
+// пропорционально длине периода; performance_fee_percent — процент от прибыли
+// выше high-water mark (см. newProfit).
+func calculateCharges(terms *Terms, from, to time.Time, profit float64) []Charge {
+	var charges []Charge
+
+	if terms.ManagementFeePercent != nil && *terms.ManagementFeePercent > 0 && terms.Equity > 0 {
+		share := to.Sub(from).Hours() / hoursPerYear
+		amount := roundMoney(terms.Equity * *terms.ManagementFeePercent / 100 * share)
+		if amount > 0 {
+			charges = append(charges, Charge{
+				Type:       statistics.CommissionTypeManagement,
+				Amount:     amount,
+				PeriodFrom: from,
+				PeriodTo:   to,
+			})
+		}
+	}
+
+	if terms.PerformanceFeePercent != nil && *terms.PerformanceFeePercent > 0 && profit > 0 {
+		amount := roundMoney(profit * *terms.PerformanceFeePercent / 100)
+		if amount > 0 {
+			charges = append(charges, Charge{
+				Type:       statistics.CommissionTypePerformance,
+				Amount:     amount,
+				PeriodFrom: from,
+				PeriodTo:   to,
+			})
+		}
+	}
+
+	return charges
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,199 @@
+package billing
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const invoiceDateLayout = "2006-01-02 15:04 MST"
+
+// renderInvoiceCSV пишет строки счёта: одна строка на комиссию и итоговая строка total
+func renderInvoiceCSV(w io.Writer, invoice *Invoice) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"invoice_number", "commission_id", "type", "period_from", "period_to", "amount", "currency"}); err != nil {
+		return err
+	}
+
+	for _, item := range invoice.Items {
+		if err := cw.Write([]string{
+			invoice.Number,
+			strconv.FormatInt(item.ID, 10),
+			string(item.Type),
+			formatOptionalTime(item.PeriodFrom, time.RFC3339),
+			formatOptionalTime(item.PeriodTo, time.RFC3339),
+			formatAmount(item.Amount),
+			invoice.Currency,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := cw.Write([]string{
+		invoice.Number,
+		"",
+		"total",
+		invoice.PeriodFrom.UTC().Format(time.RFC3339),
+		invoice.PeriodTo.UTC().Format(time.RFC3339),
+		formatAmount(invoice.TotalAmount),
+		invoice.Currency,
+	}); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// renderInvoicePDF формирует PDF без внешних зависимостей: текст набирается
+// встроенным моноширинным шрифтом Courier, кириллица транслитерируется.
+func renderInvoicePDF(w io.Writer, invoice *Invoice) error {
+	lines := []string{
+		"INVOICE " + invoice.Number,
+		"",
+		"Issued:        " + invoice.CreatedAt.UTC().Format(invoiceDateLayout),
+		"Period:        " + invoice.PeriodFrom.UTC().Format(invoiceDateLayout) + " - " + invoice.PeriodTo.UTC().Format(invoiceDateLayout),
+		"Subscription:  #" + strconv.FormatInt(invoice.SubscriptionID, 10),
+		"Investor:      user #" + strconv.FormatInt(invoice.InvestorUserID, 10),
+		"Strategy:      #" + strconv.FormatInt(invoice.StrategyID, 10) + " " + invoice.StrategyTitle,
+		"Offer:         " + invoice.OfferName,
+		"Currency:      " + invoice.Currency,
+		"",
+		fmt.Sprintf("%-10s %-14s %-40s %14s", "ID", "Type", "Period", "Amount"),
+		strings.Repeat("-", 81),
+	}
+
+	for _, item := range invoice.Items {
+		period := formatOptionalTime(item.PeriodFrom, "2006-01-02 15:04")
+		if item.PeriodTo != nil && (item.PeriodFrom == nil || !item.PeriodTo.Equal(*item.PeriodFrom)) {
+			period += " - " + formatOptionalTime(item.PeriodTo, "2006-01-02 15:04")
+		}
+		lines = append(lines, fmt.Sprintf("%-10d %-14s %-40s %14s", item.ID, item.Type, period, formatAmount(item.Amount)))
+	}
+
+	lines = append(lines,
+		strings.Repeat("-", 81),
+		fmt.Sprintf("%-66s %14s", "Total, "+invoice.Currency, formatAmount(invoice.TotalAmount)),
+	)
+
+	return writePDF(w, lines)
+}
+
+func formatOptionalTime(t *time.Time, layout string) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 50
+	pdfFontSize     = 9
+	pdfLeading      = 13
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// writePDF раскладывает строки моноширинным шрифтом по страницам A4
+func writePDF(w io.Writer, lines []string) error {
+	var pages [][]string
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// 1 — каталог, 2 — дерево страниц, 3 — шрифт, далее пары (страница, содержимое)
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(line))
+		}
+		content.WriteString("ET")
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfEscape экранирует строку PDF и оставляет только печатные ASCII-символы
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+var cyrillicToLatin = func() map[rune]string {
+	lower := map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+		'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+		'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+		'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+		'я': "ya",
+	}
+	table := make(map[rune]string, 2*len(lower))
+	for r, latin := range lower {
+		table[r] = latin
+		upper := []rune(strings.ToUpper(string(r)))[0]
+		if latin != "" {
+			latin = strings.ToUpper(latin[:1]) + latin[1:]
+		}
+		table[upper] = latin
+	}
+	return table
+}()
@@ -0,0 +1,416 @@
+package billing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/statistics"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// errPeriodAlreadyBilled — курсор подписки уже сдвинут другим прогоном биллинга
+var errPeriodAlreadyBilled = errors.New("billing period already processed")
+
+type Repository interface {
+	GetTerms(ctx context.Context, subscriptionID int64) (*Terms, error)
+	Activate(ctx context.Context, subscriptionID int64, at time.Time) error
+	ChargeRegistration(ctx context.Context, subscriptionID int64, amount float64, at time.Time) (*statistics.Commission, error)
+	ListDue(ctx context.Context, at time.Time, afterID int64, limit int) ([]int64, error)
+	GetCumulativeProfit(ctx context.Context, subscriptionID int64, until *time.Time) (float64, error)
+	SavePeriod(ctx context.Context, subscriptionID int64, from, to time.Time, highWaterMark float64, charges []Charge) ([]*statistics.Commission, error)
+	GetInvoice(ctx context.Context, id int64) (*Invoice, error)
+	ListInvoices(ctx context.Context, filter *InvoiceFilter) (*common.PaginatedResult[Invoice], error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
+	return &repository{db: db, logger: logger}
+}
+
+func (r *repository) GetTerms(ctx context.Context, subscriptionID int64) (*Terms, error) {
+	query := `
+		SELECT s.id AS subscription_id, s.status,
+		       o.fee_interval, o.performance_fee_percent, o.management_fee_percent, o.registration_fee_amount,
+		       COALESCE(ab.balance, 0) AS equity, b.activated_at, b.billed_until, COALESCE(b.high_water_mark, 0) AS high_water_mark
+		FROM subscriptions s
+		JOIN offers o ON o.id = s.offer_id
+		LEFT JOIN account_balances ab ON ab.account_id = s.investor_account_id
+		LEFT JOIN subscription_billing_state b ON b.subscription_id = s.id
+		WHERE s.id = $1
+	`
+
+	var terms Terms
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &terms, query, subscriptionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get billing terms",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get billing terms: %w", err)
+	}
+
+	return &terms, nil
+}
+
+// Activate заводит курсор биллинга при первой активации подписки.
+// Повторная активация (после приостановки) курсор не сбрасывает.
+func (r *repository) Activate(ctx context.Context, subscriptionID int64, at time.Time) error {
+	query := `
+		INSERT INTO subscription_billing_state (subscription_id, activated_at, billed_until)
+		VALUES ($1, $2, $2)
+		ON CONFLICT (subscription_id) DO NOTHING
+	`
+
+	if _, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, subscriptionID, at); err != nil {
+		r.logger.Error("Failed to activate subscription billing",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return fmt.Errorf("activate subscription billing: %w", err)
+	}
+
+	return nil
+}
+
+// ChargeRegistration списывает регистрационную комиссию. Если она уже была
+// списана, возвращает nil.
+func (r *repository) ChargeRegistration(ctx context.Context, subscriptionID int64, amount float64, at time.Time) (*statistics.Commission, error) {
+	query := `
+		INSERT INTO commissions (subscription_id, type, amount, period_from, period_to)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (subscription_id) WHERE type = 'registration' DO NOTHING
+		RETURNING id, subscription_id, type, amount, period_from, period_to, created_at
+	`
+
+	var commission statistics.Commission
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
+		subscriptionID,
+		statistics.CommissionTypeRegistration,
+		amount,
+		at,
+	).StructScan(&commission)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to charge registration fee",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return nil, fmt.Errorf("charge registration fee: %w", err)
+	}
+
+	r.logger.Info("Registration fee charged",
+		zap.Int64("id", commission.ID),
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Float64("amount", commission.Amount))
+
+	return &commission, nil
+}
+
+// ListDue возвращает подписки, у которых к моменту at закончился хотя бы один
+// неоплаченный период. Граница периода считается так же, как periodEnd.
+func (r *repository) ListDue(ctx context.Context, at time.Time, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT s.id
+		FROM subscriptions s
+		JOIN offers o ON o.id = s.offer_id
+		JOIN subscription_billing_state b ON b.subscription_id = s.id
+		WHERE s.status IN ('active', 'suspended')
+		  AND s.id > $2
+		  AND (CASE o.fee_interval
+		           WHEN 'daily' THEN date_trunc('day', b.billed_until AT TIME ZONE 'UTC') + INTERVAL '1 day'
+		           WHEN 'weekly' THEN date_trunc('week', b.billed_until AT TIME ZONE 'UTC') + INTERVAL '1 week'
+		           ELSE date_trunc('month', b.billed_until AT TIME ZONE 'UTC') + INTERVAL '1 month'
+		       END) AT TIME ZONE 'UTC' <= $1
+		ORDER BY s.id
+		LIMIT $3
+	`
+
+	var ids []int64
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &ids, query, at, afterID, limit)
+	if err != nil {
+		r.logger.Error("Failed to list subscriptions due for billing", zap.Error(err))
+		return nil, fmt.Errorf("list subscriptions due for billing: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetCumulativeProfit возвращает прибыль скопированных сделок, закрытых до until
+// (все закрытые сделки, если until не задан)
+func (r *repository) GetCumulativeProfit(ctx context.Context, subscriptionID int64, until *time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(profit), 0)
+		FROM copied_trades
+		WHERE subscription_id = $1
+		  AND close_time IS NOT NULL
+		  AND ($2::timestamptz IS NULL OR close_time < $2)
+	`
+
+	var profit float64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &profit, query, subscriptionID, until)
+	if err != nil {
+		r.logger.Error("Failed to get cumulative profit",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return 0, fmt.Errorf("get cumulative profit: %w", err)
+	}
+
+	return profit, nil
+}
+
+// SavePeriod списывает комиссии за период, сдвигает курсор подписки на его конец
+// и сохраняет новый high-water mark в одной транзакции. Курсор сдвигается только
+// с ожидаемого значения from, поэтому параллельные прогоны не спишут период дважды.
+func (r *repository) SavePeriod(ctx context.Context, subscriptionID int64, from, to time.Time, highWaterMark float64, charges []Charge) ([]*statistics.Commission, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE subscription_billing_state
+		SET billed_until = $3, high_water_mark = $4, updated_at = now()
+		WHERE subscription_id = $1 AND billed_until = $2
+	`, subscriptionID, from, to, highWaterMark)
+	if err != nil {
+		r.logger.Error("Failed to advance billing cursor",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return nil, fmt.Errorf("advance billing cursor: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, errPeriodAlreadyBilled
+	}
+
+	query := `
+		INSERT INTO commissions (subscription_id, type, amount, period_from, period_to)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (subscription_id, type, period_from) WHERE type <> 'registration' DO NOTHING
+		RETURNING id, subscription_id, type, amount, period_from, period_to, created_at
+	`
+
+	var commissions []*statistics.Commission
+	for _, charge := range charges {
+		var commission statistics.Commission
+		err := tx.QueryRowxContext(ctx, query,
+			subscriptionID,
+			charge.Type,
+			charge.Amount,
+			charge.PeriodFrom,
+			charge.PeriodTo,
+		).StructScan(&commission)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			r.logger.Error("Failed to create commission",
+				zap.Int64("subscription_id", subscriptionID),
+				zap.String("type", string(charge.Type)),
+				zap.Error(err))
+			return nil, fmt.Errorf("create commission: %w", err)
+		}
+		commissions = append(commissions, &commission)
+	}
+
+	invoiceID, err := r.createInvoice(ctx, tx, subscriptionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, commission := range commissions {
+		commission.InvoiceID = invoiceID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	r.logger.Info("Billing period saved",
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Time("period_from", from),
+		zap.Time("period_to", to),
+		zap.Int("commissions", len(commissions)))
+
+	return commissions, nil
+}
+
+// createInvoice выставляет счёт за период: в него попадают все ещё не выставленные
+// комиссии подписки, начисленные до конца периода (включая регистрационную).
+// Если таких комиссий нет, счёт не создаётся и возвращается nil.
+func (r *repository) createInvoice(ctx context.Context, q dbtx.Querier, subscriptionID int64, from, to time.Time) (*int64, error) {
+	query := `
+		WITH pending AS (
+			SELECT id, amount
+			FROM commissions
+			WHERE subscription_id = $1
+			  AND invoice_id IS NULL
+			  AND period_to <= $3
+			FOR UPDATE
+		), invoice AS (
+			INSERT INTO invoices (number, subscription_id, period_from, period_to, currency, total_amount)
+			SELECT 'INV-' || to_char($3::timestamptz AT TIME ZONE 'UTC', 'YYYYMM') || '-' || lpad(nextval('invoice_number_seq')::text, 6, '0'),
+			       s.id, $2, $3, a.currency, (SELECT SUM(amount) FROM pending)
+			FROM subscriptions s
+			JOIN accounts a ON a.id = s.investor_account_id
+			WHERE s.id = $1
+			  AND EXISTS (SELECT 1 FROM pending)
+			RETURNING id
+		), linked AS (
+			UPDATE commissions c
+			SET invoice_id = invoice.id
+			FROM invoice
+			WHERE c.id IN (SELECT id FROM pending)
+		)
+		SELECT id FROM invoice
+	`
+
+	var invoiceID int64
+	err := q.QueryRowxContext(ctx, query, subscriptionID, from, to).Scan(&invoiceID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to create invoice",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Time("period_from", from),
+			zap.Time("period_to", to),
+			zap.Error(err))
+		return nil, fmt.Errorf("create invoice: %w", err)
+	}
+
+	r.logger.Info("Invoice created",
+		zap.Int64("id", invoiceID),
+		zap.Int64("subscription_id", subscriptionID))
+
+	return &invoiceID, nil
+}
+
+const invoiceSelect = `
+	SELECT i.id, i.number, i.subscription_id, s.investor_user_id, o.strategy_id,
+	       st.title AS strategy_title, o.name AS offer_name, i.currency,
+	       i.period_from, i.period_to, i.total_amount, i.created_at
+	FROM invoices i
+	JOIN subscriptions s ON s.id = i.subscription_id
+	JOIN offers o ON o.id = s.offer_id
+	JOIN strategies st ON st.id = o.strategy_id
+`
+
+func (r *repository) GetInvoice(ctx context.Context, id int64) (*Invoice, error) {
+	var invoice Invoice
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &invoice, invoiceSelect+" WHERE i.id = $1", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get invoice by ID",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get invoice by id: %w", err)
+	}
+
+	itemsQuery := `
+		SELECT id, subscription_id, type, amount, period_from, period_to, invoice_id, created_at
+		FROM commissions
+		WHERE invoice_id = $1
+		ORDER BY period_from, id
+	`
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &invoice.Items, itemsQuery, id)
+	if err != nil {
+		r.logger.Error("Failed to get invoice items",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get invoice items: %w", err)
+	}
+
+	return &invoice, nil
+}
+
+func (r *repository) ListInvoices(ctx context.Context, filter *InvoiceFilter) (*common.PaginatedResult[Invoice], error) {
+	filter.SetDefaults()
+
+	var (
+		conditions []string
+		args       []interface{}
+		argIndex   = 1
+	)
+
+	if filter.SubscriptionID != 0 {
+		conditions = append(conditions, fmt.Sprintf("i.subscription_id = $%d", argIndex))
+		args = append(args, filter.SubscriptionID)
+		argIndex++
+	}
+
+	if filter.StrategyID != 0 {
+		conditions = append(conditions, fmt.Sprintf("o.strategy_id = $%d", argIndex))
+		args = append(args, filter.StrategyID)
+		argIndex++
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("i.period_to >= $%d", argIndex))
+		args = append(args, filter.From)
+		argIndex++
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("i.period_from < $%d", argIndex))
+		args = append(args, filter.To)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM invoices i
+		JOIN subscriptions s ON s.id = i.subscription_id
+		JOIN offers o ON o.id = s.offer_id
+		%s
+	`, whereClause)
+	var total int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		r.logger.Error("Failed to count invoices", zap.Error(err))
+		return nil, fmt.Errorf("count invoices: %w", err)
+	}
+
+	query := fmt.Sprintf(`%s
+		%s
+		ORDER BY i.period_to DESC, i.id DESC
+		LIMIT $%d OFFSET $%d
+	`, invoiceSelect, whereClause, argIndex, argIndex+1)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	invoices := []Invoice{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &invoices, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list invoices", zap.Error(err))
+		return nil, fmt.Errorf("list invoices: %w", err)
+	}
+
+	return &common.PaginatedResult[Invoice]{
+		Data:       invoices,
+		Total:      total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
@@ -0,0 +1,22 @@
+package billing
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	billing := router.Group("/billing")
+	{
+		billing.POST("/run", handler.Run)
+	}
+
+	invoices := router.Group("/invoices")
+	{
+		invoices.GET("", handler.ListInvoices)
+		invoices.GET("/:id", handler.GetInvoice)
+	}
+
+	subscriptions := router.Group("/subscriptions")
+	{
+		subscriptions.GET("/:id/high-water-mark", handler.GetHighWaterMark)
+		subscriptions.GET("/:id/invoices", handler.ListSubscriptionInvoices)
+	}
+}
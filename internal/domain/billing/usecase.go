@@ -0,0 +1,215 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/statistics"
+	"go.uber.org/zap"
+)
+
+const runBatchSize = 100
+
+type UseCase interface {
+	Activate(ctx context.Context, subscriptionID int64) (*statistics.Commission, error)
+	RunDue(ctx context.Context, at time.Time) (*RunResult, error)
+	GetHighWaterMark(ctx context.Context, subscriptionID int64) (*HighWaterMark, error)
+	GetInvoice(ctx context.Context, id int64) (*Invoice, error)
+	ListInvoices(ctx context.Context, filter *InvoiceFilter) (*common.PaginatedResult[Invoice], error)
+}
+
+type useCase struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, logger: logger}
+}
+
+// Activate запускает биллинг подписки и списывает регистрационную комиссию оффера.
+// Регистрационная комиссия списывается один раз за всё время жизни подписки.
+func (u *useCase) Activate(ctx context.Context, subscriptionID int64) (*statistics.Commission, error) {
+	u.logger.Info("UseCase: Activating subscription billing", zap.Int64("subscription_id", subscriptionID))
+
+	terms, err := u.repo.GetTerms(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("get billing terms: %w", err)
+	}
+	if terms == nil {
+		return nil, fmt.Errorf("subscription not found: %d", subscriptionID)
+	}
+
+	now := time.Now()
+	if err := u.repo.Activate(ctx, subscriptionID, now); err != nil {
+		return nil, fmt.Errorf("activate billing: %w", err)
+	}
+
+	if terms.RegistrationFeeAmount == nil || *terms.RegistrationFeeAmount <= 0 {
+		return nil, nil
+	}
+
+	commission, err := u.repo.ChargeRegistration(ctx, subscriptionID, roundMoney(*terms.RegistrationFeeAmount), now)
+	if err != nil {
+		return nil, fmt.Errorf("charge registration fee: %w", err)
+	}
+
+	return commission, nil
+}
+
+// RunDue начисляет комиссии за обслуживание и за результат по всем периодам,
+// закончившимся к моменту at. Ошибка по одной подписке не останавливает прогон.
+func (u *useCase) RunDue(ctx context.Context, at time.Time) (*RunResult, error) {
+	u.logger.Info("UseCase: Running billing", zap.Time("at", at))
+
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	result := &RunResult{Commissions: []*statistics.Commission{}}
+	var afterID int64
+	for {
+		ids, err := u.repo.ListDue(ctx, at, afterID, runBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("list subscriptions due for billing: %w", err)
+		}
+
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			afterID = id
+
+			periods, commissions, err := u.billSubscription(ctx, id, at)
+			result.BilledPeriods += periods
+			for _, commission := range commissions {
+				result.TotalAmount += commission.Amount
+			}
+			result.Commissions = append(result.Commissions, commissions...)
+			if err != nil {
+				u.logger.Error("Failed to bill subscription",
+					zap.Int64("subscription_id", id),
+					zap.Error(err))
+				result.FailedSubscriptions++
+				continue
+			}
+			result.ProcessedSubscriptions++
+		}
+
+		if len(ids) < runBatchSize {
+			break
+		}
+	}
+
+	result.TotalAmount = roundMoney(result.TotalAmount)
+	return result, nil
+}
+
+// billSubscription проходит по закончившимся периодам подписки начиная с курсора
+func (u *useCase) billSubscription(ctx context.Context, subscriptionID int64, at time.Time) (int, []*statistics.Commission, error) {
+	terms, err := u.repo.GetTerms(ctx, subscriptionID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("get billing terms: %w", err)
+	}
+	if terms == nil || terms.BilledUntil == nil {
+		return 0, nil, nil
+	}
+
+	var (
+		periods     int
+		commissions []*statistics.Commission
+	)
+	from := *terms.BilledUntil
+	highWaterMark := terms.HighWaterMark
+	for {
+		to := periodEnd(from, terms.FeeInterval)
+		if to.After(at) {
+			break
+		}
+
+		cumulative, err := u.repo.GetCumulativeProfit(ctx, subscriptionID, &to)
+		if err != nil {
+			return periods, commissions, err
+		}
+		profit, nextHighWaterMark := newProfit(highWaterMark, cumulative)
+
+		created, err := u.repo.SavePeriod(ctx, subscriptionID, from, to, nextHighWaterMark, calculateCharges(terms, from, to, profit))
+		if err != nil {
+			if errors.Is(err, errPeriodAlreadyBilled) {
+				return periods, commissions, nil
+			}
+			return periods, commissions, err
+		}
+
+		periods++
+		commissions = append(commissions, created...)
+		from = to
+		highWaterMark = nextHighWaterMark
+	}
+
+	return periods, commissions, nil
+}
+
+// GetHighWaterMark показывает текущий пик и прибыль, с которой комиссия за результат
+// ещё не взята (включая сделки текущего, не закрытого периода)
+func (u *useCase) GetHighWaterMark(ctx context.Context, subscriptionID int64) (*HighWaterMark, error) {
+	u.logger.Info("UseCase: Getting subscription high-water mark", zap.Int64("subscription_id", subscriptionID))
+
+	terms, err := u.repo.GetTerms(ctx, subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("get billing terms: %w", err)
+	}
+	if terms == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	cumulative, err := u.repo.GetCumulativeProfit(ctx, subscriptionID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get cumulative profit: %w", err)
+	}
+	uncharged, _ := newProfit(terms.HighWaterMark, cumulative)
+
+	result := &HighWaterMark{
+		SubscriptionID:        subscriptionID,
+		HighWaterMark:         terms.HighWaterMark,
+		CumulativeProfit:      roundMoney(cumulative),
+		UnchargedProfit:       roundMoney(uncharged),
+		PerformanceFeePercent: terms.PerformanceFeePercent,
+		BilledUntil:           terms.BilledUntil,
+	}
+	if terms.PerformanceFeePercent != nil {
+		result.AccruedPerformanceFee = roundMoney(uncharged * *terms.PerformanceFeePercent / 100)
+	}
+
+	return result, nil
+}
+
+func (u *useCase) GetInvoice(ctx context.Context, id int64) (*Invoice, error) {
+	u.logger.Info("UseCase: Getting invoice by ID", zap.Int64("id", id))
+
+	invoice, err := u.repo.GetInvoice(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get invoice: %w", err)
+	}
+	if invoice == nil {
+		return nil, ErrInvoiceNotFound
+	}
+
+	return invoice, nil
+}
+
+func (u *useCase) ListInvoices(ctx context.Context, filter *InvoiceFilter) (*common.PaginatedResult[Invoice], error) {
+	filter.SetDefaults()
+	u.logger.Info("UseCase: Listing invoices", zap.Any("filter", filter))
+
+	invoices, err := u.repo.ListInvoices(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list invoices: %w", err)
+	}
+
+	return invoices, nil
+}
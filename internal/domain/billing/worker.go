@@ -0,0 +1,40 @@
+package billing
+
+import (
+	"context"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/worker"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const billingPollInterval = time.Minute
+
+// Worker периодически начисляет комиссии по закончившимся периодам
+type Worker struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewWorker(lc fx.Lifecycle, useCase UseCase, logger *zap.Logger) *Worker {
+	w := &Worker{useCase: useCase, logger: logger}
+	worker.Start(lc, logger, "Billing worker", billingPollInterval, w.runOnce)
+	return w
+}
+
+func (w *Worker) runOnce(ctx context.Context) error {
+	result, err := w.useCase.RunDue(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if result.BilledPeriods > 0 || result.FailedSubscriptions > 0 {
+		w.logger.Info("Billing run done",
+			zap.Int("processed_subscriptions", result.ProcessedSubscriptions),
+			zap.Int("failed_subscriptions", result.FailedSubscriptions),
+			zap.Int("billed_periods", result.BilledPeriods),
+			zap.Float64("total_amount", result.TotalAmount))
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error описывает доменную ошибку, которая отображается в конкретный HTTP-статус
+type Error struct {
+	Status  int         `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"error"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func NewError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func NewNotFoundError(code, message string) *Error {
+	return NewError(http.StatusNotFound, code, message)
+}
+
+func NewConflictError(code, message string) *Error {
+	return NewError(http.StatusConflict, code, message)
+}
+
+// WithDetails возвращает копию ошибки с дополнительными данными для клиента
+func (e *Error) WithDetails(details interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Is сравнивает ошибки по коду, чтобы копии из WithDetails совпадали с исходной
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+func AsError(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
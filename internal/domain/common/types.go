@@ -82,6 +82,16 @@ type UserRole string
 const (
 	UserRoleMaster   UserRole = "master"
 	UserRoleInvestor UserRole = "investor"
+	UserRoleBoth     UserRole = "both"
+	UserRoleAdmin    UserRole = "admin"
+)
+
+type SizingMode string
+
+const (
+	SizingModeFixedLot    SizingMode = "fixed_lot"
+	SizingModeMultiplier  SizingMode = "multiplier"
+	SizingModeEquityRatio SizingMode = "equity_ratio"
 )
 
 type FeeInterval string
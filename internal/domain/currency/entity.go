@@ -0,0 +1,80 @@
+package currency
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Default — валюта агрегатов статистики, если клиент не указал свою
+const Default = "USD"
+
+// rateNotFoundCode — SQLSTATE, с которым fn_fx_convert сообщает об отсутствии курса
+const rateNotFoundCode = "FX404"
+
+var ErrRateNotFound = common.NewError(http.StatusUnprocessableEntity, "fx_rate_not_found", "no exchange rate for currency pair on the operation date")
+
+// Rate — курс на дату: 1 единица Base = Rate единиц Quote
+type Rate struct {
+	Base      string    `json:"base" db:"base_currency"`
+	Quote     string    `json:"quote" db:"quote_currency"`
+	Date      time.Time `json:"date" db:"rate_date"`
+	Rate      float64   `json:"rate" db:"rate"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type RateFilter struct {
+	Base  string `form:"base" binding:"omitempty,len=3,alpha"`
+	Quote string `form:"quote" binding:"omitempty,len=3,alpha"`
+	common.TimeRange
+	common.Pagination
+}
+
+// ConvertRequest — пересчёт суммы по историческому курсу на момент At
+type ConvertRequest struct {
+	From   string    `form:"from" binding:"required,len=3,alpha"`
+	To     string    `form:"to" binding:"required,len=3,alpha"`
+	Amount float64   `form:"amount"`
+	At     time.Time `form:"at"`
+}
+
+type Conversion struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Rate   float64   `json:"rate"`
+	Amount float64   `json:"amount"`
+	Result float64   `json:"result"`
+}
+
+// RateListResponse представляет пагинированный ответ со списком курсов
+type RateListResponse struct {
+	Data       []Rate `json:"data"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	TotalPages int    `json:"total_pages"`
+}
+
+// Normalize приводит код валюты к верхнему регистру; пустой код заменяется на Default
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return Default
+	}
+	return code
+}
+
+// MapError заменяет ошибку БД об отсутствии курса на ErrRateNotFound с текстом из fn_fx_convert
+func MapError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == rateNotFoundCode {
+		return ErrRateNotFound.WithDetails(pgErr.Message)
+	}
+	return err
+}
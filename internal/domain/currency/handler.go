@@ -0,0 +1,87 @@
+package currency
+
+import (
+	"net/http"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// ListRates godoc
+// @Summary      Курсы валют
+// @Description  Возвращает загруженные курсы валют по датам с фильтрами по паре и периоду
+// @Tags         currency
+// @Accept       json
+// @Produce      json
+// @Param        base query string false "Базовая валюта (ISO 4217)"
+// @Param        quote query string false "Котируемая валюта (ISO 4217)"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} RateListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /fx-rates [get]
+func (h *Handler) ListRates(c *gin.Context) {
+	var filter RateFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.ListRates(c.Request.Context(), &filter)
+	if err != nil {
+		h.logger.Error("Failed to list fx rates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Convert godoc
+// @Summary      Пересчитать сумму
+// @Description  Пересчитывает сумму по последнему известному на момент at курсу (прямому, обратному или кросс-курсу через USD)
+// @Tags         currency
+// @Accept       json
+// @Produce      json
+// @Param        from query string true "Исходная валюта (ISO 4217)"
+// @Param        to query string true "Целевая валюта (ISO 4217)"
+// @Param        amount query number false "Сумма"
+// @Param        at query string false "Момент пересчёта (RFC3339), по умолчанию текущий"
+// @Success      200 {object} Conversion
+// @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /fx-rates/convert [get]
+func (h *Handler) Convert(c *gin.Context) {
+	var req ConvertRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.Convert(c.Request.Context(), &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to convert amount", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,13 @@
+package currency
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewUseCase,
+		NewHandler,
+	),
+)
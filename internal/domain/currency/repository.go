@@ -0,0 +1,146 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Upsert(ctx context.Context, rate *Rate) (*Rate, error)
+	List(ctx context.Context, filter *RateFilter) (*common.PaginatedResult[Rate], error)
+	GetRate(ctx context.Context, from, to string, at time.Time) (*float64, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
+	return &repository{db: db, logger: logger}
+}
+
+// Upsert сохраняет курс на дату; повторный импорт той же даты перезаписывает значение
+func (r *repository) Upsert(ctx context.Context, rate *Rate) (*Rate, error) {
+	query := `
+		INSERT INTO fx_rates (base_currency, quote_currency, rate_date, rate)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (base_currency, quote_currency, rate_date)
+		DO UPDATE SET rate = EXCLUDED.rate, updated_at = now()
+		RETURNING base_currency, quote_currency, rate_date, rate, created_at, updated_at
+	`
+
+	var saved Rate
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
+		rate.Base,
+		rate.Quote,
+		rate.Date,
+		rate.Rate,
+	).StructScan(&saved)
+	if err != nil {
+		r.logger.Error("Failed to upsert fx rate",
+			zap.String("base", rate.Base),
+			zap.String("quote", rate.Quote),
+			zap.Time("date", rate.Date),
+			zap.Error(err))
+		return nil, fmt.Errorf("upsert fx rate: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (r *repository) List(ctx context.Context, filter *RateFilter) (*common.PaginatedResult[Rate], error) {
+	filter.SetDefaults()
+
+	var (
+		conditions []string
+		args       []interface{}
+		argIndex   = 1
+	)
+
+	if filter.Base != "" {
+		conditions = append(conditions, fmt.Sprintf("base_currency = $%d", argIndex))
+		args = append(args, strings.ToUpper(filter.Base))
+		argIndex++
+	}
+
+	if filter.Quote != "" {
+		conditions = append(conditions, fmt.Sprintf("quote_currency = $%d", argIndex))
+		args = append(args, strings.ToUpper(filter.Quote))
+		argIndex++
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("rate_date >= ($%d::timestamptz AT TIME ZONE 'UTC')::date", argIndex))
+		args = append(args, filter.From)
+		argIndex++
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("rate_date < ($%d::timestamptz AT TIME ZONE 'UTC')::date", argIndex))
+		args = append(args, filter.To)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM fx_rates %s", whereClause)
+	var total int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		r.logger.Error("Failed to count fx rates", zap.Error(err))
+		return nil, fmt.Errorf("count fx rates: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT base_currency, quote_currency, rate_date, rate, created_at, updated_at
+		FROM fx_rates
+		%s
+		ORDER BY rate_date DESC, base_currency, quote_currency
+		LIMIT $%d OFFSET $%d
+	`, whereClause, argIndex, argIndex+1)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	rates := []Rate{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &rates, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list fx rates", zap.Error(err))
+		return nil, fmt.Errorf("list fx rates: %w", err)
+	}
+
+	return &common.PaginatedResult[Rate]{
+		Data:       rates,
+		Total:      total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// GetRate возвращает курс from → to, действовавший на момент at, или nil, если курса нет
+func (r *repository) GetRate(ctx context.Context, from, to string, at time.Time) (*float64, error) {
+	var rate *float64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &rate, `SELECT fn_fx_rate($1, $2, $3)`, from, to, at)
+	if err != nil {
+		r.logger.Error("Failed to get fx rate",
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Time("at", at),
+			zap.Error(err))
+		return nil, fmt.Errorf("get fx rate: %w", err)
+	}
+
+	return rate, nil
+}
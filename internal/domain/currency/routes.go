@@ -0,0 +1,11 @@
+package currency
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	rates := router.Group("/fx-rates")
+	{
+		rates.GET("", handler.ListRates)
+		rates.GET("/convert", handler.Convert)
+	}
+}
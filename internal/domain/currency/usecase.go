@@ -0,0 +1,69 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"go.uber.org/zap"
+)
+
+type UseCase interface {
+	ListRates(ctx context.Context, filter *RateFilter) (*common.PaginatedResult[Rate], error)
+	Convert(ctx context.Context, req *ConvertRequest) (*Conversion, error)
+}
+
+type useCase struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, logger: logger}
+}
+
+func (u *useCase) ListRates(ctx context.Context, filter *RateFilter) (*common.PaginatedResult[Rate], error) {
+	filter.SetDefaults()
+	u.logger.Info("UseCase: Listing fx rates", zap.Any("filter", filter))
+
+	rates, err := u.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list fx rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+func (u *useCase) Convert(ctx context.Context, req *ConvertRequest) (*Conversion, error) {
+	at := req.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	from, to := strings.ToUpper(req.From), strings.ToUpper(req.To)
+
+	u.logger.Info("UseCase: Converting amount",
+		zap.String("from", from),
+		zap.String("to", to),
+		zap.Time("at", at),
+		zap.Float64("amount", req.Amount))
+
+	rate, err := u.repo.GetRate(ctx, from, to, at)
+	if err != nil {
+		return nil, fmt.Errorf("get fx rate: %w", err)
+	}
+	if rate == nil {
+		return nil, ErrRateNotFound.WithDetails(fmt.Sprintf("no exchange rate %s -> %s on %s", from, to, at.UTC().Format(time.DateOnly)))
+	}
+
+	return &Conversion{
+		From:   from,
+		To:     to,
+		At:     at,
+		Rate:   *rate,
+		Amount: req.Amount,
+		Result: math.Round(req.Amount**rate*100) / 100,
+	}, nil
+}
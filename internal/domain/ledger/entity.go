@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+var ErrInsufficientFunds = common.NewError(http.StatusConflict, "insufficient_funds", "account balance is not enough for withdrawal")
+
+type EntryType string
+
+const (
+	EntryTypeDeposit     EntryType = "deposit"
+	EntryTypeWithdrawal  EntryType = "withdrawal"
+	EntryTypeTradeProfit EntryType = "trade_profit"
+	EntryTypeCommission  EntryType = "commission"
+)
+
+// SystemAccount — контрагент двойной записи вне таблицы accounts; fx — клиринг между валютами
+type SystemAccount string
+
+const (
+	SystemAccountExternal SystemAccount = "external"
+	SystemAccountMarket   SystemAccount = "market"
+	SystemAccountFX       SystemAccount = "fx"
+)
+
+// Balance — остаток счёта и обороты по типам операций (со знаком)
+type Balance struct {
+	AccountID     int64      `json:"account_id" db:"account_id"`
+	Currency      string     `json:"currency" db:"currency"`
+	Balance       float64    `json:"balance" db:"balance"`
+	Deposits      float64    `json:"deposits" db:"deposits"`
+	Withdrawals   float64    `json:"withdrawals" db:"withdrawals"`
+	TradingProfit float64    `json:"trading_profit" db:"trading_profit"`
+	Commissions   float64    `json:"commissions" db:"commissions"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// Entry — проводка по счёту вместе с контрагентом и источником операции
+type Entry struct {
+	ID                    int64          `json:"id" db:"id"`
+	TransactionID         int64          `json:"transaction_id" db:"transaction_id"`
+	Type                  EntryType      `json:"type" db:"type"`
+	AccountID             int64          `json:"account_id" db:"account_id"`
+	Amount                float64        `json:"amount" db:"amount"`
+	BalanceAfter          float64        `json:"balance_after" db:"balance_after"`
+	CounterpartyAccountID *int64         `json:"counterparty_account_id,omitempty" db:"counterparty_account_id"`
+	CounterpartySystem    *SystemAccount `json:"counterparty_system,omitempty" db:"counterparty_system"`
+	CopiedTradeID         *int64         `json:"copied_trade_id,omitempty" db:"copied_trade_id"`
+	CommissionID          *int64         `json:"commission_id,omitempty" db:"commission_id"`
+	Description           *string        `json:"description,omitempty" db:"description"`
+	CreatedAt             time.Time      `json:"created_at" db:"created_at"`
+}
+
+type EntryFilter struct {
+	Type EntryType `form:"type" binding:"omitempty,oneof=deposit withdrawal trade_profit commission"`
+	common.TimeRange
+	common.Pagination
+}
+
+// MovementRequest — ручное пополнение или вывод средств
+type MovementRequest struct {
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Description string  `json:"description"`
+}
+
+// EntryListResponse представляет пагинированный ответ со списком проводок
+type EntryListResponse struct {
+	Data       []Entry `json:"data"`
+	Total      int64   `json:"total"`
+	Page       int     `json:"page"`
+	Limit      int     `json:"limit"`
+	TotalPages int     `json:"total_pages"`
+}
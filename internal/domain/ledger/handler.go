@@ -0,0 +1,163 @@
+package ledger
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// GetBalance godoc
+// @Summary      Баланс счёта
+// @Description  Возвращает текущий остаток счёта по журналу двойной записи и обороты по типам операций
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID счёта"
+// @Success      200 {object} Balance
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /accounts/{id}/balance [get]
+func (h *Handler) GetBalance(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	balance, err := h.useCase.GetBalance(c.Request.Context(), id)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get account balance", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// ListEntries godoc
+// @Summary      Журнал счёта
+// @Description  Возвращает проводки по счёту с остатком после каждой операции
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID счёта"
+// @Param        type query string false "Тип операции" Enums(deposit, withdrawal, trade_profit, commission)
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} EntryListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /accounts/{id}/ledger [get]
+func (h *Handler) ListEntries(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var filter EntryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.ListEntries(c.Request.Context(), id, &filter)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to list ledger entries", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Deposit godoc
+// @Summary      Пополнить счёт
+// @Description  Зачисляет средства на счёт от внешнего контрагента
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID счёта"
+// @Param        request body MovementRequest true "Сумма и комментарий"
+// @Success      201 {object} Entry
+// @Failure      400 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /accounts/{id}/deposits [post]
+func (h *Handler) Deposit(c *gin.Context) {
+	h.move(c, h.useCase.Deposit, "Failed to deposit to account")
+}
+
+// Withdraw godoc
+// @Summary      Вывести средства
+// @Description  Списывает средства со счёта; сумма не может превышать остаток
+// @Tags         ledger
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID счёта"
+// @Param        request body MovementRequest true "Сумма и комментарий"
+// @Success      201 {object} Entry
+// @Failure      400 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /accounts/{id}/withdrawals [post]
+func (h *Handler) Withdraw(c *gin.Context) {
+	h.move(c, h.useCase.Withdraw, "Failed to withdraw from account")
+}
+
+type movementFunc func(ctx context.Context, accountID int64, req *MovementRequest) (*Entry, error)
+
+func (h *Handler) move(c *gin.Context, fn movementFunc, failureMessage string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var req MovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := fn(c.Request.Context(), id, &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error(failureMessage, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
@@ -0,0 +1,203 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	GetBalance(ctx context.Context, accountID int64) (*Balance, error)
+	ListEntries(ctx context.Context, accountID int64, filter *EntryFilter) (*common.PaginatedResult[Entry], error)
+	Post(ctx context.Context, entryType EntryType, accountID int64, amount float64, description string) (*Entry, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
+	return &repository{db: db, logger: logger}
+}
+
+func (r *repository) GetBalance(ctx context.Context, accountID int64) (*Balance, error) {
+	query := `
+		SELECT a.id AS account_id, a.currency,
+		       COALESCE(b.balance, 0) AS balance, b.updated_at,
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.type = 'deposit'), 0) AS deposits,
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.type = 'withdrawal'), 0) AS withdrawals,
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.type = 'trade_profit'), 0) AS trading_profit,
+		       COALESCE(SUM(e.amount) FILTER (WHERE t.type = 'commission'), 0) AS commissions
+		FROM accounts a
+		LEFT JOIN account_balances b ON b.account_id = a.id
+		LEFT JOIN ledger_entries e ON e.account_id = a.id
+		LEFT JOIN ledger_transactions t ON t.id = e.transaction_id
+		WHERE a.id = $1
+		GROUP BY a.id, a.currency, b.balance, b.updated_at
+	`
+
+	var balance Balance
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &balance, query, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get account balance",
+			zap.Int64("account_id", accountID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get account balance: %w", err)
+	}
+
+	return &balance, nil
+}
+
+const entrySelect = `
+	SELECT e.id, e.transaction_id, t.type, e.account_id, e.amount, e.balance_after,
+	       c.account_id AS counterparty_account_id, c.system_account AS counterparty_system,
+	       t.copied_trade_id, t.commission_id, t.description, e.created_at
+	FROM ledger_entries e
+	JOIN ledger_transactions t ON t.id = e.transaction_id
+	LEFT JOIN LATERAL (
+		SELECT account_id, system_account
+		FROM ledger_entries c
+		WHERE c.transaction_id = e.transaction_id AND c.id <> e.id
+		ORDER BY c.system_account IS NOT DISTINCT FROM 'fx', c.id
+		LIMIT 1
+	) c ON true
+`
+
+func (r *repository) ListEntries(ctx context.Context, accountID int64, filter *EntryFilter) (*common.PaginatedResult[Entry], error) {
+	filter.SetDefaults()
+
+	var (
+		conditions = []string{"e.account_id = $1"}
+		args       = []interface{}{accountID}
+		argIndex   = 2
+	)
+
+	if filter.Type != "" {
+		conditions = append(conditions, fmt.Sprintf("t.type = $%d", argIndex))
+		args = append(args, filter.Type)
+		argIndex++
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("e.created_at >= $%d", argIndex))
+		args = append(args, filter.From)
+		argIndex++
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("e.created_at < $%d", argIndex))
+		args = append(args, filter.To)
+		argIndex++
+	}
+
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM ledger_entries e
+		JOIN ledger_transactions t ON t.id = e.transaction_id
+		%s
+	`, whereClause)
+	var total int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		r.logger.Error("Failed to count ledger entries", zap.Error(err))
+		return nil, fmt.Errorf("count ledger entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`%s
+		%s
+		ORDER BY e.id DESC
+		LIMIT $%d OFFSET $%d
+	`, entrySelect, whereClause, argIndex, argIndex+1)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	entries := []Entry{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &entries, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list ledger entries",
+			zap.Int64("account_id", accountID),
+			zap.Error(err))
+		return nil, fmt.Errorf("list ledger entries: %w", err)
+	}
+
+	return &common.PaginatedResult[Entry]{
+		Data:       entries,
+		Total:      total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// Post проводит ручное движение средств между счётом и внешним контрагентом.
+// amount положительный; для вывода он списывается со счёта под блокировкой
+// баланса, чтобы параллельные выводы не увели остаток в минус.
+func (r *repository) Post(ctx context.Context, entryType EntryType, accountID int64, amount float64, description string) (*Entry, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	signed := amount
+	if entryType == EntryTypeWithdrawal {
+		var balance float64
+		err := tx.GetContext(ctx, &balance, `
+			SELECT balance FROM account_balances WHERE account_id = $1 FOR UPDATE
+		`, accountID)
+		if err != nil && err != sql.ErrNoRows {
+			r.logger.Error("Failed to lock account balance",
+				zap.Int64("account_id", accountID),
+				zap.Error(err))
+			return nil, fmt.Errorf("lock account balance: %w", err)
+		}
+		if balance < amount {
+			return nil, ErrInsufficientFunds
+		}
+		signed = -amount
+	}
+
+	var transactionID int64
+	err = tx.GetContext(ctx, &transactionID, `
+		SELECT fn_ledger_post($1, $2, $3, NULL, $4, NULL, NULL, NULLIF($5, ''))
+	`, entryType, accountID, signed, SystemAccountExternal, description)
+	if err != nil {
+		r.logger.Error("Failed to post ledger transaction",
+			zap.Int64("account_id", accountID),
+			zap.String("type", string(entryType)),
+			zap.Float64("amount", amount),
+			zap.Error(err))
+		return nil, fmt.Errorf("post ledger transaction: %w", err)
+	}
+
+	var entry Entry
+	err = tx.GetContext(ctx, &entry, entrySelect+" WHERE e.transaction_id = $1 AND e.account_id = $2", transactionID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	r.logger.Info("Ledger transaction posted",
+		zap.Int64("transaction_id", transactionID),
+		zap.Int64("account_id", accountID),
+		zap.String("type", string(entryType)),
+		zap.Float64("amount", signed))
+
+	return &entry, nil
+}
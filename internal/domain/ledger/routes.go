@@ -0,0 +1,13 @@
+package ledger
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	accounts := router.Group("/accounts")
+	{
+		accounts.GET("/:id/balance", handler.GetBalance)
+		accounts.GET("/:id/ledger", handler.ListEntries)
+		accounts.POST("/:id/deposits", handler.Deposit)
+		accounts.POST("/:id/withdrawals", handler.Withdraw)
+	}
+}
@@ -0,0 +1,105 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/domain/account"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"go.uber.org/zap"
+)
+
+type UseCase interface {
+	GetBalance(ctx context.Context, accountID int64) (*Balance, error)
+	ListEntries(ctx context.Context, accountID int64, filter *EntryFilter) (*common.PaginatedResult[Entry], error)
+	Deposit(ctx context.Context, accountID int64, req *MovementRequest) (*Entry, error)
+	Withdraw(ctx context.Context, accountID int64, req *MovementRequest) (*Entry, error)
+}
+
+type useCase struct {
+	repo        Repository
+	accountRepo account.Repository
+	logger      *zap.Logger
+}
+
+func NewUseCase(repo Repository, accountRepo account.Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, accountRepo: accountRepo, logger: logger}
+}
+
+func (u *useCase) GetBalance(ctx context.Context, accountID int64) (*Balance, error) {
+	u.logger.Info("UseCase: Getting account balance", zap.Int64("account_id", accountID))
+
+	balance, err := u.repo.GetBalance(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("get account balance: %w", err)
+	}
+	if balance == nil {
+		return nil, account.ErrAccountNotFound
+	}
+
+	return balance, nil
+}
+
+func (u *useCase) ListEntries(ctx context.Context, accountID int64, filter *EntryFilter) (*common.PaginatedResult[Entry], error) {
+	filter.SetDefaults()
+	u.logger.Info("UseCase: Listing ledger entries",
+		zap.Int64("account_id", accountID),
+		zap.Any("filter", filter))
+
+	if _, err := u.getAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	entries, err := u.repo.ListEntries(ctx, accountID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list ledger entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (u *useCase) Deposit(ctx context.Context, accountID int64, req *MovementRequest) (*Entry, error) {
+	u.logger.Info("UseCase: Depositing to account",
+		zap.Int64("account_id", accountID),
+		zap.Float64("amount", req.Amount))
+
+	return u.move(ctx, EntryTypeDeposit, accountID, req)
+}
+
+func (u *useCase) Withdraw(ctx context.Context, accountID int64, req *MovementRequest) (*Entry, error) {
+	u.logger.Info("UseCase: Withdrawing from account",
+		zap.Int64("account_id", accountID),
+		zap.Float64("amount", req.Amount))
+
+	return u.move(ctx, EntryTypeWithdrawal, accountID, req)
+}
+
+// move проводит пополнение или вывод; управлять средствами может только владелец счёта
+func (u *useCase) move(ctx context.Context, entryType EntryType, accountID int64, req *MovementRequest) (*Entry, error) {
+	acc, err := u.getAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := auth.RequireUser(ctx, acc.UserID); err != nil {
+		return nil, err
+	}
+
+	entry, err := u.repo.Post(ctx, entryType, accountID, req.Amount, req.Description)
+	if err != nil {
+		return nil, fmt.Errorf("post %s: %w", entryType, err)
+	}
+
+	return entry, nil
+}
+
+func (u *useCase) getAccount(ctx context.Context, accountID int64) (*account.Account, error) {
+	acc, err := u.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("get account: %w", err)
+	}
+	if acc == nil {
+		return nil, account.ErrAccountNotFound
+	}
+	return acc, nil
+}
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+// daysPerYear — годовой пересчёт дневных доходностей: торговля идёт и в выходные
+const daysPerYear = 365
+
+// drawdownBuckets и volatilityBuckets — верхние границы просадки и годовой волатильности (в %) для оценок риска 1–9;
+// всё, что выше последней границы, получает 10
+var (
+	drawdownBuckets   = []float64{2, 5, 10, 15, 20, 30, 40, 50, 70}
+	volatilityBuckets = []float64{5, 10, 15, 20, 30, 40, 60, 80, 100}
+)
+
+// calculate считает метрики по закрытым сделкам, упорядоченным по времени закрытия.
+//
+// Кривая капитала начинается с capital и растёт на прибыль каждой сделки. Дневные
+// доходности берутся по календарным дням UTC от первого до последнего закрытия
+// (дни без сделок дают нулевую доходность) относительно капитала на начало дня.
+// Коэффициенты Шарпа и Сортино годовые, безрисковая ставка нулевая.
+func calculate(strategyID int64, capital *float64, trades []ClosedTrade, now time.Time) *Metrics {
+	m := &Metrics{
+		StrategyID:   strategyID,
+		Capital:      capital,
+		ClosedTrades: len(trades),
+		ComputedAt:   now,
+	}
+
+	var wins int
+	var grossProfit, grossLoss float64
+	for _, t := range trades {
+		m.TotalProfit += t.Profit
+		switch {
+		case t.Profit > 0:
+			wins++
+			grossProfit += t.Profit
+		case t.Profit < 0:
+			grossLoss -= t.Profit
+		}
+	}
+	m.TotalProfit = round(m.TotalProfit, 2)
+
+	if len(trades) == 0 {
+		return m
+	}
+
+	last := trades[len(trades)-1].CloseTime
+	m.LastTradeClose = &last
+	m.WinRate = ptr(round(float64(wins)/float64(len(trades))*100, 4))
+	if grossLoss > 0 {
+		m.ProfitFactor = ptr(round(grossProfit/grossLoss, 4))
+	}
+
+	if capital == nil || *capital <= 0 {
+		return m
+	}
+
+	m.ROI = ptr(round(m.TotalProfit / *capital * 100, 4))
+
+	drawdown := maxDrawdown(*capital, trades)
+	m.MaxDrawdownPct = ptr(round(drawdown, 4))
+
+	returns := dailyReturns(*capital, trades)
+	risk := bucket(drawdown, drawdownBuckets)
+
+	if len(returns) >= 2 {
+		mean, stdev := meanStdev(returns)
+		if stdev > 0 {
+			m.SharpeRatio = ptr(round(mean/stdev*math.Sqrt(daysPerYear), 4))
+			if vol := bucket(stdev*math.Sqrt(daysPerYear)*100, volatilityBuckets); vol > risk {
+				risk = vol
+			}
+		}
+		if downside := downsideDeviation(returns); downside > 0 {
+			m.SortinoRatio = ptr(round(mean/downside*math.Sqrt(daysPerYear), 4))
+		}
+	}
+
+	m.RiskScore = &risk
+	return m
+}
+
+// capitalOf оценивает стартовый капитал мастера: чистые пополнения счёта по журналу
+// (включая внесённое эквити), иначе эквити счёта
+func capitalOf(in *Inputs) *float64 {
+	for _, candidate := range []float64{in.NetDeposits, in.Equity} {
+		if candidate > 0 {
+			capital := round(candidate, 2)
+			return &capital
+		}
+	}
+	return nil
+}
+
+// maxDrawdown — наибольшее падение кривой капитала от предыдущего пика, в процентах от пика
+func maxDrawdown(capital float64, trades []ClosedTrade) float64 {
+	equity, peak, worst := capital, capital, 0.0
+	for _, t := range trades {
+		equity += t.Profit
+		if equity > peak {
+			peak = equity
+		}
+		if dd := (peak - equity) / peak * 100; dd > worst {
+			worst = dd
+		}
+	}
+	// Эквити ниже нуля — потеря всего капитала: при крошечном капитале иначе
+	// получаются тысячи процентов, которые не помещаются в max_drawdown_pct
+	return math.Min(worst, 100)
+}
+
+// dailyReturns возвращает доходности по дням; после обнуления капитала ряд обрывается
+func dailyReturns(capital float64, trades []ClosedTrade) []float64 {
+	byDay := make(map[time.Time]float64)
+	for _, t := range trades {
+		byDay[utcDay(t.CloseTime)] += t.Profit
+	}
+
+	first, last := utcDay(trades[0].CloseTime), utcDay(trades[len(trades)-1].CloseTime)
+
+	var returns []float64
+	equity := capital
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if equity <= 0 {
+			break
+		}
+		profit := byDay[day]
+		returns = append(returns, profit/equity)
+		equity += profit
+	}
+	return returns
+}
+
+func meanStdev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+func downsideDeviation(values []float64) float64 {
+	var sq float64
+	for _, v := range values {
+		if v < 0 {
+			sq += v * v
+		}
+	}
+	return math.Sqrt(sq / float64(len(values)))
+}
+
+func bucket(value float64, bounds []float64) int {
+	for i, bound := range bounds {
+		if value <= bound {
+			return i + 1
+		}
+	}
+	return len(bounds) + 1
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Metrics — показатели доходности и риска стратегии по закрытым сделкам мастера.
+// Показатели, для которых не хватает данных (нет капитала, убыточных дней и т.п.), равны nil.
+type Metrics struct {
+	StrategyID     int64      `json:"strategy_id" db:"strategy_id"`
+	Capital        *float64   `json:"capital,omitempty" db:"capital"`
+	ClosedTrades   int        `json:"closed_trades" db:"closed_trades"`
+	TotalProfit    float64    `json:"total_profit" db:"total_profit"`
+	ROI            *float64   `json:"roi,omitempty" db:"roi"`
+	MaxDrawdownPct *float64   `json:"max_drawdown_pct,omitempty" db:"max_drawdown_pct"`
+	SharpeRatio    *float64   `json:"sharpe_ratio,omitempty" db:"sharpe_ratio"`
+	SortinoRatio   *float64   `json:"sortino_ratio,omitempty" db:"sortino_ratio"`
+	WinRate        *float64   `json:"win_rate,omitempty" db:"win_rate"`
+	ProfitFactor   *float64   `json:"profit_factor,omitempty" db:"profit_factor"`
+	RiskScore      *int       `json:"risk_score,omitempty" db:"risk_score"`
+	LastTradeClose *time.Time `json:"last_trade_close,omitempty" db:"last_trade_close"`
+	ComputedAt     time.Time  `json:"computed_at" db:"computed_at"`
+}
+
+// RefreshResult — итог пересчёта устаревших метрик
+type RefreshResult struct {
+	Refreshed int `json:"refreshed"`
+	Failed    int `json:"failed"`
+}
+
+type ClosedTrade struct {
+	CloseTime time.Time `db:"close_time"`
+	Profit    float64   `db:"profit"`
+}
+
+// Inputs — исходные данные стратегии для пересчёта метрик
+type Inputs struct {
+	StrategyID  int64   `db:"strategy_id"`
+	NetDeposits float64 `db:"net_deposits"`
+	Equity      float64 `db:"equity"`
+	Trades      []ClosedTrade
+}
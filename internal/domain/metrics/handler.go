@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
+	return &Handler{useCase: useCase, logger: logger}
+}
+
+// Get godoc
+// @Summary      Метрики стратегии
+// @Description  Возвращает ROI, максимальную просадку, коэффициенты Шарпа и Сортино, долю прибыльных сделок, профит-фактор и оценку риска 1–10 по закрытым сделкам мастера
+// @Tags         strategies
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID стратегии"
+// @Success      200 {object} Metrics
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /strategies/{id}/metrics [get]
+func (h *Handler) Get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid strategy id"})
+		return
+	}
+
+	metrics, err := h.useCase.Get(c.Request.Context(), id)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get strategy metrics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// RefreshStale godoc
+// @Summary      Пересчитать метрики стратегий
+// @Description  Пересчитывает метрики стратегий с новыми закрытыми сделками, не дожидаясь воркера (только для администратора)
+// @Tags         strategies
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} RefreshResult
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /strategies/metrics/refresh [post]
+func (h *Handler) RefreshStale(c *gin.Context) {
+	result, err := h.useCase.RefreshStale(c.Request.Context())
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to refresh strategy metrics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
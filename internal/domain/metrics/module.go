@@ -0,0 +1,15 @@
+package metrics
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Options(
+	fx.Provide(
+		NewRepository,
+		NewUseCase,
+		NewHandler,
+		NewWorker,
+	),
+	fx.Invoke(func(*Worker) {}),
+)
@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Get(ctx context.Context, strategyID int64) (*Metrics, error)
+	Save(ctx context.Context, metrics *Metrics) (*Metrics, error)
+	LoadInputs(ctx context.Context, strategyID int64) (*Inputs, error)
+	ListStale(ctx context.Context, maxAge time.Duration, limit int) ([]int64, error)
+}
+
+type repository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
+	return &repository{db: db, logger: logger}
+}
+
+const metricsColumns = `strategy_id, capital, closed_trades, total_profit, roi, max_drawdown_pct,
+	sharpe_ratio, sortino_ratio, win_rate, profit_factor, risk_score, last_trade_close, computed_at`
+
+func (r *repository) Get(ctx context.Context, strategyID int64) (*Metrics, error) {
+	query := `SELECT ` + metricsColumns + ` FROM strategy_metrics WHERE strategy_id = $1`
+
+	var metrics Metrics
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &metrics, query, strategyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get strategy metrics",
+			zap.Int64("strategy_id", strategyID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get strategy metrics: %w", err)
+	}
+
+	return &metrics, nil
+}
+
+func (r *repository) Save(ctx context.Context, metrics *Metrics) (*Metrics, error) {
+	query := `
+		INSERT INTO strategy_metrics (
+			strategy_id, capital, closed_trades, total_profit, roi, max_drawdown_pct,
+			sharpe_ratio, sortino_ratio, win_rate, profit_factor, risk_score, last_trade_close, computed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (strategy_id) DO UPDATE SET
+			capital          = EXCLUDED.capital,
+			closed_trades    = EXCLUDED.closed_trades,
+			total_profit     = EXCLUDED.total_profit,
+			roi              = EXCLUDED.roi,
+			max_drawdown_pct = EXCLUDED.max_drawdown_pct,
+			sharpe_ratio     = EXCLUDED.sharpe_ratio,
+			sortino_ratio    = EXCLUDED.sortino_ratio,
+			win_rate         = EXCLUDED.win_rate,
+			profit_factor    = EXCLUDED.profit_factor,
+			risk_score       = EXCLUDED.risk_score,
+			last_trade_close = EXCLUDED.last_trade_close,
+			computed_at      = EXCLUDED.computed_at
+		RETURNING ` + metricsColumns
+
+	var saved Metrics
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
+		metrics.StrategyID,
+		metrics.Capital,
+		metrics.ClosedTrades,
+		metrics.TotalProfit,
+		metrics.ROI,
+		metrics.MaxDrawdownPct,
+		metrics.SharpeRatio,
+		metrics.SortinoRatio,
+		metrics.WinRate,
+		metrics.ProfitFactor,
+		metrics.RiskScore,
+		metrics.LastTradeClose,
+		metrics.ComputedAt,
+	).StructScan(&saved)
+	if err != nil {
+		r.logger.Error("Failed to save strategy metrics",
+			zap.Int64("strategy_id", metrics.StrategyID),
+			zap.Error(err))
+		return nil, fmt.Errorf("save strategy metrics: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// LoadInputs читает закрытые сделки мастера и данные для оценки капитала.
+// Возвращает nil, если стратегии нет.
+func (r *repository) LoadInputs(ctx context.Context, strategyID int64) (*Inputs, error) {
+	query := `
+		SELECT s.id AS strategy_id, a.equity,
+		       COALESCE((
+		           SELECT SUM(e.amount)
+		           FROM ledger_entries e
+		           JOIN ledger_transactions t ON t.id = e.transaction_id
+		           WHERE e.account_id = s.master_account_id
+		             AND t.type IN ('deposit', 'withdrawal')
+		       ), 0) AS net_deposits
+		FROM strategies s
+		JOIN accounts a ON a.id = s.master_account_id
+		WHERE s.id = $1
+	`
+
+	var in Inputs
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &in, query, strategyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to load strategy metrics Inputs",
+			zap.Int64("strategy_id", strategyID),
+			zap.Error(err))
+		return nil, fmt.Errorf("load strategy metrics Inputs: %w", err)
+	}
+
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &in.Trades, `
+		SELECT close_time, profit
+		FROM trades
+		WHERE strategy_id = $1 AND close_time IS NOT NULL AND profit IS NOT NULL
+		ORDER BY close_time, id
+	`, strategyID)
+	if err != nil {
+		r.logger.Error("Failed to load closed trades",
+			zap.Int64("strategy_id", strategyID),
+			zap.Error(err))
+		return nil, fmt.Errorf("load closed trades: %w", err)
+	}
+
+	return &in, nil
+}
+
+// ListStale возвращает стратегии без метрик, с новыми закрытыми сделками или
+// с метриками старше maxAge (капитал мог измениться пополнениями)
+func (r *repository) ListStale(ctx context.Context, maxAge time.Duration, limit int) ([]int64, error) {
+	query := `
+		SELECT s.id
+		FROM strategies s
+		LEFT JOIN strategy_metrics m ON m.strategy_id = s.id
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*)::INT AS closed_trades, MAX(t.close_time) AS last_trade_close
+			FROM trades t
+			WHERE t.strategy_id = s.id AND t.close_time IS NOT NULL AND t.profit IS NOT NULL
+		) t ON true
+		WHERE s.status <> 'deleted'
+		  AND (
+		      m.strategy_id IS NULL
+		      OR m.closed_trades <> t.closed_trades
+		      OR m.last_trade_close IS DISTINCT FROM t.last_trade_close
+		      OR m.computed_at < now() - $1::interval
+		  )
+		ORDER BY m.computed_at NULLS FIRST, s.id
+		LIMIT $2
+	`
+
+	var ids []int64
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &ids, query, fmt.Sprintf("%d seconds", int64(maxAge.Seconds())), limit)
+	if err != nil {
+		r.logger.Error("Failed to list stale strategy metrics", zap.Error(err))
+		return nil, fmt.Errorf("list stale strategy metrics: %w", err)
+	}
+
+	return ids, nil
+}
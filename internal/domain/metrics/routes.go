@@ -0,0 +1,11 @@
+package metrics
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
+	strategies := router.Group("/strategies")
+	{
+		strategies.GET("/:id/metrics", handler.Get)
+		strategies.POST("/metrics/refresh", handler.RefreshStale)
+	}
+}
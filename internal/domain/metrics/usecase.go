@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/strategy"
+	"go.uber.org/zap"
+)
+
+const (
+	// metricsMaxAge — метрики без новых сделок всё равно пересчитываются раз в сутки
+	metricsMaxAge = 24 * time.Hour
+	// refreshBatchSize — сколько стратегий пересчитывается за один проход
+	refreshBatchSize = 100
+)
+
+type UseCase interface {
+	Get(ctx context.Context, strategyID int64) (*Metrics, error)
+	Refresh(ctx context.Context, strategyID int64) (*Metrics, error)
+	RefreshStale(ctx context.Context) (*RefreshResult, error)
+}
+
+type useCase struct {
+	repo   Repository
+	logger *zap.Logger
+}
+
+func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, logger: logger}
+}
+
+// Get возвращает сохранённые метрики; если воркер ещё не успел их посчитать, считает их
+// на лету, не сохраняя: чтение ничего не пишет, метрики сохраняет только воркер
+func (u *useCase) Get(ctx context.Context, strategyID int64) (*Metrics, error) {
+	u.logger.Info("UseCase: Getting strategy metrics", zap.Int64("strategy_id", strategyID))
+
+	metrics, err := u.repo.Get(ctx, strategyID)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy metrics: %w", err)
+	}
+	if metrics != nil {
+		return metrics, nil
+	}
+
+	return u.compute(ctx, strategyID)
+}
+
+func (u *useCase) Refresh(ctx context.Context, strategyID int64) (*Metrics, error) {
+	u.logger.Info("UseCase: Refreshing strategy metrics", zap.Int64("strategy_id", strategyID))
+
+	computed, err := u.compute(ctx, strategyID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := u.repo.Save(ctx, computed)
+	if err != nil {
+		return nil, fmt.Errorf("save strategy metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// compute считает метрики стратегии по её закрытым сделкам, не сохраняя их
+func (u *useCase) compute(ctx context.Context, strategyID int64) (*Metrics, error) {
+	in, err := u.repo.LoadInputs(ctx, strategyID)
+	if err != nil {
+		return nil, fmt.Errorf("load strategy metrics inputs: %w", err)
+	}
+	if in == nil {
+		return nil, strategy.ErrStrategyNotFound
+	}
+
+	return calculate(strategyID, capitalOf(in), in.Trades, time.Now()), nil
+}
+
+// RefreshStale пересчитывает метрики стратегий с новыми сделками (только для администратора)
+func (u *useCase) RefreshStale(ctx context.Context) (*RefreshResult, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	ids, err := u.repo.ListStale(ctx, metricsMaxAge, refreshBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("list stale strategy metrics: %w", err)
+	}
+
+	result := &RefreshResult{}
+	for _, id := range ids {
+		if _, err := u.Refresh(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result.Failed++
+			u.logger.Error("Failed to refresh strategy metrics",
+				zap.Int64("strategy_id", id),
+				zap.Error(err))
+			continue
+		}
+		result.Refreshed++
+	}
+
+	return result, nil
+}
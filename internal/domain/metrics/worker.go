@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/worker"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const metricsPollInterval = time.Minute
+
+// Worker периодически пересчитывает устаревшие метрики стратегий
+type Worker struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewWorker(lc fx.Lifecycle, useCase UseCase, logger *zap.Logger) *Worker {
+	w := &Worker{useCase: useCase, logger: logger}
+	worker.Start(lc, logger, "Metrics worker", metricsPollInterval, w.runOnce)
+	return w
+}
+
+func (w *Worker) runOnce(ctx context.Context) error {
+	result, err := w.useCase.RefreshStale(ctx)
+	if err != nil {
+		return err
+	}
+
+	if result.Refreshed > 0 || result.Failed > 0 {
+		w.logger.Info("Metrics refresh done",
+			zap.Int("refreshed", result.Refreshed),
+			zap.Int("failed", result.Failed))
+	}
+	return nil
+}
@@ -4,6 +4,10 @@ import (
 	"github.com/finlleyl/cp_database/internal/domain/account"
 	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/batchimport"
+	"github.com/finlleyl/cp_database/internal/domain/billing"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
+	"github.com/finlleyl/cp_database/internal/domain/ledger"
+	"github.com/finlleyl/cp_database/internal/domain/metrics"
 	"github.com/finlleyl/cp_database/internal/domain/offer"
 	"github.com/finlleyl/cp_database/internal/domain/statistics"
 	"github.com/finlleyl/cp_database/internal/domain/strategy"
@@ -24,6 +28,10 @@ var Module = fx.Options(
 	trade.Module,
 
 	statistics.Module,
+	billing.Module,
+	ledger.Module,
+	currency.Module,
+	metrics.Module,
 	batchimport.Module,
 	audit.Module,
 )
@@ -1,11 +1,14 @@
 package offer
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+var ErrOfferNotFound = common.NewError(http.StatusNotFound, "offer_not_found", "offer not found")
+
 type Offer struct {
 	ID                    int64              `json:"id" db:"id"`
 	StrategyID            int64              `json:"strategy_id" db:"strategy_id"`
@@ -14,6 +17,7 @@ type Offer struct {
 	PerformanceFeePercent *float64           `json:"performance_fee_percent" db:"performance_fee_percent"`
 	ManagementFeePercent  *float64           `json:"management_fee_percent" db:"management_fee_percent"`
 	RegistrationFeeAmount *float64           `json:"registration_fee_amount" db:"registration_fee_amount"`
+	FeeInterval           common.FeeInterval `json:"fee_interval" db:"fee_interval"`
 	CreatedAt             time.Time          `json:"created_at" db:"created_at"`
 	UpdatedAt             time.Time          `json:"updated_at" db:"updated_at"`
 }
@@ -24,6 +28,16 @@ type CreateOfferRequest struct {
 	PerformanceFeePercent *float64 `json:"performance_fee_percent"`
 	ManagementFeePercent  *float64 `json:"management_fee_percent"`
 	RegistrationFeeAmount *float64 `json:"registration_fee_amount"`
+
+	FeeInterval common.FeeInterval `json:"fee_interval,omitempty" binding:"omitempty,oneof=daily weekly monthly"`
+}
+
+// Interval возвращает период начисления комиссий, по умолчанию — ежемесячный
+func (r *CreateOfferRequest) Interval() common.FeeInterval {
+	if r.FeeInterval == "" {
+		return common.FeeIntervalMonthly
+	}
+	return r.FeeInterval
 }
 
 type UpdateOfferRequest struct {
@@ -31,10 +45,13 @@ type UpdateOfferRequest struct {
 	PerformanceFeePercent *float64 `json:"performance_fee_percent,omitempty"`
 	ManagementFeePercent  *float64 `json:"management_fee_percent,omitempty"`
 	RegistrationFeeAmount *float64 `json:"registration_fee_amount,omitempty"`
+
+	FeeInterval *common.FeeInterval `json:"fee_interval,omitempty" binding:"omitempty,oneof=daily weekly monthly"`
 }
 
 type ChangeStatusRequest struct {
-	Status common.OfferStatus `json:"status" binding:"required,oneof=active archived deleted"`
+	Status       common.OfferStatus `json:"status" binding:"required,oneof=active archived deleted"`
+	StatusReason string             `json:"status_reason"`
 }
 
 type OfferFilter struct {
@@ -50,4 +67,15 @@ type OfferListResponse struct {
 	Page       int     `json:"page"`
 	Limit      int     `json:"limit"`
 	TotalPages int     `json:"total_pages"`
-}
\ No newline at end of file
+}
+
+// StatusHistory — смена статуса оффера; автор пуст для изменений воркеров
+type StatusHistory struct {
+	ID        int64              `json:"id" db:"id"`
+	OfferID   int64              `json:"offer_id" db:"offer_id"`
+	OldStatus common.OfferStatus `json:"old_status" db:"old_status"`
+	NewStatus common.OfferStatus `json:"new_status" db:"new_status"`
+	Reason    *string            `json:"reason,omitempty" db:"reason"`
+	ChangedBy *int64             `json:"changed_by,omitempty" db:"changed_by"`
+	CreatedAt time.Time          `json:"created_at" db:"created_at"`
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -178,3 +179,36 @@ func (h *Handler) ChangeStatus(c *gin.Context) {
 
 	c.JSON(http.StatusOK, offer)
 }
+
+// GetStatusHistory godoc
+// @Summary      История статусов оффера
+// @Description  Возвращает смены статуса оффера с причиной и автором, начиная с последней
+// @Tags         offers
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID оффера"
+// @Success      200 {array} StatusHistory
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /offers/{id}/status-history [get]
+func (h *Handler) GetStatusHistory(c *gin.Context) {
+	offerID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offer id"})
+		return
+	}
+
+	history, err := h.useCase.GetStatusHistory(c.Request.Context(), offerID)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get offer status history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
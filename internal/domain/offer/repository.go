@@ -7,6 +7,7 @@ import (
 	"math"
 	"strings"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -20,6 +21,7 @@ type Repository interface {
 	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Offer, error)
 	GetByStrategyID(ctx context.Context, strategyID int64) ([]*Offer, error)
 	GetActiveByStrategyID(ctx context.Context, strategyID int64) ([]*Offer, error)
+	GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error)
 }
 
 type repository struct {
@@ -33,19 +35,20 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 
 func (r *repository) Create(ctx context.Context, req *CreateOfferRequest) (*Offer, error) {
 	query := `
-		INSERT INTO offers (strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		INSERT INTO offers (strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 	`
 
 	var offer Offer
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.StrategyID,
 		req.Name,
 		common.OfferStatusActive,
 		req.PerformanceFeePercent,
 		req.ManagementFeePercent,
 		req.RegistrationFeeAmount,
+		req.Interval(),
 	).StructScan(&offer)
 	if err != nil {
 		r.logger.Error("Failed to create offer",
@@ -64,13 +67,13 @@ func (r *repository) Create(ctx context.Context, req *CreateOfferRequest) (*Offe
 
 func (r *repository) GetByID(ctx context.Context, id int64) (*Offer, error) {
 	query := `
-		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 		FROM offers
 		WHERE id = $1
 	`
 
 	var offer Offer
-	err := r.db.GetContext(ctx, &offer, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &offer, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -112,14 +115,14 @@ func (r *repository) List(ctx context.Context, filter *OfferFilter) (*common.Pag
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM offers %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count offers", zap.Error(err))
 		return nil, fmt.Errorf("count offers: %w", err)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 		FROM offers
 		%s
 		ORDER BY created_at DESC
@@ -129,7 +132,7 @@ func (r *repository) List(ctx context.Context, filter *OfferFilter) (*common.Pag
 	args = append(args, filter.Limit, filter.Offset)
 
 	var offers []Offer
-	err = r.db.SelectContext(ctx, &offers, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &offers, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list offers", zap.Error(err))
 		return nil, fmt.Errorf("list offers: %w", err)
@@ -175,6 +178,12 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateOfferReque
 		argIndex++
 	}
 
+	if req.FeeInterval != nil {
+		setClauses = append(setClauses, fmt.Sprintf("fee_interval = $%d", argIndex))
+		args = append(args, *req.FeeInterval)
+		argIndex++
+	}
+
 	if len(setClauses) == 0 {
 		return r.GetByID(ctx, id)
 	}
@@ -186,11 +195,11 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateOfferReque
 		UPDATE offers
 		SET %s
 		WHERE id = $%d
-		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var offer Offer
-	err := r.db.QueryRowxContext(ctx, query, args...).StructScan(&offer)
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query, args...).StructScan(&offer)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("offer not found: %d", id)
@@ -211,11 +220,21 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 		UPDATE offers
 		SET status = $1, updated_at = now()
 		WHERE id = $2
-		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		RETURNING id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 	`
 
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingStatusReason, req.StatusReason); err != nil {
+		return nil, err
+	}
+
 	var offer Offer
-	err := r.db.QueryRowxContext(ctx, query, req.Status, id).StructScan(&offer)
+	err = tx.QueryRowxContext(ctx, query, req.Status, id).StructScan(&offer)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("offer not found: %d", id)
@@ -227,6 +246,10 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 		return nil, fmt.Errorf("change offer status: %w", err)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
 	r.logger.Info("Offer status changed",
 		zap.Int64("id", offer.ID),
 		zap.String("status", string(offer.Status)))
@@ -236,14 +259,14 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 
 func (r *repository) GetByStrategyID(ctx context.Context, strategyID int64) ([]*Offer, error) {
 	query := `
-		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 		FROM offers
 		WHERE strategy_id = $1
 		ORDER BY created_at DESC
 	`
 
 	var offers []*Offer
-	err := r.db.SelectContext(ctx, &offers, query, strategyID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &offers, query, strategyID)
 	if err != nil {
 		r.logger.Error("Failed to get offers by strategy ID",
 			zap.Int64("strategy_id", strategyID),
@@ -256,14 +279,14 @@ func (r *repository) GetByStrategyID(ctx context.Context, strategyID int64) ([]*
 
 func (r *repository) GetActiveByStrategyID(ctx context.Context, strategyID int64) ([]*Offer, error) {
 	query := `
-		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, created_at, updated_at
+		SELECT id, strategy_id, name, status, performance_fee_percent, management_fee_percent, registration_fee_amount, fee_interval, created_at, updated_at
 		FROM offers
 		WHERE strategy_id = $1 AND status = 'active'
 		ORDER BY created_at DESC
 	`
 
 	var offers []*Offer
-	err := r.db.SelectContext(ctx, &offers, query, strategyID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &offers, query, strategyID)
 	if err != nil {
 		r.logger.Error("Failed to get active offers by strategy ID",
 			zap.Int64("strategy_id", strategyID),
@@ -273,3 +296,23 @@ func (r *repository) GetActiveByStrategyID(ctx context.Context, strategyID int64
 
 	return offers, nil
 }
+
+func (r *repository) GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error) {
+	query := `
+		SELECT id, offer_id, old_status, new_status, reason, changed_by, created_at
+		FROM offer_status_history
+		WHERE offer_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	history := []*StatusHistory{}
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &history, query, id)
+	if err != nil {
+		r.logger.Error("Failed to get offer status history",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get offer status history: %w", err)
+	}
+
+	return history, nil
+}
@@ -12,5 +12,6 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 		offers.GET("/:id", h.GetByID)
 		offers.PUT("/:id", h.Update)
 		offers.POST("/:id/status", h.ChangeStatus)
+		offers.GET("/:id/status-history", h.GetStatusHistory)
 	}
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/finlleyl/cp_database/internal/domain/strategy"
 	"go.uber.org/zap"
@@ -16,25 +15,23 @@ type UseCase interface {
 	List(ctx context.Context, filter *OfferFilter) (*common.PaginatedResult[Offer], error)
 	Update(ctx context.Context, id int64, req *UpdateOfferRequest) (*Offer, error)
 	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Offer, error)
+	GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error)
 }
 
 type useCase struct {
 	repo         Repository
 	strategyRepo strategy.Repository
-	auditRepo    audit.Repository
 	logger       *zap.Logger
 }
 
 func NewUseCase(
 	repo Repository,
 	strategyRepo strategy.Repository,
-	auditRepo audit.Repository,
 	logger *zap.Logger,
 ) UseCase {
 	return &useCase{
 		repo:         repo,
 		strategyRepo: strategyRepo,
-		auditRepo:    auditRepo,
 		logger:       logger,
 	}
 }
@@ -57,13 +54,6 @@ func (u *useCase) Create(ctx context.Context, req *CreateOfferRequest) (*Offer,
 		return nil, fmt.Errorf("create offer: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeOffer,
-		EntityID:   offer.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   offer,
-	})
-
 	return offer, nil
 }
 
@@ -81,24 +71,11 @@ func (u *useCase) List(ctx context.Context, filter *OfferFilter) (*common.Pagina
 func (u *useCase) Update(ctx context.Context, id int64, req *UpdateOfferRequest) (*Offer, error) {
 	u.logger.Info("UseCase: Updating offer", zap.Int64("id", id))
 
-	oldOffer, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get offer: %w", err)
-	}
-
 	offer, err := u.repo.Update(ctx, id, req)
 	if err != nil {
 		return nil, fmt.Errorf("update offer: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeOffer,
-		EntityID:   offer.ID,
-		Action:     audit.AuditActionUpdate,
-		OldValue:   oldOffer,
-		NewValue:   offer,
-	})
-
 	return offer, nil
 }
 
@@ -107,27 +84,29 @@ func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusR
 		zap.Int64("id", id),
 		zap.String("status", string(req.Status)))
 
-	oldOffer, err := u.repo.GetByID(ctx, id)
+	offer, err := u.repo.ChangeStatus(ctx, id, req)
 	if err != nil {
-		return nil, fmt.Errorf("get offer: %w", err)
+		return nil, fmt.Errorf("change offer status: %w", err)
 	}
 
-	offer, err := u.repo.ChangeStatus(ctx, id, req)
+	return offer, nil
+}
+
+func (u *useCase) GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error) {
+	u.logger.Info("UseCase: Getting offer status history", zap.Int64("id", id))
+
+	offer, err := u.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("change offer status: %w", err)
+		return nil, fmt.Errorf("get offer: %w", err)
+	}
+	if offer == nil {
+		return nil, ErrOfferNotFound
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeOffer,
-		EntityID:   offer.ID,
-		Action:     audit.AuditActionStatusChange,
-		OldValue:   oldOffer,
-		NewValue:   offer,
-		Changes: map[string]interface{}{
-			"old_status": oldOffer.Status,
-			"new_status": offer.Status,
-		},
-	})
+	history, err := u.repo.GetStatusHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get offer status history: %w", err)
+	}
 
-	return offer, nil
+	return history, nil
 }
@@ -4,16 +4,29 @@ import (
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+// LeaderboardRequest — критерий и окно рейтинга; по умолчанию по прибыли за всё время
 type LeaderboardRequest struct {
-	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+	RankBy   LeaderboardRankBy     `form:"rank_by" binding:"omitempty,oneof=profit roi active_subscriptions commissions risk_adjusted"`
+	Period   Period                `form:"period" binding:"omitempty,oneof=day week month year all"`
+	Status   common.StrategyStatus `form:"status" binding:"omitempty,oneof=preparing active archived deleted"`
+	Currency string                `form:"currency" binding:"omitempty,len=3,alpha"`
+	common.Pagination
 }
 
 type InvestorPortfolioRequest struct {
-	UserID int64 `form:"user_id" binding:"required"`
+	UserID   int64  `form:"user_id" binding:"required"`
+	Currency string `form:"currency" binding:"omitempty,len=3,alpha"`
 }
 
 type MasterIncomeRequest struct {
-	UserID int64 `form:"user_id" binding:"required"`
+	UserID   int64  `form:"user_id" binding:"required"`
+	Currency string `form:"currency" binding:"omitempty,len=3,alpha"`
+	common.TimeRange
+}
+
+// EquityCurveRequest — шаг кривой капитала и период [from, to)
+type EquityCurveRequest struct {
+	Interval Period `form:"interval" binding:"omitempty,oneof=day week month"`
 	common.TimeRange
 }
 
@@ -21,3 +34,30 @@ type AccountStatisticsRequest struct {
 	AccountID int64  `uri:"account_id" binding:"required"`
 	Period    Period `form:"period" binding:"omitempty,oneof=day week month year all"`
 }
+
+type CommissionFilter struct {
+	SubscriptionID int64          `form:"subscription_id"`
+	StrategyID     int64          `form:"strategy_id"`
+	Type           CommissionType `form:"type" binding:"omitempty,oneof=performance management registration"`
+	Currency       string         `form:"currency" binding:"omitempty,len=3,alpha"`
+	common.TimeRange
+	common.Pagination
+}
+
+// LeaderboardResponse представляет пагинированный ответ с лидербордом стратегий
+type LeaderboardResponse struct {
+	Data       []LeaderboardEntry `json:"data"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	TotalPages int                `json:"total_pages"`
+}
+
+// CommissionListResponse представляет пагинированный ответ со списком комиссий
+type CommissionListResponse struct {
+	Data       []Commission `json:"data"`
+	Total      int64        `json:"total"`
+	Page       int          `json:"page"`
+	Limit      int          `json:"limit"`
+	TotalPages int          `json:"total_pages"`
+}
@@ -2,6 +2,8 @@ package statistics
 
 import (
 	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
 type Period string
@@ -14,16 +16,58 @@ const (
 	PeriodAll   Period = "all"
 )
 
+// Start возвращает начало скользящего окна периода, заканчивающегося в now;
+// для PeriodAll и пустого периода окно не ограничено и возвращается nil.
+func (p Period) Start(now time.Time) *time.Time {
+	var start time.Time
+	switch p {
+	case PeriodDay:
+		start = now.AddDate(0, 0, -1)
+	case PeriodWeek:
+		start = now.AddDate(0, 0, -7)
+	case PeriodMonth:
+		start = now.AddDate(0, -1, 0)
+	case PeriodYear:
+		start = now.AddDate(-1, 0, 0)
+	default:
+		return nil
+	}
+	return &start
+}
+
+// LeaderboardRankBy — критерий ранжирования лидерборда
+type LeaderboardRankBy string
+
+const (
+	LeaderboardRankByProfit              LeaderboardRankBy = "profit"
+	LeaderboardRankByROI                 LeaderboardRankBy = "roi"
+	LeaderboardRankByActiveSubscriptions LeaderboardRankBy = "active_subscriptions"
+	LeaderboardRankByCommissions         LeaderboardRankBy = "commissions"
+	LeaderboardRankByRiskAdjusted        LeaderboardRankBy = "risk_adjusted"
+)
+
+// StrategyLeaderboard — место стратегии за период. RankChange — на сколько мест стратегия
+// поднялась с предыдущего такого же периода (отрицательное — опустилась); для периода all
+// и стратегий, созданных позже начала периода, не заполняется.
 type StrategyLeaderboard struct {
-	StrategyID          int64   `json:"strategy_id" db:"strategy_id"`
-	Title               string  `json:"title" db:"title"`
-	TotalProfit         float64 `json:"total_profit" db:"total_profit"`
-	TotalCommissions    float64 `json:"total_commissions" db:"total_commissions"`
-	ActiveSubscriptions int     `json:"active_subscriptions" db:"active_subscriptions"`
+	StrategyID          int64                 `json:"strategy_id" db:"strategy_id"`
+	Title               string                `json:"title" db:"title"`
+	Status              common.StrategyStatus `json:"status" db:"status"`
+	TotalProfit         float64               `json:"total_profit" db:"total_profit"`
+	ROI                 *float64              `json:"roi,omitempty" db:"roi"`
+	TotalCommissions    float64               `json:"total_commissions" db:"total_commissions"`
+	ActiveSubscriptions int                   `json:"active_subscriptions" db:"active_subscriptions"`
+	RiskScore           *int                  `json:"risk_score,omitempty" db:"risk_score"`
+	RiskAdjusted        *float64              `json:"risk_adjusted,omitempty" db:"risk_adjusted"`
+	Rank                int                   `json:"rank" db:"rank"`
+	PreviousRank        *int                  `json:"previous_rank,omitempty" db:"previous_rank"`
+	RankChange          *int                  `json:"rank_change,omitempty" db:"rank_change"`
+	Currency            string                `json:"currency" db:"-"`
 }
 
 type InvestorPortfolio struct {
 	UserID        int64           `json:"user_id"`
+	Currency      string          `json:"currency"`
 	Subscriptions []PortfolioItem `json:"subscriptions"`
 }
 
@@ -37,6 +81,7 @@ type PortfolioItem struct {
 
 type MasterIncome struct {
 	UserID           int64   `json:"user_id"`
+	Currency         string  `json:"currency"`
 	TotalIncome      float64 `json:"total_income"`
 	PerformanceFees  float64 `json:"performance_fees"`
 	ManagementFees   float64 `json:"management_fees"`
@@ -50,6 +95,8 @@ type Commission struct {
 	Amount         float64        `json:"amount" db:"amount"`
 	PeriodFrom     *time.Time     `json:"period_from,omitempty" db:"period_from"`
 	PeriodTo       *time.Time     `json:"period_to,omitempty" db:"period_to"`
+	Currency       string         `json:"currency,omitempty" db:"currency"`
+	InvoiceID      *int64         `json:"invoice_id,omitempty" db:"invoice_id"`
 	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
 }
 
@@ -61,6 +108,86 @@ const (
 	CommissionTypeRegistration CommissionType = "registration"
 )
 
+// EquityCurve — накопленная прибыль закрытых сделок мастера по интервалам, в валюте счёта мастера
+type EquityCurve struct {
+	StrategyID int64         `json:"strategy_id" db:"strategy_id"`
+	Interval   Period        `json:"interval" db:"-"`
+	Currency   string        `json:"currency" db:"currency"`
+	Capital    *float64      `json:"capital,omitempty" db:"capital"`
+	Points     []EquityPoint `json:"points" db:"-"`
+}
+
+// EquityPoint — интервал кривой капитала. Просадка считается на конец интервала
+// от пика накопленной прибыли; в процентах — относительно капитала на пике.
+type EquityPoint struct {
+	Bucket           time.Time `json:"bucket" db:"bucket"`
+	Profit           float64   `json:"profit" db:"profit"`
+	CumulativeProfit float64   `json:"cumulative_profit" db:"cumulative_profit"`
+	Drawdown         float64   `json:"drawdown" db:"drawdown"`
+	DrawdownPct      *float64  `json:"drawdown_pct,omitempty" db:"drawdown_pct"`
+	TradesCount      int       `json:"trades_count" db:"trades_count"`
+}
+
+// AccountStatistics — итоги торговли счёта за период в валюте счёта. Для счёта мастера
+// учитываются сделки мастера и заработанные комиссии, для счёта инвестора — скопированные
+// сделки и уплаченные комиссии. Открытая позиция не зависит от периода.
+type AccountStatistics struct {
+	AccountID         int64         `json:"account_id" db:"account_id"`
+	AccountType       string        `json:"account_type" db:"account_type"`
+	Currency          string        `json:"currency" db:"currency"`
+	Period            Period        `json:"period" db:"-"`
+	From              *time.Time    `json:"from,omitempty" db:"-"`
+	To                time.Time     `json:"to" db:"-"`
+	TradesCount       int           `json:"trades_count" db:"trades_count"`
+	Volume            float64       `json:"volume" db:"volume"`
+	TradingProfit     float64       `json:"trading_profit" db:"trading_profit"`
+	CommissionsPaid   float64       `json:"commissions_paid" db:"commissions_paid"`
+	CommissionsEarned float64       `json:"commissions_earned" db:"commissions_earned"`
+	NetProfit         float64       `json:"net_profit" db:"net_profit"`
+	BestSymbol        *SymbolResult `json:"best_symbol,omitempty" db:"-"`
+	WorstSymbol       *SymbolResult `json:"worst_symbol,omitempty" db:"-"`
+	OpenExposure      OpenExposure  `json:"open_exposure" db:"-"`
+}
+
+// SymbolResult — прибыль закрытых сделок счёта по инструменту
+type SymbolResult struct {
+	Symbol      string  `json:"symbol" db:"symbol"`
+	TradesCount int     `json:"trades_count" db:"trades_count"`
+	Volume      float64 `json:"volume" db:"volume"`
+	Profit      float64 `json:"profit" db:"profit"`
+}
+
+// OpenExposure — открытые позиции счёта: суммарный объём и разбивка по инструментам
+type OpenExposure struct {
+	PositionsCount int              `json:"positions_count"`
+	Volume         float64          `json:"volume"`
+	Symbols        []SymbolExposure `json:"symbols"`
+}
+
+// SymbolExposure — открытый объём по инструменту; net_volume = long_volume - short_volume
+type SymbolExposure struct {
+	Symbol         string  `json:"symbol" db:"symbol"`
+	PositionsCount int     `json:"positions_count" db:"positions_count"`
+	LongVolume     float64 `json:"long_volume" db:"long_volume"`
+	ShortVolume    float64 `json:"short_volume" db:"short_volume"`
+	NetVolume      float64 `json:"net_volume" db:"net_volume"`
+}
+
+// StatsDrift — расхождение счётчика strategy_stats с пересчётом по исходным таблицам
+type StatsDrift struct {
+	StrategyID int64   `json:"strategy_id" db:"strategy_id"`
+	Field      string  `json:"field" db:"field"`
+	Stored     float64 `json:"stored" db:"stored"`
+	Actual     float64 `json:"actual" db:"actual"`
+}
+
+// StatsRebuildResult — итог полного пересчёта strategy_stats
+type StatsRebuildResult struct {
+	Strategies int          `json:"strategies"`
+	Drifted    int          `json:"drifted"`
+	Drift      []StatsDrift `json:"drift"`
+}
+
 type CreateCommissionRequest struct {
 	SubscriptionID int64          `json:"subscription_id" binding:"required"`
 	Type           CommissionType `json:"type" binding:"required,oneof=performance management registration"`
@@ -71,11 +198,19 @@ type CreateCommissionRequest struct {
 
 // LeaderboardEntry представляет запись в лидерборде стратегий
 type LeaderboardEntry struct {
-	StrategyID          int64   `json:"strategy_id"`
-	Title               string  `json:"title"`
-	TotalProfit         float64 `json:"total_profit"`
-	TotalCommissions    float64 `json:"total_commissions"`
-	ActiveSubscriptions int     `json:"active_subscriptions"`
+	StrategyID          int64    `json:"strategy_id"`
+	Title               string   `json:"title"`
+	Status              string   `json:"status"`
+	TotalProfit         float64  `json:"total_profit"`
+	ROI                 *float64 `json:"roi,omitempty"`
+	TotalCommissions    float64  `json:"total_commissions"`
+	ActiveSubscriptions int      `json:"active_subscriptions"`
+	RiskScore           *int     `json:"risk_score,omitempty"`
+	RiskAdjusted        *float64 `json:"risk_adjusted,omitempty"`
+	Rank                int      `json:"rank"`
+	PreviousRank        *int     `json:"previous_rank,omitempty"`
+	RankChange          *int     `json:"rank_change,omitempty"`
+	Currency            string   `json:"currency"`
 }
 
 // PortfolioEntry представляет запись в портфеле инвестора
@@ -85,4 +220,4 @@ type PortfolioEntry struct {
 	StrategyTitle     string  `json:"strategy_title"`
 	TotalProfit       float64 `json:"total_profit"`
 	CopiedTradesCount int64   `json:"copied_trades_count"`
-}
\ No newline at end of file
+}
@@ -2,7 +2,9 @@ package statistics
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -18,13 +20,19 @@ func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
 
 // GetStrategyLeaderboard godoc
 // @Summary      Лидерборд стратегий
-// @Description  Возвращает топ стратегий по доходности
+// @Description  Возвращает рейтинг стратегий за период с изменением места относительно предыдущего такого же периода. Прибыль пересчитывается в валюту currency по курсу на дату закрытия сделки, комиссии — на конец периода начисления
 // @Tags         statistics
 // @Accept       json
 // @Produce      json
-// @Param        limit query int false "Количество записей" default(10)
-// @Success      200 {array} LeaderboardEntry
+// @Param        rank_by query string false "Критерий рейтинга" Enums(profit, roi, active_subscriptions, commissions, risk_adjusted) default(profit)
+// @Param        period query string false "Период" Enums(day, week, month, year, all) default(all)
+// @Param        status query string false "Фильтр по статусу стратегии" Enums(preparing, active, archived, deleted)
+// @Param        currency query string false "Валюта отчёта (ISO 4217)" default(USD)
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} LeaderboardResponse
 // @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /statistics/leaderboard [get]
 func (h *Handler) GetStrategyLeaderboard(c *gin.Context) {
@@ -36,6 +44,10 @@ func (h *Handler) GetStrategyLeaderboard(c *gin.Context) {
 
 	leaderboard, err := h.useCase.GetStrategyLeaderboard(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get strategy leaderboard", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -46,13 +58,15 @@ func (h *Handler) GetStrategyLeaderboard(c *gin.Context) {
 
 // GetInvestorPortfolio godoc
 // @Summary      Портфель инвестора
-// @Description  Возвращает портфель инвестора с его подписками и статистикой
+// @Description  Возвращает портфель инвестора с его подписками и статистикой; прибыль пересчитывается в валюту currency по курсу на дату закрытия сделки
 // @Tags         statistics
 // @Accept       json
 // @Produce      json
 // @Param        user_id query int true "ID пользователя-инвестора"
+// @Param        currency query string false "Валюта отчёта (ISO 4217)" default(USD)
 // @Success      200 {array} PortfolioEntry
 // @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /statistics/portfolio [get]
 func (h *Handler) GetInvestorPortfolio(c *gin.Context) {
@@ -64,6 +78,10 @@ func (h *Handler) GetInvestorPortfolio(c *gin.Context) {
 
 	portfolio, err := h.useCase.GetInvestorPortfolio(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get investor portfolio", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -74,15 +92,17 @@ func (h *Handler) GetInvestorPortfolio(c *gin.Context) {
 
 // GetMasterIncome godoc
 // @Summary      Доход мастера
-// @Description  Возвращает информацию о доходах мастер-трейдера
+// @Description  Возвращает информацию о доходах мастер-трейдера; комиссии пересчитываются в валюту currency по курсу на конец периода начисления
 // @Tags         statistics
 // @Accept       json
 // @Produce      json
 // @Param        user_id query int true "ID пользователя-мастера"
 // @Param        from query string false "Начало периода (RFC3339)"
 // @Param        to query string false "Конец периода (RFC3339)"
+// @Param        currency query string false "Валюта отчёта (ISO 4217)" default(USD)
 // @Success      200 {object} MasterIncome
 // @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /statistics/master-income [get]
 func (h *Handler) GetMasterIncome(c *gin.Context) {
@@ -94,6 +114,10 @@ func (h *Handler) GetMasterIncome(c *gin.Context) {
 
 	income, err := h.useCase.GetMasterIncome(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get master income", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -101,3 +125,196 @@ func (h *Handler) GetMasterIncome(c *gin.Context) {
 
 	c.JSON(http.StatusOK, income)
 }
+
+// ListCommissions godoc
+// @Summary      Список комиссий
+// @Description  Возвращает начисленные комиссии с фильтрами по подписке, стратегии, типу и периоду начисления
+// @Tags         commissions
+// @Accept       json
+// @Produce      json
+// @Param        subscription_id query int false "Фильтр по ID подписки"
+// @Param        strategy_id query int false "Фильтр по ID стратегии"
+// @Param        type query string false "Тип комиссии" Enums(performance, management, registration)
+// @Param        currency query string false "Пересчитать суммы в валюту (ISO 4217); по умолчанию — валюта счёта инвестора"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} CommissionListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /commissions [get]
+func (h *Handler) ListCommissions(c *gin.Context) {
+	var filter CommissionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.ListCommissions(c.Request.Context(), &filter)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to list commissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ListSubscriptionCommissions godoc
+// @Summary      Комиссии подписки
+// @Description  Возвращает комиссии, начисленные по подписке, с фильтрами по типу и периоду начисления
+// @Tags         commissions
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID подписки"
+// @Param        type query string false "Тип комиссии" Enums(performance, management, registration)
+// @Param        currency query string false "Пересчитать суммы в валюту (ISO 4217); по умолчанию — валюта счёта инвестора"
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Param        page query int false "Номер страницы" default(1)
+// @Param        limit query int false "Количество записей на странице" default(20)
+// @Success      200 {object} CommissionListResponse
+// @Failure      400 {object} map[string]string
+// @Failure      422 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /subscriptions/{id}/commissions [get]
+func (h *Handler) ListSubscriptionCommissions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	var filter CommissionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.SubscriptionID = id
+
+	result, err := h.useCase.ListCommissions(c.Request.Context(), &filter)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to list subscription commissions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetStrategyEquityCurve godoc
+// @Summary      Кривая капитала стратегии
+// @Description  Возвращает по интервалам прибыль, накопленную прибыль, просадку от пика и число закрытых сделок мастера. Накопленная прибыль учитывает сделки до from
+// @Tags         strategies
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID стратегии"
+// @Param        interval query string false "Шаг кривой" Enums(day, week, month) default(day)
+// @Param        from query string false "Начало периода (RFC3339)"
+// @Param        to query string false "Конец периода (RFC3339)"
+// @Success      200 {object} EquityCurve
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /strategies/{id}/equity-curve [get]
+func (h *Handler) GetStrategyEquityCurve(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid strategy id"})
+		return
+	}
+
+	var req EquityCurveRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	curve, err := h.useCase.GetEquityCurve(c.Request.Context(), id, &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get strategy equity curve", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, curve)
+}
+
+// GetAccountStatistics godoc
+// @Summary      Статистика счёта
+// @Description  Возвращает для счёта мастера или инвестора число и объём закрытых за период сделок, прибыль, уплаченные и заработанные комиссии, лучший и худший инструмент и текущую открытую позицию. Суммы — в валюте счёта
+// @Tags         statistics
+// @Accept       json
+// @Produce      json
+// @Param        account_id path int true "ID счёта"
+// @Param        period query string false "Период" Enums(day, week, month, year, all) default(all)
+// @Success      200 {object} AccountStatistics
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      422 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /statistics/accounts/{account_id} [get]
+func (h *Handler) GetAccountStatistics(c *gin.Context) {
+	var req AccountStatisticsRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account id"})
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.useCase.GetAccountStatistics(c.Request.Context(), &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get account statistics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// RebuildStrategyStats godoc
+// @Summary      Пересчитать статистику стратегий
+// @Description  Полностью пересчитывает strategy_stats по подпискам, скопированным сделкам и комиссиям и возвращает найденные расхождения (только для администратора)
+// @Tags         statistics
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} StatsRebuildResult
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /statistics/strategy-stats/rebuild [post]
+func (h *Handler) RebuildStrategyStats(c *gin.Context) {
+	result, err := h.useCase.RebuildStrategyStats(c.Request.Context())
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to rebuild strategy stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -9,5 +9,7 @@ var Module = fx.Options(
 		NewRepository,
 		NewUseCase,
 		NewHandler,
+		NewWorker,
 	),
+	fx.Invoke(func(*Worker) {}),
 )
@@ -4,17 +4,28 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
-	GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) ([]*StrategyLeaderboard, error)
+	GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest, from *time.Time, to time.Time) (*common.PaginatedResult[StrategyLeaderboard], error)
 	GetInvestorPortfolio(ctx context.Context, req *InvestorPortfolioRequest) (*InvestorPortfolio, error)
 	GetMasterIncome(ctx context.Context, req *MasterIncomeRequest) (*MasterIncome, error)
 	CreateCommission(ctx context.Context, req *CreateCommissionRequest) (*Commission, error)
 	GetCommissionsBySubscriptionID(ctx context.Context, subscriptionID int64) ([]*Commission, error)
+	ListCommissions(ctx context.Context, filter *CommissionFilter) (*common.PaginatedResult[Commission], error)
+	GetEquityCurve(ctx context.Context, strategyID int64, req *EquityCurveRequest) (*EquityCurve, error)
+	GetAccountStatistics(ctx context.Context, accountID int64, from *time.Time, to time.Time) (*AccountStatistics, error)
+	RefreshQueuedStrategyStats(ctx context.Context, limit int) (int, error)
+	RebuildStrategyStats(ctx context.Context) (*StatsRebuildResult, error)
 }
 
 type repository struct {
@@ -26,44 +37,87 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 	return &repository{db: db, logger: logger}
 }
 
-func (r *repository) GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) ([]*StrategyLeaderboard, error) {
-	if req.Limit <= 0 {
-		req.Limit = 10
+// GetStrategyLeaderboard возвращает страницу рейтинга за окно [from, to). Если окно
+// ограничено, место сравнивается с рейтингом за предыдущее окно той же длины.
+func (r *repository) GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest, from *time.Time, to time.Time) (*common.PaginatedResult[StrategyLeaderboard], error) {
+	req.SetDefaults()
+
+	r.logger.Info("Getting strategy leaderboard",
+		zap.String("rank_by", string(req.RankBy)),
+		zap.String("period", string(req.Period)),
+		zap.String("status", string(req.Status)),
+		zap.String("currency", req.Currency))
+
+	var total int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, `
+		SELECT COUNT(*)
+		FROM strategies
+		WHERE created_at < $1
+		  AND ($2 = '' OR status::text = $2)
+	`, to, string(req.Status))
+	if err != nil {
+		r.logger.Error("Failed to count leaderboard strategies", zap.Error(err))
+		return nil, fmt.Errorf("count leaderboard strategies: %w", err)
 	}
-	if req.Limit > 100 {
-		req.Limit = 100
+
+	args := []interface{}{req.Currency, string(req.RankBy), from, to, string(req.Status)}
+
+	previous := "(SELECT NULL::BIGINT AS strategy_id, NULL::INT AS rank WHERE false)"
+	if from != nil {
+		previous = "fn_get_strategy_leaderboard($1, $2, $6, $3, NULLIF($5, '')::strategy_status)"
+		args = append(args, req.Period.Start(*from))
 	}
 
-	r.logger.Info("Getting strategy leaderboard", zap.Int("limit", req.Limit))
+	query := fmt.Sprintf(`
+		SELECT c.strategy_id, c.title, c.status, c.total_profit, c.roi, c.total_commissions,
+		       c.active_subscriptions, c.risk_score, c.risk_adjusted, c.rank,
+		       p.rank AS previous_rank, p.rank - c.rank AS rank_change
+		FROM fn_get_strategy_leaderboard($1, $2, $3, $4, NULLIF($5, '')::strategy_status) c
+		LEFT JOIN %s p ON p.strategy_id = c.strategy_id
+		ORDER BY c.rank
+		LIMIT $%d OFFSET $%d
+	`, previous, len(args)+1, len(args)+2)
 
-	query := `SELECT strategy_id, title, total_profit, total_commissions, active_subscriptions
-			  FROM fn_get_strategy_leaderboard($1)`
+	args = append(args, req.Limit, req.Offset)
 
-	var leaderboard []*StrategyLeaderboard
-	if err := r.db.SelectContext(ctx, &leaderboard, query, req.Limit); err != nil {
+	leaderboard := []StrategyLeaderboard{}
+	if err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &leaderboard, query, args...); err != nil {
 		r.logger.Error("Failed to get strategy leaderboard", zap.Error(err))
-		return nil, fmt.Errorf("get strategy leaderboard: %w", err)
+		return nil, fmt.Errorf("get strategy leaderboard: %w", currency.MapError(err))
+	}
+
+	for i := range leaderboard {
+		leaderboard[i].Currency = req.Currency
 	}
 
-	return leaderboard, nil
+	return &common.PaginatedResult[StrategyLeaderboard]{
+		Data:       leaderboard,
+		Total:      total,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(req.Limit))),
+	}, nil
 }
 
 func (r *repository) GetInvestorPortfolio(ctx context.Context, req *InvestorPortfolioRequest) (*InvestorPortfolio, error) {
-	r.logger.Info("Getting investor portfolio", zap.Int64("user_id", req.UserID))
+	r.logger.Info("Getting investor portfolio",
+		zap.Int64("user_id", req.UserID),
+		zap.String("currency", req.Currency))
 
 	query := `SELECT subscription_id, strategy_id, strategy_title, total_profit, copied_trades_count
-			  FROM fn_get_investor_portfolio($1)`
+			  FROM fn_get_investor_portfolio($1, $2)`
 
 	var items []PortfolioItem
-	if err := r.db.SelectContext(ctx, &items, query, req.UserID); err != nil {
+	if err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &items, query, req.UserID, req.Currency); err != nil {
 		r.logger.Error("Failed to get investor portfolio",
 			zap.Int64("user_id", req.UserID),
 			zap.Error(err))
-		return nil, fmt.Errorf("get investor portfolio: %w", err)
+		return nil, fmt.Errorf("get investor portfolio: %w", currency.MapError(err))
 	}
 
 	return &InvestorPortfolio{
 		UserID:        req.UserID,
+		Currency:      req.Currency,
 		Subscriptions: items,
 	}, nil
 }
@@ -72,22 +126,23 @@ func (r *repository) GetMasterIncome(ctx context.Context, req *MasterIncomeReque
 	r.logger.Info("Getting master income",
 		zap.Int64("user_id", req.UserID),
 		zap.Time("from", req.From),
-		zap.Time("to", req.To))
+		zap.Time("to", req.To),
+		zap.String("currency", req.Currency))
 
+	// Комиссия списывается в валюте счёта инвестора и пересчитывается по курсу на конец периода
 	query := `
-		SELECT
-			COALESCE(SUM(CASE WHEN c.type = 'performance' THEN c.amount ELSE 0 END), 0) as performance_fees,
-			COALESCE(SUM(CASE WHEN c.type = 'management' THEN c.amount ELSE 0 END), 0) as management_fees,
-			COALESCE(SUM(CASE WHEN c.type = 'registration' THEN c.amount ELSE 0 END), 0) as registration_fees
-		FROM commissions c
-		JOIN subscriptions s ON c.subscription_id = s.id
-		JOIN offers o ON s.offer_id = o.id
-		JOIN strategies st ON o.strategy_id = st.id
-		WHERE st.master_user_id = $1
+		WITH converted AS (
+			SELECT c.type, fn_fx_convert(c.amount, a.currency, $2, COALESCE(c.period_to, c.created_at)) AS amount
+			FROM commissions c
+			JOIN subscriptions s ON c.subscription_id = s.id
+			JOIN accounts a ON a.id = s.investor_account_id
+			JOIN offers o ON s.offer_id = o.id
+			JOIN strategies st ON o.strategy_id = st.id
+			WHERE st.master_user_id = $1
 	`
 
-	args := []interface{}{req.UserID}
-	argIndex := 2
+	args := []interface{}{req.UserID, req.Currency}
+	argIndex := 3
 
 	if !req.From.IsZero() {
 		query += fmt.Sprintf(" AND c.created_at >= $%d", argIndex)
@@ -100,17 +155,27 @@ func (r *repository) GetMasterIncome(ctx context.Context, req *MasterIncomeReque
 		args = append(args, req.To)
 	}
 
+	query += `
+		)
+		SELECT
+			ROUND(COALESCE(SUM(CASE WHEN type = 'performance' THEN amount ELSE 0 END), 0), 2) as performance_fees,
+			ROUND(COALESCE(SUM(CASE WHEN type = 'management' THEN amount ELSE 0 END), 0), 2) as management_fees,
+			ROUND(COALESCE(SUM(CASE WHEN type = 'registration' THEN amount ELSE 0 END), 0), 2) as registration_fees
+		FROM converted
+	`
+
 	var result struct {
 		PerformanceFees  float64 `db:"performance_fees"`
 		ManagementFees   float64 `db:"management_fees"`
 		RegistrationFees float64 `db:"registration_fees"`
 	}
 
-	err := r.db.GetContext(ctx, &result, query, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &result, query, args...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &MasterIncome{
 				UserID:           req.UserID,
+				Currency:         req.Currency,
 				TotalIncome:      0,
 				PerformanceFees:  0,
 				ManagementFees:   0,
@@ -120,11 +185,12 @@ func (r *repository) GetMasterIncome(ctx context.Context, req *MasterIncomeReque
 		r.logger.Error("Failed to get master income",
 			zap.Int64("user_id", req.UserID),
 			zap.Error(err))
-		return nil, fmt.Errorf("get master income: %w", err)
+		return nil, fmt.Errorf("get master income: %w", currency.MapError(err))
 	}
 
 	return &MasterIncome{
 		UserID:           req.UserID,
+		Currency:         req.Currency,
 		TotalIncome:      result.PerformanceFees + result.ManagementFees + result.RegistrationFees,
 		PerformanceFees:  result.PerformanceFees,
 		ManagementFees:   result.ManagementFees,
@@ -134,13 +200,19 @@ func (r *repository) GetMasterIncome(ctx context.Context, req *MasterIncomeReque
 
 func (r *repository) CreateCommission(ctx context.Context, req *CreateCommissionRequest) (*Commission, error) {
 	query := `
-		INSERT INTO commissions (subscription_id, type, amount, period_from, period_to)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, subscription_id, type, amount, period_from, period_to, created_at
+		WITH c AS (
+			INSERT INTO commissions (subscription_id, type, amount, period_from, period_to)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, subscription_id, type, amount, period_from, period_to, created_at
+		)
+		SELECT c.id, c.subscription_id, c.type, c.amount, c.period_from, c.period_to, a.currency, c.created_at
+		FROM c
+		JOIN subscriptions s ON s.id = c.subscription_id
+		JOIN accounts a ON a.id = s.investor_account_id
 	`
 
 	var commission Commission
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.SubscriptionID,
 		req.Type,
 		req.Amount,
@@ -166,14 +238,16 @@ func (r *repository) CreateCommission(ctx context.Context, req *CreateCommission
 
 func (r *repository) GetCommissionsBySubscriptionID(ctx context.Context, subscriptionID int64) ([]*Commission, error) {
 	query := `
-		SELECT id, subscription_id, type, amount, period_from, period_to, created_at
-		FROM commissions
-		WHERE subscription_id = $1
-		ORDER BY created_at DESC
+		SELECT c.id, c.subscription_id, c.type, c.amount, c.period_from, c.period_to, a.currency, c.invoice_id, c.created_at
+		FROM commissions c
+		JOIN subscriptions s ON s.id = c.subscription_id
+		JOIN accounts a ON a.id = s.investor_account_id
+		WHERE c.subscription_id = $1
+		ORDER BY c.created_at DESC
 	`
 
 	var commissions []*Commission
-	err := r.db.SelectContext(ctx, &commissions, query, subscriptionID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &commissions, query, subscriptionID)
 	if err != nil {
 		r.logger.Error("Failed to get commissions by subscription ID",
 			zap.Int64("subscription_id", subscriptionID),
@@ -183,3 +257,463 @@ func (r *repository) GetCommissionsBySubscriptionID(ctx context.Context, subscri
 
 	return commissions, nil
 }
+
+// ListCommissions возвращает комиссии с фильтрами. Период [from, to) отбирает
+// комиссии, чей период начисления с ним пересекается. Без валюты суммы отдаются
+// в валюте счёта инвестора, с валютой — по курсу на конец периода начисления.
+func (r *repository) ListCommissions(ctx context.Context, filter *CommissionFilter) (*common.PaginatedResult[Commission], error) {
+	filter.SetDefaults()
+
+	var (
+		conditions []string
+		args       []interface{}
+		argIndex   = 1
+	)
+
+	if filter.SubscriptionID != 0 {
+		conditions = append(conditions, fmt.Sprintf("c.subscription_id = $%d", argIndex))
+		args = append(args, filter.SubscriptionID)
+		argIndex++
+	}
+
+	if filter.StrategyID != 0 {
+		conditions = append(conditions, fmt.Sprintf("o.strategy_id = $%d", argIndex))
+		args = append(args, filter.StrategyID)
+		argIndex++
+	}
+
+	if filter.Type != "" {
+		conditions = append(conditions, fmt.Sprintf("c.type = $%d", argIndex))
+		args = append(args, filter.Type)
+		argIndex++
+	}
+
+	if !filter.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("COALESCE(c.period_to, c.created_at) >= $%d", argIndex))
+		args = append(args, filter.From)
+		argIndex++
+	}
+
+	if !filter.To.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("COALESCE(c.period_from, c.created_at) < $%d", argIndex))
+		args = append(args, filter.To)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	fromClause := `
+		FROM commissions c
+		JOIN subscriptions s ON s.id = c.subscription_id
+		JOIN accounts a ON a.id = s.investor_account_id
+		JOIN offers o ON o.id = s.offer_id
+	`
+
+	countQuery := fmt.Sprintf("SELECT COUNT(*) %s %s", fromClause, whereClause)
+	var total int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
+	if err != nil {
+		r.logger.Error("Failed to count commissions", zap.Error(err))
+		return nil, fmt.Errorf("count commissions: %w", err)
+	}
+
+	amountColumns := "c.amount, a.currency"
+	if filter.Currency != "" {
+		amountColumns = fmt.Sprintf(
+			"ROUND(fn_fx_convert(c.amount, a.currency, $%d, COALESCE(c.period_to, c.created_at)), 2) AS amount, $%d::text AS currency",
+			argIndex, argIndex+1)
+		args = append(args, filter.Currency, filter.Currency)
+		argIndex += 2
+	}
+
+	query := fmt.Sprintf(`
+		SELECT c.id, c.subscription_id, c.type, %s, c.period_from, c.period_to, c.invoice_id, c.created_at
+		%s
+		%s
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $%d OFFSET $%d
+	`, amountColumns, fromClause, whereClause, argIndex, argIndex+1)
+
+	args = append(args, filter.Limit, filter.Offset)
+
+	commissions := []Commission{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &commissions, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to list commissions", zap.Error(err))
+		return nil, fmt.Errorf("list commissions: %w", currency.MapError(err))
+	}
+
+	return &common.PaginatedResult[Commission]{
+		Data:       commissions,
+		Total:      total,
+		Page:       filter.Page,
+		Limit:      filter.Limit,
+		TotalPages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// GetEquityCurve собирает кривую капитала из дневного свода strategy_equity_daily.
+// Накопленная прибыль учитывает сделки до from; интервалы без сделок заполняются нулями.
+// Возвращает nil, если стратегии нет.
+func (r *repository) GetEquityCurve(ctx context.Context, strategyID int64, req *EquityCurveRequest) (*EquityCurve, error) {
+	curve := EquityCurve{Interval: req.Interval, Points: []EquityPoint{}}
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &curve, `
+		SELECT s.id AS strategy_id, a.currency, m.capital
+		FROM strategies s
+		JOIN accounts a ON a.id = s.master_account_id
+		LEFT JOIN strategy_metrics m ON m.strategy_id = s.id
+		WHERE s.id = $1
+	`, strategyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get strategy for equity curve",
+			zap.Int64("strategy_id", strategyID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get strategy for equity curve: %w", err)
+	}
+
+	var (
+		dayConditions = []string{"d.strategy_id = $1"}
+		conditions    []string
+		args          = []interface{}{strategyID, string(req.Interval), curve.Capital}
+		argIndex      = 4
+	)
+
+	if !req.To.IsZero() {
+		dayConditions = append(dayConditions, fmt.Sprintf("d.day < ($%d::timestamptz AT TIME ZONE 'UTC')::date", argIndex))
+		args = append(args, req.To)
+		argIndex++
+	}
+
+	if !req.From.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("bucket >= date_trunc($2, $%d::timestamptz AT TIME ZONE 'UTC')::date", argIndex))
+		args = append(args, req.From)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		WITH buckets AS (
+			SELECT date_trunc($2, d.day::timestamp)::date AS bucket,
+			       SUM(d.profit) AS profit,
+			       SUM(d.trades_count)::INT AS trades_count
+			FROM strategy_equity_daily d
+			WHERE %s
+			GROUP BY 1
+		),
+		series AS (
+			SELECT generate_series(MIN(bucket)::timestamp, MAX(bucket)::timestamp, ('1 ' || $2)::interval)::date AS bucket
+			FROM buckets
+		),
+		curve AS (
+			SELECT sr.bucket,
+			       COALESCE(b.profit, 0) AS profit,
+			       COALESCE(b.trades_count, 0) AS trades_count,
+			       SUM(COALESCE(b.profit, 0)) OVER (ORDER BY sr.bucket) AS cumulative_profit
+			FROM series sr
+			LEFT JOIN buckets b ON b.bucket = sr.bucket
+		),
+		peaks AS (
+			SELECT c.*, GREATEST(MAX(c.cumulative_profit) OVER (ORDER BY c.bucket), 0) AS peak
+			FROM curve c
+		)
+		SELECT bucket, profit, trades_count, cumulative_profit,
+		       peak - cumulative_profit AS drawdown,
+		       ROUND((peak - cumulative_profit) / NULLIF($3::numeric + peak, 0) * 100, 4) AS drawdown_pct
+		FROM peaks
+		%s
+		ORDER BY bucket
+	`, strings.Join(dayConditions, " AND "), whereClause)
+
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &curve.Points, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to get strategy equity curve",
+			zap.Int64("strategy_id", strategyID),
+			zap.String("interval", string(req.Interval)),
+			zap.Error(err))
+		return nil, fmt.Errorf("get strategy equity curve: %w", err)
+	}
+
+	return &curve, nil
+}
+
+// accountPositions — сделки счёта: собственные сделки мастера и скопированные сделки
+// инвестора с инструментом и направлением исходной сделки. $1 — ID счёта.
+const accountPositions = `
+	WITH positions AS (
+		SELECT t.symbol, t.direction, t.volume_lots, t.profit, t.close_time
+		FROM trades t
+		WHERE t.master_account_id = $1
+		UNION ALL
+		SELECT t.symbol, t.direction, ct.volume_lots, ct.profit, ct.close_time
+		FROM copied_trades ct
+		JOIN trades t ON t.id = ct.trade_id
+		WHERE ct.investor_account_id = $1
+	)
+`
+
+// GetAccountStatistics считает итоги счёта по сделкам, закрытым в [from, to]; from = nil
+// снимает нижнюю границу. Комиссии относятся к периоду по концу периода начисления и
+// пересчитываются в валюту счёта. Возвращает nil, если счёта нет.
+func (r *repository) GetAccountStatistics(ctx context.Context, accountID int64, from *time.Time, to time.Time) (*AccountStatistics, error) {
+	args := []interface{}{accountID, from, to}
+
+	var stats AccountStatistics
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &stats, accountPositions+`
+		SELECT a.id AS account_id, a.account_type, a.currency,
+		       t.trades_count, t.volume, t.trading_profit,
+		       ROUND(p.amount, 2) AS commissions_paid,
+		       ROUND(e.amount, 2) AS commissions_earned,
+		       ROUND(t.trading_profit - p.amount + e.amount, 2) AS net_profit
+		FROM accounts a
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*)::INT AS trades_count,
+			       COALESCE(SUM(volume_lots), 0) AS volume,
+			       COALESCE(SUM(profit), 0) AS trading_profit
+			FROM positions
+			WHERE close_time IS NOT NULL
+			  AND ($2::timestamptz IS NULL OR close_time >= $2)
+			  AND close_time <= $3
+		) t
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(c.amount), 0) AS amount
+			FROM commissions c
+			JOIN subscriptions sub ON sub.id = c.subscription_id
+			WHERE sub.investor_account_id = a.id
+			  AND ($2::timestamptz IS NULL OR COALESCE(c.period_to, c.created_at) >= $2)
+			  AND COALESCE(c.period_to, c.created_at) <= $3
+		) p
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(fn_fx_convert(c.amount, ia.currency, a.currency, COALESCE(c.period_to, c.created_at))), 0) AS amount
+			FROM commissions c
+			JOIN subscriptions sub ON sub.id = c.subscription_id
+			JOIN offers o ON o.id = sub.offer_id
+			JOIN strategies st ON st.id = o.strategy_id
+			JOIN accounts ia ON ia.id = sub.investor_account_id
+			WHERE st.master_account_id = a.id
+			  AND ($2::timestamptz IS NULL OR COALESCE(c.period_to, c.created_at) >= $2)
+			  AND COALESCE(c.period_to, c.created_at) <= $3
+		) e
+		WHERE a.id = $1
+	`, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get account statistics",
+			zap.Int64("account_id", accountID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get account statistics: %w", currency.MapError(err))
+	}
+
+	symbols := []SymbolResult{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &symbols, accountPositions+`
+		SELECT symbol,
+		       COUNT(*)::INT AS trades_count,
+		       SUM(volume_lots) AS volume,
+		       COALESCE(SUM(profit), 0) AS profit
+		FROM positions
+		WHERE close_time IS NOT NULL
+		  AND ($2::timestamptz IS NULL OR close_time >= $2)
+		  AND close_time <= $3
+		GROUP BY symbol
+		ORDER BY profit DESC, symbol
+	`, args...)
+	if err != nil {
+		r.logger.Error("Failed to get account symbol results",
+			zap.Int64("account_id", accountID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get account symbol results: %w", err)
+	}
+	if len(symbols) > 0 {
+		stats.BestSymbol = &symbols[0]
+		stats.WorstSymbol = &symbols[len(symbols)-1]
+	}
+
+	stats.OpenExposure.Symbols = []SymbolExposure{}
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &stats.OpenExposure.Symbols, accountPositions+`
+		SELECT symbol,
+		       COUNT(*)::INT AS positions_count,
+		       COALESCE(SUM(volume_lots) FILTER (WHERE direction = 'buy'), 0) AS long_volume,
+		       COALESCE(SUM(volume_lots) FILTER (WHERE direction = 'sell'), 0) AS short_volume,
+		       COALESCE(SUM(CASE WHEN direction = 'buy' THEN volume_lots ELSE -volume_lots END), 0) AS net_volume
+		FROM positions
+		WHERE close_time IS NULL
+		GROUP BY symbol
+		ORDER BY symbol
+	`, accountID)
+	if err != nil {
+		r.logger.Error("Failed to get account open exposure",
+			zap.Int64("account_id", accountID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get account open exposure: %w", err)
+	}
+	for _, symbol := range stats.OpenExposure.Symbols {
+		stats.OpenExposure.PositionsCount += symbol.PositionsCount
+		stats.OpenExposure.Volume += symbol.LongVolume + symbol.ShortVolume
+	}
+
+	return &stats, nil
+}
+
+// RefreshQueuedStrategyStats забирает из очереди до limit стратегий и пересчитывает их
+// strategy_stats в одной транзакции. Стратегии, изменяемые незавершёнными транзакциями,
+// заблокированы ими в очереди и пропускаются до следующего раза.
+func (r *repository) RefreshQueuedStrategyStats(ctx context.Context, limit int) (int, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	err = tx.SelectContext(ctx, &ids, `
+		DELETE FROM strategy_stats_queue
+		WHERE strategy_id IN (
+			SELECT strategy_id
+			FROM strategy_stats_queue
+			ORDER BY enqueued_at, strategy_id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING strategy_id
+	`, limit)
+	if err != nil {
+		r.logger.Error("Failed to dequeue strategy stats", zap.Error(err))
+		return 0, fmt.Errorf("dequeue strategy stats: %w", err)
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `SELECT fn_refresh_strategy_stats($1)`, id); err != nil {
+			r.logger.Error("Failed to refresh strategy stats",
+				zap.Int64("strategy_id", id),
+				zap.Error(err))
+			return 0, fmt.Errorf("refresh strategy stats: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return len(ids), nil
+}
+
+// strategyStatsActual — счётчики strategy_stats, пересчитанные по исходным таблицам для всех стратегий
+const strategyStatsActual = `
+	WITH subs AS (
+		SELECT o.strategy_id,
+		       COUNT(*)::INT AS total,
+		       COUNT(*) FILTER (WHERE sub.status = 'active')::INT AS active
+		FROM subscriptions sub
+		JOIN offers o ON o.id = sub.offer_id
+		GROUP BY o.strategy_id
+	),
+	copies AS (
+		SELECT o.strategy_id, COUNT(*)::INT AS total, COALESCE(SUM(ct.profit), 0) AS profit
+		FROM copied_trades ct
+		JOIN subscriptions sub ON sub.id = ct.subscription_id
+		JOIN offers o ON o.id = sub.offer_id
+		GROUP BY o.strategy_id
+	),
+	fees AS (
+		SELECT o.strategy_id, COALESCE(SUM(c.amount), 0) AS amount
+		FROM commissions c
+		JOIN subscriptions sub ON sub.id = c.subscription_id
+		JOIN offers o ON o.id = sub.offer_id
+		GROUP BY o.strategy_id
+	),
+	actual AS (
+		SELECT s.id AS strategy_id,
+		       COALESCE(subs.total, 0) AS total_subscriptions,
+		       COALESCE(subs.active, 0) AS active_subscriptions,
+		       COALESCE(copies.total, 0) AS total_copied_trades,
+		       COALESCE(copies.profit, 0) AS total_profit,
+		       COALESCE(fees.amount, 0) AS total_commissions
+		FROM strategies s
+		LEFT JOIN subs ON subs.strategy_id = s.id
+		LEFT JOIN copies ON copies.strategy_id = s.id
+		LEFT JOIN fees ON fees.strategy_id = s.id
+	)
+`
+
+// RebuildStrategyStats пересчитывает strategy_stats всех стратегий по исходным таблицам
+// и возвращает найденные расхождения. Отсутствующая строка считается нулевой.
+func (r *repository) RebuildStrategyStats(ctx context.Context) (*StatsRebuildResult, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := StatsRebuildResult{Drift: []StatsDrift{}}
+	err = tx.SelectContext(ctx, &result.Drift, strategyStatsActual+`
+		SELECT a.strategy_id, d.field, d.stored, d.actual
+		FROM actual a
+		LEFT JOIN strategy_stats ss ON ss.strategy_id = a.strategy_id
+		CROSS JOIN LATERAL (VALUES
+			('total_subscriptions', COALESCE(ss.total_subscriptions, 0)::NUMERIC, a.total_subscriptions::NUMERIC),
+			('active_subscriptions', COALESCE(ss.active_subscriptions, 0)::NUMERIC, a.active_subscriptions::NUMERIC),
+			('total_copied_trades', COALESCE(ss.total_copied_trades, 0)::NUMERIC, a.total_copied_trades::NUMERIC),
+			('total_profit', COALESCE(ss.total_profit, 0), a.total_profit),
+			('total_commissions', COALESCE(ss.total_commissions, 0), a.total_commissions)
+		) AS d(field, stored, actual)
+		WHERE d.stored <> d.actual
+		ORDER BY a.strategy_id, d.field
+	`)
+	if err != nil {
+		r.logger.Error("Failed to find strategy stats drift", zap.Error(err))
+		return nil, fmt.Errorf("find strategy stats drift: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, strategyStatsActual+`
+		INSERT INTO strategy_stats (
+			strategy_id, total_subscriptions, active_subscriptions,
+			total_copied_trades, total_profit, total_commissions, updated_at
+		)
+		SELECT strategy_id, total_subscriptions, active_subscriptions,
+		       total_copied_trades, total_profit, total_commissions, now()
+		FROM actual
+		ON CONFLICT (strategy_id) DO UPDATE
+		SET
+			total_subscriptions  = EXCLUDED.total_subscriptions,
+			active_subscriptions = EXCLUDED.active_subscriptions,
+			total_copied_trades  = EXCLUDED.total_copied_trades,
+			total_profit         = EXCLUDED.total_profit,
+			total_commissions    = EXCLUDED.total_commissions,
+			updated_at           = EXCLUDED.updated_at
+	`)
+	if err != nil {
+		r.logger.Error("Failed to rebuild strategy stats", zap.Error(err))
+		return nil, fmt.Errorf("rebuild strategy stats: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("rebuild strategy stats: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	result.Strategies = int(rows)
+	drifted := map[int64]struct{}{}
+	for _, d := range result.Drift {
+		drifted[d.StrategyID] = struct{}{}
+	}
+	result.Drifted = len(drifted)
+
+	r.logger.Info("Strategy stats rebuilt",
+		zap.Int("strategies", result.Strategies),
+		zap.Int("drifted", result.Drifted))
+
+	return &result, nil
+}
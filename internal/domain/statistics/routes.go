@@ -8,5 +8,11 @@ func RegisterRoutes(router *gin.RouterGroup, handler *Handler) {
 		statistics.GET("/leaderboard", handler.GetStrategyLeaderboard)
 		statistics.GET("/investor-portfolio", handler.GetInvestorPortfolio)
 		statistics.GET("/master-income", handler.GetMasterIncome)
+		statistics.GET("/accounts/:account_id", handler.GetAccountStatistics)
+		statistics.POST("/strategy-stats/rebuild", handler.RebuildStrategyStats)
 	}
+
+	router.GET("/commissions", handler.ListCommissions)
+	router.GET("/subscriptions/:id/commissions", handler.ListSubscriptionCommissions)
+	router.GET("/strategies/:id/equity-curve", handler.GetStrategyEquityCurve)
 }
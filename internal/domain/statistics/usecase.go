@@ -3,16 +3,31 @@ package statistics
 import (
 	"context"
 	"fmt"
-
+	"strings"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/domain/account"
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
+	"github.com/finlleyl/cp_database/internal/domain/strategy"
 	"go.uber.org/zap"
 )
 
 type UseCase interface {
-	GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) ([]*StrategyLeaderboard, error)
+	GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) (*common.PaginatedResult[StrategyLeaderboard], error)
 	GetInvestorPortfolio(ctx context.Context, req *InvestorPortfolioRequest) (*InvestorPortfolio, error)
 	GetMasterIncome(ctx context.Context, req *MasterIncomeRequest) (*MasterIncome, error)
+	ListCommissions(ctx context.Context, filter *CommissionFilter) (*common.PaginatedResult[Commission], error)
+	GetEquityCurve(ctx context.Context, strategyID int64, req *EquityCurveRequest) (*EquityCurve, error)
+	GetAccountStatistics(ctx context.Context, req *AccountStatisticsRequest) (*AccountStatistics, error)
+	RefreshQueuedStrategyStats(ctx context.Context) (int, error)
+	RebuildStrategyStats(ctx context.Context) (*StatsRebuildResult, error)
 }
 
+// statsQueueBatchSize — сколько стратегий из очереди пересчитывается в одной транзакции
+const statsQueueBatchSize = 100
+
 type useCase struct {
 	repo   Repository
 	logger *zap.Logger
@@ -22,15 +37,23 @@ func NewUseCase(repo Repository, logger *zap.Logger) UseCase {
 	return &useCase{repo: repo, logger: logger}
 }
 
-func (u *useCase) GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) ([]*StrategyLeaderboard, error) {
-
-	if req.Limit <= 0 {
-		req.Limit = 10
+func (u *useCase) GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRequest) (*common.PaginatedResult[StrategyLeaderboard], error) {
+	if req.RankBy == "" {
+		req.RankBy = LeaderboardRankByProfit
+	}
+	if req.Period == "" {
+		req.Period = PeriodAll
 	}
+	req.Currency = currency.Normalize(req.Currency)
 
-	u.logger.Info("UseCase: Getting strategy leaderboard", zap.Int("limit", req.Limit))
+	u.logger.Info("UseCase: Getting strategy leaderboard",
+		zap.String("rank_by", string(req.RankBy)),
+		zap.String("period", string(req.Period)),
+		zap.String("status", string(req.Status)),
+		zap.String("currency", req.Currency))
 
-	leaderboard, err := u.repo.GetStrategyLeaderboard(ctx, req)
+	now := time.Now()
+	leaderboard, err := u.repo.GetStrategyLeaderboard(ctx, req, req.Period.Start(now), now)
 	if err != nil {
 		return nil, fmt.Errorf("get strategy leaderboard: %w", err)
 	}
@@ -39,7 +62,10 @@ func (u *useCase) GetStrategyLeaderboard(ctx context.Context, req *LeaderboardRe
 }
 
 func (u *useCase) GetInvestorPortfolio(ctx context.Context, req *InvestorPortfolioRequest) (*InvestorPortfolio, error) {
-	u.logger.Info("UseCase: Getting investor portfolio", zap.Int64("user_id", req.UserID))
+	req.Currency = currency.Normalize(req.Currency)
+	u.logger.Info("UseCase: Getting investor portfolio",
+		zap.Int64("user_id", req.UserID),
+		zap.String("currency", req.Currency))
 
 	portfolio, err := u.repo.GetInvestorPortfolio(ctx, req)
 	if err != nil {
@@ -50,10 +76,12 @@ func (u *useCase) GetInvestorPortfolio(ctx context.Context, req *InvestorPortfol
 }
 
 func (u *useCase) GetMasterIncome(ctx context.Context, req *MasterIncomeRequest) (*MasterIncome, error) {
+	req.Currency = currency.Normalize(req.Currency)
 	u.logger.Info("UseCase: Getting master income",
 		zap.Int64("user_id", req.UserID),
 		zap.Time("from", req.From),
-		zap.Time("to", req.To))
+		zap.Time("to", req.To),
+		zap.String("currency", req.Currency))
 
 	income, err := u.repo.GetMasterIncome(ctx, req)
 	if err != nil {
@@ -62,3 +90,108 @@ func (u *useCase) GetMasterIncome(ctx context.Context, req *MasterIncomeRequest)
 
 	return income, nil
 }
+
+func (u *useCase) ListCommissions(ctx context.Context, filter *CommissionFilter) (*common.PaginatedResult[Commission], error) {
+	filter.SetDefaults()
+	filter.Currency = strings.ToUpper(filter.Currency)
+	u.logger.Info("UseCase: Listing commissions", zap.Any("filter", filter))
+
+	commissions, err := u.repo.ListCommissions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list commissions: %w", err)
+	}
+
+	return commissions, nil
+}
+
+func (u *useCase) GetEquityCurve(ctx context.Context, strategyID int64, req *EquityCurveRequest) (*EquityCurve, error) {
+	if req.Interval == "" {
+		req.Interval = PeriodDay
+	}
+
+	u.logger.Info("UseCase: Getting strategy equity curve",
+		zap.Int64("strategy_id", strategyID),
+		zap.String("interval", string(req.Interval)),
+		zap.Time("from", req.From),
+		zap.Time("to", req.To))
+
+	curve, err := u.repo.GetEquityCurve(ctx, strategyID, req)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy equity curve: %w", err)
+	}
+	if curve == nil {
+		return nil, strategy.ErrStrategyNotFound
+	}
+
+	return curve, nil
+}
+
+func (u *useCase) GetAccountStatistics(ctx context.Context, req *AccountStatisticsRequest) (*AccountStatistics, error) {
+	if req.Period == "" {
+		req.Period = PeriodAll
+	}
+
+	u.logger.Info("UseCase: Getting account statistics",
+		zap.Int64("account_id", req.AccountID),
+		zap.String("period", string(req.Period)))
+
+	now := time.Now()
+	from := req.Period.Start(now)
+
+	stats, err := u.repo.GetAccountStatistics(ctx, req.AccountID, from, now)
+	if err != nil {
+		return nil, fmt.Errorf("get account statistics: %w", err)
+	}
+	if stats == nil {
+		return nil, account.ErrAccountNotFound
+	}
+
+	stats.Period = req.Period
+	stats.From = from
+	stats.To = now
+
+	return stats, nil
+}
+
+// RefreshQueuedStrategyStats пересчитывает strategy_stats стратегий из очереди, пока она
+// не опустеет, и возвращает число пересчитанных стратегий (только для администратора)
+func (u *useCase) RefreshQueuedStrategyStats(ctx context.Context) (int, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for {
+		n, err := u.repo.RefreshQueuedStrategyStats(ctx, statsQueueBatchSize)
+		refreshed += n
+		if err != nil {
+			return refreshed, fmt.Errorf("refresh queued strategy stats: %w", err)
+		}
+		if n < statsQueueBatchSize {
+			return refreshed, nil
+		}
+	}
+}
+
+// RebuildStrategyStats пересчитывает strategy_stats всех стратегий и сообщает о расхождениях
+// (только для администратора)
+func (u *useCase) RebuildStrategyStats(ctx context.Context) (*StatsRebuildResult, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info("UseCase: Rebuilding strategy stats")
+
+	result, err := u.repo.RebuildStrategyStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rebuild strategy stats: %w", err)
+	}
+
+	if result.Drifted > 0 {
+		u.logger.Warn("Strategy stats drift found",
+			zap.Int("drifted", result.Drifted),
+			zap.Int("fields", len(result.Drift)))
+	}
+
+	return result, nil
+}
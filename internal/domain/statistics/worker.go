@@ -0,0 +1,36 @@
+package statistics
+
+import (
+	"context"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/worker"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const statsQueuePollInterval = 5 * time.Second
+
+// Worker пересчитывает strategy_stats стратегий из очереди strategy_stats_queue
+type Worker struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewWorker(lc fx.Lifecycle, useCase UseCase, logger *zap.Logger) *Worker {
+	w := &Worker{useCase: useCase, logger: logger}
+	worker.Start(lc, logger, "Strategy stats worker", statsQueuePollInterval, w.runOnce)
+	return w
+}
+
+func (w *Worker) runOnce(ctx context.Context) error {
+	refreshed, err := w.useCase.RefreshQueuedStrategyStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	if refreshed > 0 {
+		w.logger.Info("Strategy stats refreshed", zap.Int("strategies", refreshed))
+	}
+	return nil
+}
@@ -16,6 +16,14 @@ type GetStrategyByIDResponse struct {
 	TotalProfit         float64               `json:"total_profit" db:"total_profit"`
 	TotalCommissions    float64               `json:"total_commissions" db:"total_commissions"`
 	UpdatedAt           time.Time             `json:"updated_at" db:"updated_at"`
+	ROI                 *float64              `json:"roi,omitempty" db:"roi"`
+	MaxDrawdownPct      *float64              `json:"max_drawdown_pct,omitempty" db:"max_drawdown_pct"`
+	SharpeRatio         *float64              `json:"sharpe_ratio,omitempty" db:"sharpe_ratio"`
+	SortinoRatio        *float64              `json:"sortino_ratio,omitempty" db:"sortino_ratio"`
+	WinRate             *float64              `json:"win_rate,omitempty" db:"win_rate"`
+	ProfitFactor        *float64              `json:"profit_factor,omitempty" db:"profit_factor"`
+	RiskScore           *int                  `json:"risk_score,omitempty" db:"risk_score"`
+	MetricsUpdatedAt    *time.Time            `json:"metrics_updated_at,omitempty" db:"metrics_updated_at"`
 }
 
 type CreateStrategyRequest struct {
@@ -25,6 +33,7 @@ type CreateStrategyRequest struct {
 	Summary          string `json:"summary"`
 	PaymentAccountID *int64 `json:"payment_account_id,omitempty"`
 	AvatarURL        string `json:"avatar_url"`
+	AutoCopy         bool   `json:"auto_copy"`
 }
 
 type UpdateStrategyRequest struct {
@@ -32,6 +41,7 @@ type UpdateStrategyRequest struct {
 	Summary          *string `json:"summary,omitempty"`
 	PaymentAccountID *int64  `json:"payment_account_id,omitempty"`
 	AvatarURL        *string `json:"avatar_url,omitempty"`
+	AutoCopy         *bool   `json:"auto_copy,omitempty"`
 }
 
 type ChangeStatusRequest struct {
@@ -43,13 +53,19 @@ type StrategyFilter struct {
 	Status         common.StrategyStatus `form:"status"`
 	MinROI         *float64              `form:"min_roi"`
 	MaxDrawdownPct *float64              `form:"max_drawdown_pct"`
-	RiskScore      *int                  `form:"risk_score"`
+	RiskScore      *int                  `form:"risk_score" binding:"omitempty,min=1,max=10"`
 	common.Pagination
 }
 
+// SummaryRequest — валюта сводки; по умолчанию валюта счёта мастера
+type SummaryRequest struct {
+	Currency string `form:"currency" binding:"omitempty,len=3,alpha"`
+}
+
 type StrategySummary struct {
-	StrategyID  int64   `json:"strategy_id"`
-	TotalProfit float64 `json:"total_profit"`
+	StrategyID  int64   `json:"strategy_id" db:"strategy_id"`
+	TotalProfit float64 `json:"total_profit" db:"total_profit"`
+	Currency    string  `json:"currency" db:"currency"`
 }
 
 // StrategyListResponse представляет пагинированный ответ со списком стратегий
@@ -59,4 +75,4 @@ type StrategyListResponse struct {
 	Page       int        `json:"page"`
 	Limit      int        `json:"limit"`
 	TotalPages int        `json:"total_pages"`
-}
\ No newline at end of file
+}
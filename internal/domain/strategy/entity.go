@@ -1,11 +1,14 @@
 package strategy
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+var ErrStrategyNotFound = common.NewError(http.StatusNotFound, "strategy_not_found", "strategy not found")
+
 type Strategy struct {
 	ID              int64                 `json:"id" db:"id"`
 	MasterUserID    int64                 `json:"master_user_id" db:"master_user_id"`
@@ -13,6 +16,18 @@ type Strategy struct {
 	Title           string                `json:"title" db:"title"`
 	Description     string                `json:"description" db:"description"`
 	Status          common.StrategyStatus `json:"status" db:"status"`
+	AutoCopy        bool                  `json:"auto_copy" db:"auto_copy"`
 	CreatedAt       time.Time             `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time             `json:"updated_at" db:"updated_at"`
 }
+
+// StatusHistory — смена статуса стратегии; автор пуст для изменений воркеров
+type StatusHistory struct {
+	ID         int64                 `json:"id" db:"id"`
+	StrategyID int64                 `json:"strategy_id" db:"strategy_id"`
+	OldStatus  common.StrategyStatus `json:"old_status" db:"old_status"`
+	NewStatus  common.StrategyStatus `json:"new_status" db:"new_status"`
+	Reason     *string               `json:"reason,omitempty" db:"reason"`
+	ChangedBy  *int64                `json:"changed_by,omitempty" db:"changed_by"`
+	CreatedAt  time.Time             `json:"created_at" db:"created_at"`
+}
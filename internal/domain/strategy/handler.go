@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -37,6 +38,10 @@ func (h *Handler) Create(c *gin.Context) {
 
 	strategy, err := h.useCase.Create(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to create strategy", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -85,9 +90,9 @@ func (h *Handler) GetByID(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param        status query string false "Фильтр по статусу"
-// @Param        min_roi query number false "Минимальный ROI"
-// @Param        max_drawdown_pct query number false "Максимальная просадка"
-// @Param        risk_score query int false "Оценка риска"
+// @Param        min_roi query number false "Минимальный ROI, %"
+// @Param        max_drawdown_pct query number false "Максимальная просадка, %"
+// @Param        risk_score query int false "Оценка риска (1–10)"
 // @Param        page query int false "Номер страницы" default(1)
 // @Param        limit query int false "Количество записей на странице" default(20)
 // @Success      200 {object} StrategyListResponse
@@ -138,6 +143,10 @@ func (h *Handler) Update(c *gin.Context) {
 
 	strategy, err := h.useCase.Update(c.Request.Context(), strategyID, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to update strategy", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -173,6 +182,10 @@ func (h *Handler) ChangeStatus(c *gin.Context) {
 
 	strategy, err := h.useCase.ChangeStatus(c.Request.Context(), strategyID, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to change strategy status", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -183,13 +196,16 @@ func (h *Handler) ChangeStatus(c *gin.Context) {
 
 // GetSummary godoc
 // @Summary      Получить сводку по стратегии
-// @Description  Возвращает суммарную статистику по стратегии
+// @Description  Возвращает суммарную статистику по стратегии; прибыль пересчитывается в валюту currency по курсу на дату закрытия сделки
 // @Tags         strategies
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "ID стратегии"
+// @Param        currency query string false "Валюта сводки (ISO 4217); по умолчанию валюта счёта мастера"
 // @Success      200 {object} StrategySummary
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /strategies/{id}/summary [get]
 func (h *Handler) GetSummary(c *gin.Context) {
@@ -199,8 +215,18 @@ func (h *Handler) GetSummary(c *gin.Context) {
 		return
 	}
 
-	summary, err := h.useCase.GetSummary(c.Request.Context(), strategyID)
+	var req SummaryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := h.useCase.GetSummary(c.Request.Context(), strategyID, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get strategy summary", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -208,3 +234,36 @@ func (h *Handler) GetSummary(c *gin.Context) {
 
 	c.JSON(http.StatusOK, summary)
 }
+
+// GetStatusHistory godoc
+// @Summary      История статусов стратегии
+// @Description  Возвращает смены статуса стратегии с причиной и автором, начиная с последней
+// @Tags         strategies
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID стратегии"
+// @Success      200 {array} StatusHistory
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /strategies/{id}/status-history [get]
+func (h *Handler) GetStatusHistory(c *gin.Context) {
+	strategyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid strategy id"})
+		return
+	}
+
+	history, err := h.useCase.GetStatusHistory(c.Request.Context(), strategyID)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get strategy status history", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
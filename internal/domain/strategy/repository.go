@@ -7,7 +7,9 @@ import (
 	"math"
 	"strings"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -21,7 +23,8 @@ type Repository interface {
 	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Strategy, error)
 	GetByAccountID(ctx context.Context, accountID int64) (*Strategy, error)
 	GetActiveByID(ctx context.Context, id int64) (*Strategy, error)
-	GetSummary(ctx context.Context, id int64) (*StrategySummary, error)
+	GetSummary(ctx context.Context, id int64, reportCurrency string) (*StrategySummary, error)
+	GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error)
 }
 
 type repository struct {
@@ -35,18 +38,19 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 
 func (r *repository) Create(ctx context.Context, req *CreateStrategyRequest) (*Strategy, error) {
 	query := `
-		INSERT INTO strategies (master_user_id, master_account_id, title, description, status)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		INSERT INTO strategies (master_user_id, master_account_id, title, description, status, auto_copy)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 	`
 
 	var strategy Strategy
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.UserID,
 		req.AccountID,
 		req.Nickname,
 		req.Summary,
 		common.StrategyStatusPreparing,
+		req.AutoCopy,
 	).StructScan(&strategy)
 	if err != nil {
 		r.logger.Error("Failed to create strategy",
@@ -63,11 +67,15 @@ func (r *repository) Create(ctx context.Context, req *CreateStrategyRequest) (*S
 	return &strategy, nil
 }
 
+const performanceColumns = `strategy_id AS id, title, status, total_subscriptions, active_subscriptions,
+	total_copied_trades, total_profit, total_commissions, updated_at,
+	roi, max_drawdown_pct, sharpe_ratio, sortino_ratio, win_rate, profit_factor, risk_score, metrics_updated_at`
+
 func (r *repository) GetByID(ctx context.Context, id int64) (*GetStrategyByIDResponse, error) {
-	query := `SELECT * FROM vw_strategy_performance WHERE id = $1`
+	query := `SELECT ` + performanceColumns + ` FROM vw_strategy_performance WHERE strategy_id = $1`
 
 	var response GetStrategyByIDResponse
-	err := r.db.GetContext(ctx, &response, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &response, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -117,22 +125,22 @@ func (r *repository) List(ctx context.Context, filter *StrategyFilter) (*common.
 	countQuery := `SELECT COUNT(*) FROM vw_strategy_performance ` + whereSQL
 
 	var total int64
-	if err := r.db.GetContext(ctx, &total, countQuery, args...); err != nil {
+	if err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...); err != nil {
 		return nil, fmt.Errorf("count failed: %w", err)
 	}
 
 	filter.Pagination.SetDefaults()
 
 	mainQuery := fmt.Sprintf(`
-		SELECT *
+		SELECT %s
 		FROM vw_strategy_performance
 		%s
-		ORDER BY total_profit DESC
+		ORDER BY total_profit DESC, strategy_id
 		LIMIT %d OFFSET %d
-	`, whereSQL, filter.Limit, filter.Offset)
+	`, performanceColumns, whereSQL, filter.Limit, filter.Offset)
 
 	var items []GetStrategyByIDResponse
-	if err := r.db.SelectContext(ctx, &items, mainQuery, args...); err != nil {
+	if err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &items, mainQuery, args...); err != nil {
 		return nil, fmt.Errorf("list failed: %w", err)
 	}
 
@@ -164,6 +172,12 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateStrategyRe
 		argIndex++
 	}
 
+	if req.AutoCopy != nil {
+		setClauses = append(setClauses, fmt.Sprintf("auto_copy = $%d", argIndex))
+		args = append(args, *req.AutoCopy)
+		argIndex++
+	}
+
 	if len(setClauses) == 0 {
 		strategy, err := r.GetBaseByID(ctx, id)
 		if err != nil {
@@ -179,11 +193,11 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateStrategyRe
 		UPDATE strategies
 		SET %s
 		WHERE id = $%d
-		RETURNING id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		RETURNING id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var strategy Strategy
-	err := r.db.QueryRowxContext(ctx, query, args...).StructScan(&strategy)
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query, args...).StructScan(&strategy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("strategy not found: %d", id)
@@ -204,11 +218,21 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 		UPDATE strategies
 		SET status = $1, updated_at = now()
 		WHERE id = $2
-		RETURNING id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		RETURNING id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 	`
 
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingStatusReason, req.StatusReason); err != nil {
+		return nil, err
+	}
+
 	var strategy Strategy
-	err := r.db.QueryRowxContext(ctx, query, req.Status, id).StructScan(&strategy)
+	err = tx.QueryRowxContext(ctx, query, req.Status, id).StructScan(&strategy)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("strategy not found: %d", id)
@@ -220,6 +244,10 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 		return nil, fmt.Errorf("change strategy status: %w", err)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
 	r.logger.Info("Strategy status changed",
 		zap.Int64("id", strategy.ID),
 		zap.String("status", string(strategy.Status)))
@@ -229,7 +257,7 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 
 func (r *repository) GetByAccountID(ctx context.Context, accountID int64) (*Strategy, error) {
 	query := `
-		SELECT id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		SELECT id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 		FROM strategies
 		WHERE master_account_id = $1
 		ORDER BY created_at DESC
@@ -237,7 +265,7 @@ func (r *repository) GetByAccountID(ctx context.Context, accountID int64) (*Stra
 	`
 
 	var strategy Strategy
-	err := r.db.GetContext(ctx, &strategy, query, accountID)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &strategy, query, accountID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -253,13 +281,13 @@ func (r *repository) GetByAccountID(ctx context.Context, accountID int64) (*Stra
 
 func (r *repository) GetActiveByID(ctx context.Context, id int64) (*Strategy, error) {
 	query := `
-		SELECT id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		SELECT id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 		FROM strategies
 		WHERE id = $1 AND status = 'active'
 	`
 
 	var strategy Strategy
-	err := r.db.GetContext(ctx, &strategy, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &strategy, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -273,31 +301,43 @@ func (r *repository) GetActiveByID(ctx context.Context, id int64) (*Strategy, er
 	return &strategy, nil
 }
 
-func (r *repository) GetSummary(ctx context.Context, id int64) (*StrategySummary, error) {
-	r.logger.Info("Getting strategy summary", zap.Int64("id", id))
+// GetSummary считает прибыль стратегии в валюте reportCurrency; пустая валюта —
+// валюта счёта мастера. Возвращает nil, если стратегии нет.
+func (r *repository) GetSummary(ctx context.Context, id int64, reportCurrency string) (*StrategySummary, error) {
+	r.logger.Info("Getting strategy summary",
+		zap.Int64("id", id),
+		zap.String("currency", reportCurrency))
 
-	query := `SELECT fn_get_strategy_total_profit($1) as total_profit`
+	query := `
+		SELECT s.id AS strategy_id,
+		       fn_get_strategy_total_profit(s.id, cur.code) AS total_profit,
+		       cur.code AS currency
+		FROM strategies s
+		JOIN accounts a ON a.id = s.master_account_id
+		CROSS JOIN LATERAL (SELECT COALESCE(NULLIF($2, ''), a.currency)::CHAR(3) AS code) cur
+		WHERE s.id = $1
+	`
 
-	var totalProfit float64
-	if err := r.db.GetContext(ctx, &totalProfit, query, id); err != nil {
-		return nil, fmt.Errorf("get strategy total profit: %w", err)
+	var summary StrategySummary
+	if err := dbtx.Conn(ctx, r.db).GetContext(ctx, &summary, query, id, reportCurrency); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get strategy total profit: %w", currency.MapError(err))
 	}
 
-	return &StrategySummary{
-		StrategyID:  id,
-		TotalProfit: totalProfit,
-	}, nil
+	return &summary, nil
 }
 
 func (r *repository) GetBaseByID(ctx context.Context, id int64) (*Strategy, error) {
 	query := `
-		SELECT id, master_user_id, master_account_id, title, description, status, created_at, updated_at
+		SELECT id, master_user_id, master_account_id, title, description, status, auto_copy, created_at, updated_at
 		FROM strategies
 		WHERE id = $1
 	`
 
 	var strategy Strategy
-	err := r.db.GetContext(ctx, &strategy, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &strategy, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -310,3 +350,23 @@ func (r *repository) GetBaseByID(ctx context.Context, id int64) (*Strategy, erro
 
 	return &strategy, nil
 }
+
+func (r *repository) GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error) {
+	query := `
+		SELECT id, strategy_id, old_status, new_status, reason, changed_by, created_at
+		FROM strategy_status_history
+		WHERE strategy_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	history := []*StatusHistory{}
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &history, query, id)
+	if err != nil {
+		r.logger.Error("Failed to get strategy status history",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get strategy status history: %w", err)
+	}
+
+	return history, nil
+}
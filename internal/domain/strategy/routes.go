@@ -13,5 +13,6 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 		strategies.GET("/:id/summary", h.GetSummary)
 		strategies.PUT("/:id", h.Update)
 		strategies.POST("/:id/status", h.ChangeStatus)
+		strategies.GET("/:id/status-history", h.GetStatusHistory)
 	}
 }
@@ -3,8 +3,9 @@ package strategy
 import (
 	"context"
 	"fmt"
+	"strings"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/finlleyl/cp_database/internal/domain/subscription"
 	"go.uber.org/zap"
@@ -16,26 +17,24 @@ type UseCase interface {
 	List(ctx context.Context, filter *StrategyFilter) (*common.PaginatedResult[GetStrategyByIDResponse], error)
 	Update(ctx context.Context, id int64, req *UpdateStrategyRequest) (*Strategy, error)
 	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Strategy, error)
-	GetSummary(ctx context.Context, id int64) (*StrategySummary, error)
+	GetSummary(ctx context.Context, id int64, req *SummaryRequest) (*StrategySummary, error)
+	GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error)
 }
 
 type useCase struct {
 	repo             Repository
 	subscriptionRepo subscription.Repository
-	auditRepo        audit.Repository
 	logger           *zap.Logger
 }
 
 func NewUseCase(
 	repo Repository,
 	subscriptionRepo subscription.Repository,
-	auditRepo audit.Repository,
 	logger *zap.Logger,
 ) UseCase {
 	return &useCase{
 		repo:             repo,
 		subscriptionRepo: subscriptionRepo,
-		auditRepo:        auditRepo,
 		logger:           logger,
 	}
 }
@@ -46,18 +45,18 @@ func (u *useCase) Create(ctx context.Context, req *CreateStrategyRequest) (*Stra
 		zap.String("nickname", req.Nickname),
 		zap.Int64("account_id", req.AccountID))
 
+	if _, err := auth.RequireRole(ctx, common.UserRoleMaster); err != nil {
+		return nil, err
+	}
+	if _, err := auth.RequireUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
 	strategy, err := u.repo.Create(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("create strategy: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeStrategy,
-		EntityID:   strategy.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   strategy,
-	})
-
 	return strategy, nil
 }
 
@@ -78,9 +77,8 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateStrategyReque
 
 	u.logger.Info("UseCase: Updating strategy", zap.Int64("id", id))
 
-	oldStrategy, err := u.repo.GetBaseByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get strategy: %w", err)
+	if _, err := u.getOwned(ctx, id); err != nil {
+		return nil, err
 	}
 
 	strategy, err := u.repo.Update(ctx, id, req)
@@ -88,14 +86,6 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateStrategyReque
 		return nil, fmt.Errorf("update strategy: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeStrategy,
-		EntityID:   id,
-		Action:     audit.AuditActionUpdate,
-		OldValue:   oldStrategy,
-		NewValue:   strategy,
-	})
-
 	return strategy, nil
 }
 
@@ -105,9 +95,8 @@ func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusR
 		zap.Int64("id", id),
 		zap.String("status", string(req.Status)))
 
-	oldStrategy, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get strategy: %w", err)
+	if _, err := u.getOwned(ctx, id); err != nil {
+		return nil, err
 	}
 
 	if req.Status == common.StrategyStatusArchived || req.Status == common.StrategyStatusDeleted {
@@ -125,29 +114,61 @@ func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusR
 		return nil, fmt.Errorf("change strategy status: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeStrategy,
-		EntityID:   strategy.ID,
-		Action:     audit.AuditActionStatusChange,
-		OldValue:   oldStrategy,
-		NewValue:   strategy,
-		Changes: map[string]interface{}{
-			"old_status":    oldStrategy.Status,
-			"new_status":    strategy.Status,
-			"status_reason": req.StatusReason,
-		},
-	})
+	return strategy, nil
+}
+
+// getOwned загружает стратегию и проверяет, что её изменяет мастер-владелец
+func (u *useCase) getOwned(ctx context.Context, id int64) (*Strategy, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleMaster); err != nil {
+		return nil, err
+	}
+
+	strategy, err := u.repo.GetBaseByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy: %w", err)
+	}
+	if strategy == nil {
+		return nil, ErrStrategyNotFound
+	}
+
+	if _, err := auth.RequireUser(ctx, strategy.MasterUserID); err != nil {
+		return nil, err
+	}
 
 	return strategy, nil
 }
 
-func (u *useCase) GetSummary(ctx context.Context, id int64) (*StrategySummary, error) {
-	u.logger.Info("UseCase: Getting strategy summary", zap.Int64("id", id))
+func (u *useCase) GetSummary(ctx context.Context, id int64, req *SummaryRequest) (*StrategySummary, error) {
+	u.logger.Info("UseCase: Getting strategy summary",
+		zap.Int64("id", id),
+		zap.String("currency", req.Currency))
 
-	summary, err := u.repo.GetSummary(ctx, id)
+	summary, err := u.repo.GetSummary(ctx, id, strings.ToUpper(req.Currency))
 	if err != nil {
 		return nil, fmt.Errorf("get strategy summary: %w", err)
 	}
+	if summary == nil {
+		return nil, ErrStrategyNotFound
+	}
 
 	return summary, nil
 }
+
+func (u *useCase) GetStatusHistory(ctx context.Context, id int64) ([]*StatusHistory, error) {
+	u.logger.Info("UseCase: Getting strategy status history", zap.Int64("id", id))
+
+	strategy, err := u.repo.GetBaseByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy: %w", err)
+	}
+	if strategy == nil {
+		return nil, ErrStrategyNotFound
+	}
+
+	history, err := u.repo.GetStatusHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy status history: %w", err)
+	}
+
+	return history, nil
+}
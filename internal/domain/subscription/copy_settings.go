@@ -0,0 +1,75 @@
+package subscription
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CopySettings ограничивают, какие сделки мастера копируются на подписку
+type CopySettings struct {
+	AllowedSymbols   SymbolList `json:"allowed_symbols" db:"allowed_symbols" swaggertype:"array,string"`
+	DeniedSymbols    SymbolList `json:"denied_symbols" db:"denied_symbols" swaggertype:"array,string"`
+	MaxOpenPositions *int       `json:"max_open_positions,omitempty" db:"max_open_positions"`
+	StopCopyEquity   *float64   `json:"stop_copy_equity,omitempty" db:"stop_copy_equity"`
+}
+
+func (s CopySettings) Validate() error {
+	for _, symbol := range s.DeniedSymbols {
+		if s.AllowedSymbols.Contains(symbol) {
+			return ErrSymbolListsOverlap.WithDetails(map[string]string{"symbol": symbol})
+		}
+	}
+	return nil
+}
+
+// AllowsSymbol — пустой список разрешённых символов пропускает любой символ, кроме запрещённых
+func (s CopySettings) AllowsSymbol(symbol string) bool {
+	if s.DeniedSymbols.Contains(symbol) {
+		return false
+	}
+	return len(s.AllowedSymbols) == 0 || s.AllowedSymbols.Contains(symbol)
+}
+
+// SymbolList хранится в колонке TEXT[]; символы приводятся к верхнему регистру
+type SymbolList []string
+
+func NewSymbolList(symbols []string) SymbolList {
+	list := SymbolList{}
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !list.Contains(symbol) {
+			list = append(list, symbol)
+		}
+	}
+	return list
+}
+
+func (l SymbolList) Contains(symbol string) bool {
+	for _, s := range l {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value отдаёт список кодеку text[] драйвера pgx; пустой список пишется как '{}', а не NULL
+func (l SymbolList) Value() (driver.Value, error) {
+	if l == nil {
+		return pgtype.FlatArray[string]{}, nil
+	}
+	return pgtype.FlatArray[string](l), nil
+}
+
+// Scan разбирает text[] кодеком pgx и нормализует символы так же, как NewSymbolList
+func (l *SymbolList) Scan(src interface{}) error {
+	var symbols pgtype.FlatArray[string]
+	if err := pgtype.NewMap().SQLScanner(&symbols).Scan(src); err != nil {
+		return fmt.Errorf("scan symbol list: %w", err)
+	}
+	*l = NewSymbolList(symbols)
+	return nil
+}
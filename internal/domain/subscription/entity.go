@@ -1,43 +1,150 @@
 package subscription
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+var (
+	ErrSubscriptionNotFound = common.NewError(http.StatusNotFound, "subscription_not_found", "subscription not found")
+	ErrFixedLotRequired     = common.NewError(http.StatusBadRequest, "fixed_lot_required", "fixed_lot is required for fixed_lot sizing mode")
+	ErrInvalidLotRange      = common.NewError(http.StatusBadRequest, "invalid_lot_range", "min_lot must not exceed max_lot")
+	ErrSymbolListsOverlap   = common.NewError(http.StatusBadRequest, "symbol_lists_overlap", "symbol must not be both allowed and denied")
+
+	// ошибки проверки подписки при создании
+	ErrOfferNotFound          = common.NewError(http.StatusNotFound, "offer_not_found", "offer not found")
+	ErrOfferNotActive         = common.NewError(http.StatusConflict, "offer_not_active", "offer is not active")
+	ErrStrategyNotActive      = common.NewError(http.StatusConflict, "strategy_not_active", "strategy is not active")
+	ErrSelfSubscription       = common.NewError(http.StatusUnprocessableEntity, "self_subscription", "investor cannot subscribe to own strategy")
+	ErrAccountNotOwned        = common.NewError(http.StatusForbidden, "account_not_owned", "investor account belongs to another user")
+	ErrNotInvestorAccount     = common.NewError(http.StatusUnprocessableEntity, "not_investor_account", "account is not an investor account")
+	ErrCurrencyNotConvertible = common.NewError(http.StatusUnprocessableEntity, "currency_not_convertible", "no exchange rate between investor and master account currencies")
+	ErrAlreadySubscribed      = common.NewError(http.StatusConflict, "already_subscribed", "investor already has a subscription to this offer")
+)
+
+// SizingSettings описывает, как объём сделки мастера пересчитывается для подписки
+type SizingSettings struct {
+	SizingMode       common.SizingMode `json:"sizing_mode" db:"sizing_mode"`
+	FixedLot         *float64          `json:"fixed_lot,omitempty" db:"fixed_lot"`
+	VolumeMultiplier float64           `json:"volume_multiplier" db:"volume_multiplier"`
+	MinLot           *float64          `json:"min_lot,omitempty" db:"min_lot"`
+	MaxLot           *float64          `json:"max_lot,omitempty" db:"max_lot"`
+}
+
+// OfferTarget — оффер, на который оформляется подписка, вместе со стратегией и валютой счёта мастера
+type OfferTarget struct {
+	OfferID        int64                 `db:"offer_id"`
+	OfferStatus    common.OfferStatus    `db:"offer_status"`
+	StrategyID     int64                 `db:"strategy_id"`
+	StrategyStatus common.StrategyStatus `db:"strategy_status"`
+	MasterUserID   int64                 `db:"master_user_id"`
+	MasterCurrency string                `db:"master_currency"`
+}
+
 type Subscription struct {
 	ID                int64                     `json:"id" db:"id"`
 	InvestorUserID    int64                     `json:"investor_user_id" db:"investor_user_id"`
 	InvestorAccountID int64                     `json:"investor_account_id" db:"investor_account_id"`
 	OfferID           int64                     `json:"offer_id" db:"offer_id"`
 	Status            common.SubscriptionStatus `json:"status" db:"status"`
-	CreatedAt         time.Time                 `json:"created_at" db:"created_at"`
-	UpdatedAt         time.Time                 `json:"updated_at" db:"updated_at"`
+	SizingSettings
+	CopySettings
+	RiskLimits
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateSubscriptionRequest struct {
 	InvestorUserID    int64 `json:"investor_user_id" binding:"required"`
 	InvestorAccountID int64 `json:"investor_account_id" binding:"required"`
 	OfferID           int64 `json:"offer_id" binding:"required"`
+
+	SizingRequest
+}
+
+// SizingRequest — настройки объёма в запросе; не переданные поля принимают значения по умолчанию
+type SizingRequest struct {
+	SizingMode       common.SizingMode `json:"sizing_mode,omitempty" binding:"omitempty,oneof=fixed_lot multiplier equity_ratio"`
+	FixedLot         *float64          `json:"fixed_lot,omitempty" binding:"omitempty,gt=0"`
+	VolumeMultiplier *float64          `json:"volume_multiplier,omitempty" binding:"omitempty,gt=0"`
+	MinLot           *float64          `json:"min_lot,omitempty" binding:"omitempty,gt=0"`
+	MaxLot           *float64          `json:"max_lot,omitempty" binding:"omitempty,gt=0"`
 }
 
+func (r *SizingRequest) SizingSettings() SizingSettings {
+	settings := SizingSettings{
+		SizingMode:       r.SizingMode,
+		FixedLot:         r.FixedLot,
+		VolumeMultiplier: 1,
+		MinLot:           r.MinLot,
+		MaxLot:           r.MaxLot,
+	}
+	if settings.SizingMode == "" {
+		settings.SizingMode = common.SizingModeMultiplier
+	}
+	if r.VolumeMultiplier != nil {
+		settings.VolumeMultiplier = *r.VolumeMultiplier
+	}
+	return settings
+}
+
+func (s SizingSettings) Validate() error {
+	if s.SizingMode == common.SizingModeFixedLot && s.FixedLot == nil {
+		return ErrFixedLotRequired
+	}
+	if s.MinLot != nil && s.MaxLot != nil && *s.MinLot > *s.MaxLot {
+		return ErrInvalidLotRange
+	}
+	return nil
+}
+
+// UpdateSubscriptionRequest целиком заменяет настройки копирования подписки
 type UpdateSubscriptionRequest struct {
+	SizingRequest
+
+	AllowedSymbols   []string `json:"allowed_symbols,omitempty"`
+	DeniedSymbols    []string `json:"denied_symbols,omitempty"`
+	MaxOpenPositions *int     `json:"max_open_positions,omitempty" binding:"omitempty,gt=0"`
+	StopCopyEquity   *float64 `json:"stop_copy_equity,omitempty" binding:"omitempty,gt=0"`
 
+	MaxDrawdownPct *float64 `json:"max_drawdown_pct,omitempty" binding:"omitempty,gt=0,lte=100"`
+	MaxDailyLoss   *float64 `json:"max_daily_loss,omitempty" binding:"omitempty,gt=0"`
+	MinEquity      *float64 `json:"min_equity,omitempty" binding:"omitempty,gt=0"`
 }
 
+func (r *UpdateSubscriptionRequest) CopySettings() CopySettings {
+	return CopySettings{
+		AllowedSymbols:   NewSymbolList(r.AllowedSymbols),
+		DeniedSymbols:    NewSymbolList(r.DeniedSymbols),
+		MaxOpenPositions: r.MaxOpenPositions,
+		StopCopyEquity:   r.StopCopyEquity,
+	}
+}
+
+func (r *UpdateSubscriptionRequest) RiskLimits() RiskLimits {
+	return RiskLimits{
+		MaxDrawdownPct: r.MaxDrawdownPct,
+		MaxDailyLoss:   r.MaxDailyLoss,
+		MinEquity:      r.MinEquity,
+	}
+}
+
+// ChangeStatusRequest — переход статуса; допустимые переходы заданы в statusTransitions
 type ChangeStatusRequest struct {
 	Status       common.SubscriptionStatus `json:"status" binding:"required,oneof=active archived suspended deleted"`
 	StatusReason string                    `json:"status_reason"`
 }
 
+// SubscriptionStatusHistory — смена статуса подписки; автор пуст для изменений воркеров
 type SubscriptionStatusHistory struct {
 	ID             int64                     `json:"id" db:"id"`
 	SubscriptionID int64                     `json:"subscription_id" db:"subscription_id"`
 	OldStatus      common.SubscriptionStatus `json:"old_status" db:"old_status"`
 	NewStatus      common.SubscriptionStatus `json:"new_status" db:"new_status"`
-	Reason         string                    `json:"reason" db:"reason"`
-	ChangedBy      int64                     `json:"changed_by" db:"changed_by"`
+	Reason         *string                   `json:"reason,omitempty" db:"reason"`
+	ChangedBy      *int64                    `json:"changed_by,omitempty" db:"changed_by"`
 	CreatedAt      time.Time                 `json:"created_at" db:"created_at"`
 }
 
@@ -55,4 +162,4 @@ type SubscriptionListResponse struct {
 	Page       int            `json:"page"`
 	Limit      int            `json:"limit"`
 	TotalPages int            `json:"total_pages"`
-}
\ No newline at end of file
+}
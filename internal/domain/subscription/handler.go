@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -19,13 +20,18 @@ func NewHandler(useCase UseCase, logger *zap.Logger) *Handler {
 
 // Create godoc
 // @Summary      Создать подписку
-// @Description  Создаёт новую подписку инвестора на оффер
+// @Description  Создаёт новую подписку инвестора на оффер. Оффер и его стратегия должны быть активны, счёт — инвесторским счётом того же пользователя с валютой, пересчитываемой в валюту счёта мастера. Подписаться на свою стратегию или повторно на тот же оффер нельзя
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        request body CreateSubscriptionRequest true "Данные подписки"
 // @Success      201 {object} Subscription
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /subscriptions [post]
 func (h *Handler) Create(c *gin.Context) {
@@ -37,6 +43,10 @@ func (h *Handler) Create(c *gin.Context) {
 
 	subscription, err := h.useCase.Create(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to create subscription", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -60,7 +70,7 @@ func (h *Handler) Create(c *gin.Context) {
 func (h *Handler) GetByUUID(c *gin.Context) {
 	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription uuid"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
 		return
 	}
 
@@ -111,21 +121,24 @@ func (h *Handler) List(c *gin.Context) {
 }
 
 // Update godoc
-// @Summary      Обновить подписку
-// @Description  Обновляет данные подписки по ID
+// @Summary      Обновить настройки копирования
+// @Description  Заменяет настройки объёма и фильтры копирования подписки: множитель объёма, ограничения лота, списки разрешённых и запрещённых символов, лимит открытых позиций, порог эквити и лимиты риска (просадка, дневной убыток, минимальное эквити). Не переданные поля сбрасываются к значениям по умолчанию
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "ID подписки"
-// @Param        request body UpdateSubscriptionRequest true "Данные для обновления"
+// @Param        request body UpdateSubscriptionRequest true "Настройки копирования"
 // @Success      200 {object} Subscription
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /subscriptions/{id} [put]
 func (h *Handler) Update(c *gin.Context) {
 	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription uuid"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
 		return
 	}
 
@@ -137,6 +150,10 @@ func (h *Handler) Update(c *gin.Context) {
 
 	subscription, err := h.useCase.Update(c.Request.Context(), subscriptionID, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to update subscription", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -147,7 +164,7 @@ func (h *Handler) Update(c *gin.Context) {
 
 // ChangeStatus godoc
 // @Summary      Изменить статус подписки
-// @Description  Изменяет статус подписки (active, archived, suspended, deleted)
+// @Description  Изменяет статус подписки. Допустимые переходы: preparing → active, active ↔ suspended, любой статус, кроме deleted → archived, archived → deleted. Активация списывает регистрационную комиссию, архивация закрывает открытые скопированные сделки
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
@@ -155,12 +172,14 @@ func (h *Handler) Update(c *gin.Context) {
 // @Param        request body ChangeStatusRequest true "Новый статус"
 // @Success      200 {object} Subscription
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string "Переход недопустим; details.allowed — допустимые статусы"
 // @Failure      500 {object} map[string]string
 // @Router       /subscriptions/{id}/status [patch]
 func (h *Handler) ChangeStatus(c *gin.Context) {
 	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription uuid"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
 		return
 	}
 
@@ -170,10 +189,12 @@ func (h *Handler) ChangeStatus(c *gin.Context) {
 		return
 	}
 
-	changedBy := int64(0)
-
-	subscription, err := h.useCase.ChangeStatus(c.Request.Context(), subscriptionID, &req, changedBy)
+	subscription, err := h.useCase.ChangeStatus(c.Request.Context(), subscriptionID, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to change subscription status", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -184,24 +205,29 @@ func (h *Handler) ChangeStatus(c *gin.Context) {
 
 // GetStatusHistory godoc
 // @Summary      История статусов подписки
-// @Description  Возвращает историю изменений статуса подписки
+// @Description  Возвращает смены статуса подписки с причиной и автором, начиная с последней
 // @Tags         subscriptions
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "ID подписки"
 // @Success      200 {array} SubscriptionStatusHistory
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
 // @Failure      500 {object} map[string]string
-// @Router       /subscriptions/{id}/history [get]
+// @Router       /subscriptions/{id}/status-history [get]
 func (h *Handler) GetStatusHistory(c *gin.Context) {
 	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription uuid"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
 		return
 	}
 
 	history, err := h.useCase.GetStatusHistory(c.Request.Context(), subscriptionID)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to get subscription status history", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -209,3 +235,36 @@ func (h *Handler) GetStatusHistory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, history)
 }
+
+// GetRiskEvents godoc
+// @Summary      Срабатывания лимитов риска
+// @Description  Возвращает нарушения лимитов риска подписки (просадка, дневной убыток, минимальное эквити), после которых она была приостановлена, начиная с последнего
+// @Tags         subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID подписки"
+// @Success      200 {array} RiskEvent
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /subscriptions/{id}/risk-events [get]
+func (h *Handler) GetRiskEvents(c *gin.Context) {
+	subscriptionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	events, err := h.useCase.GetRiskEvents(c.Request.Context(), subscriptionID)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get subscription risk events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
@@ -7,7 +7,12 @@ import (
 var Module = fx.Options(
 	fx.Provide(
 		NewRepository,
-		NewUseCase,
+		fx.Annotate(
+			NewUseCase,
+			fx.ParamTags(``, ``, ``, `group:"subscription_status_hooks"`, ``),
+		),
 		NewHandler,
+		NewRiskWorker,
 	),
+	fx.Invoke(func(*RiskWorker) {}),
 )
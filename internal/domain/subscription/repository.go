@@ -3,11 +3,15 @@ package subscription
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
+	"time"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
@@ -17,11 +21,19 @@ type Repository interface {
 	GetByID(ctx context.Context, id int64) (*Subscription, error)
 	List(ctx context.Context, filter *SubscriptionFilter) (*common.PaginatedResult[Subscription], error)
 	Update(ctx context.Context, id int64, req *UpdateSubscriptionRequest) (*Subscription, error)
-	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest, changedBy int64) (*Subscription, error)
+	ChangeStatus(ctx context.Context, id int64, from common.SubscriptionStatus, req *ChangeStatusRequest) (*Subscription, error)
 	GetStatusHistory(ctx context.Context, id int64) ([]*SubscriptionStatusHistory, error)
 	GetActiveByStrategyID(ctx context.Context, strategyID int64) ([]*Subscription, error)
 	GetByOfferID(ctx context.Context, offerID int64) ([]*Subscription, error)
 	ArchiveByStrategyID(ctx context.Context, strategyID int64, reason string) error
+	GetOfferTarget(ctx context.Context, offerID int64) (*OfferTarget, error)
+	HasOpenSubscription(ctx context.Context, investorUserID, offerID int64) (bool, error)
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	DequeueRiskCheck(ctx context.Context) (int64, error)
+	RetryRiskCheck(ctx context.Context, id int64, lastError string, maxAttempts int, backoff time.Duration) (int, error)
+	GetRiskSnapshot(ctx context.Context, id int64, now time.Time) (*RiskSnapshot, error)
+	CreateRiskEvent(ctx context.Context, snapshot *RiskSnapshot, breach *RiskBreach) (*RiskEvent, error)
+	GetRiskEvents(ctx context.Context, id int64) ([]*RiskEvent, error)
 }
 
 type repository struct {
@@ -33,21 +45,37 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 	return &repository{db: db, logger: logger}
 }
 
+const subscriptionColumns = `id, investor_user_id, investor_account_id, offer_id, status,
+	sizing_mode, fixed_lot, volume_multiplier, min_lot, max_lot,
+	allowed_symbols, denied_symbols, max_open_positions, stop_copy_equity,
+	risk_max_drawdown_pct, risk_max_daily_loss, risk_min_equity, created_at, updated_at`
+
 func (r *repository) Create(ctx context.Context, req *CreateSubscriptionRequest) (*Subscription, error) {
 	query := `
-		INSERT INTO subscriptions (investor_user_id, investor_account_id, offer_id, status)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, investor_user_id, investor_account_id, offer_id, status, created_at, updated_at
-	`
+		INSERT INTO subscriptions (investor_user_id, investor_account_id, offer_id, status, sizing_mode, fixed_lot, volume_multiplier, min_lot, max_lot)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING ` + subscriptionColumns
+
+	settings := req.SizingSettings()
 
 	var subscription Subscription
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.InvestorUserID,
 		req.InvestorAccountID,
 		req.OfferID,
 		common.SubscriptionStatusPreparing,
+		settings.SizingMode,
+		settings.FixedLot,
+		settings.VolumeMultiplier,
+		settings.MinLot,
+		settings.MaxLot,
 	).StructScan(&subscription)
 	if err != nil {
+		// Параллельный запрос успел оформить подписку на тот же оффер
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "uq_subscriptions_open_offer" {
+			return nil, ErrAlreadySubscribed
+		}
 		r.logger.Error("Failed to create subscription",
 			zap.Int64("investor_account_id", req.InvestorAccountID),
 			zap.Int64("offer_id", req.OfferID),
@@ -63,14 +91,10 @@ func (r *repository) Create(ctx context.Context, req *CreateSubscriptionRequest)
 }
 
 func (r *repository) GetByID(ctx context.Context, id int64) (*Subscription, error) {
-	query := `
-		SELECT id, investor_user_id, investor_account_id, offer_id, status, created_at, updated_at
-		FROM subscriptions
-		WHERE id = $1
-	`
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
 
 	var subscription Subscription
-	err := r.db.GetContext(ctx, &subscription, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &subscription, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -118,14 +142,14 @@ func (r *repository) List(ctx context.Context, filter *SubscriptionFilter) (*com
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM subscriptions %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count subscriptions", zap.Error(err))
 		return nil, fmt.Errorf("count subscriptions: %w", err)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, investor_user_id, investor_account_id, offer_id, status, created_at, updated_at
+		SELECT `+subscriptionColumns+`
 		FROM subscriptions
 		%s
 		ORDER BY created_at DESC
@@ -135,7 +159,7 @@ func (r *repository) List(ctx context.Context, filter *SubscriptionFilter) (*com
 	args = append(args, filter.Limit, filter.Offset)
 
 	var subscriptions []Subscription
-	err = r.db.SelectContext(ctx, &subscriptions, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &subscriptions, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list subscriptions", zap.Error(err))
 		return nil, fmt.Errorf("list subscriptions: %w", err)
@@ -151,36 +175,76 @@ func (r *repository) List(ctx context.Context, filter *SubscriptionFilter) (*com
 }
 
 func (r *repository) Update(ctx context.Context, id int64, req *UpdateSubscriptionRequest) (*Subscription, error) {
+	query := `
+		UPDATE subscriptions
+		SET sizing_mode = $1, fixed_lot = $2, volume_multiplier = $3, min_lot = $4, max_lot = $5,
+		    allowed_symbols = $6, denied_symbols = $7, max_open_positions = $8, stop_copy_equity = $9,
+		    risk_max_drawdown_pct = $10, risk_max_daily_loss = $11, risk_min_equity = $12,
+		    updated_at = now()
+		WHERE id = $13
+		RETURNING ` + subscriptionColumns
 
-	return r.GetByID(ctx, id)
-}
-
-func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest, changedBy int64) (*Subscription, error) {
+	sizing := req.SizingSettings()
+	copySettings := req.CopySettings()
+	riskLimits := req.RiskLimits()
 
-	current, err := r.GetByID(ctx, id)
+	var subscription Subscription
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
+		sizing.SizingMode,
+		sizing.FixedLot,
+		sizing.VolumeMultiplier,
+		sizing.MinLot,
+		sizing.MaxLot,
+		copySettings.AllowedSymbols,
+		copySettings.DeniedSymbols,
+		copySettings.MaxOpenPositions,
+		copySettings.StopCopyEquity,
+		riskLimits.MaxDrawdownPct,
+		riskLimits.MaxDailyLoss,
+		riskLimits.MinEquity,
+		id,
+	).StructScan(&subscription)
 	if err != nil {
-		return nil, err
-	}
-	if current == nil {
-		return nil, fmt.Errorf("subscription not found: %d", id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to update subscription",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("update subscription: %w", err)
 	}
 
-	tx, err := r.db.BeginTxx(ctx, nil)
+	r.logger.Info("Subscription updated", zap.Int64("id", subscription.ID))
+
+	return &subscription, nil
+}
+
+// ChangeStatus переводит подписку из статуса from в req.Status. Если статус успел
+// измениться с момента проверки перехода, возвращает ErrInvalidStatusTransition.
+// Запись в subscription_status_history добавляет триггер в той же транзакции.
+func (r *repository) ChangeStatus(ctx context.Context, id int64, from common.SubscriptionStatus, req *ChangeStatusRequest) (*Subscription, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
 	if err != nil {
 		return nil, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingStatusReason, req.StatusReason); err != nil {
+		return nil, err
+	}
+
 	updateQuery := `
 		UPDATE subscriptions
 		SET status = $1, updated_at = now()
-		WHERE id = $2
-		RETURNING id, investor_user_id, investor_account_id, offer_id, status, created_at, updated_at
-	`
+		WHERE id = $2 AND status = $3
+		RETURNING ` + subscriptionColumns
 
 	var subscription Subscription
-	err = tx.QueryRowxContext(ctx, updateQuery, req.Status, id).StructScan(&subscription)
+	err = tx.QueryRowxContext(ctx, updateQuery, req.Status, id, from).StructScan(&subscription)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidStatusTransition
+		}
 		r.logger.Error("Failed to change subscription status",
 			zap.Int64("id", id),
 			zap.String("status", string(req.Status)),
@@ -188,25 +252,13 @@ func (r *repository) ChangeStatus(ctx context.Context, id int64, req *ChangeStat
 		return nil, fmt.Errorf("change subscription status: %w", err)
 	}
 
-	historyQuery := `
-		INSERT INTO subscription_status_history (subscription_id, old_status, new_status, reason, changed_by)
-		VALUES ($1, $2, $3, $4, $5)
-	`
-	_, err = tx.ExecContext(ctx, historyQuery, id, current.Status, req.Status, req.StatusReason, changedBy)
-	if err != nil {
-
-		r.logger.Warn("Failed to record status history (table might not exist)",
-			zap.Int64("id", id),
-			zap.Error(err))
-	}
-
 	if err = tx.Commit(); err != nil {
 		return nil, fmt.Errorf("commit transaction: %w", err)
 	}
 
 	r.logger.Info("Subscription status changed",
 		zap.Int64("id", subscription.ID),
-		zap.String("old_status", string(current.Status)),
+		zap.String("old_status", string(from)),
 		zap.String("new_status", string(subscription.Status)))
 
 	return &subscription, nil
@@ -217,11 +269,11 @@ func (r *repository) GetStatusHistory(ctx context.Context, id int64) ([]*Subscri
 		SELECT id, subscription_id, old_status, new_status, reason, changed_by, created_at
 		FROM subscription_status_history
 		WHERE subscription_id = $1
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 	`
 
-	var history []*SubscriptionStatusHistory
-	err := r.db.SelectContext(ctx, &history, query, id)
+	history := []*SubscriptionStatusHistory{}
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &history, query, id)
 	if err != nil {
 		r.logger.Error("Failed to get subscription status history",
 			zap.Int64("id", id),
@@ -234,15 +286,15 @@ func (r *repository) GetStatusHistory(ctx context.Context, id int64) ([]*Subscri
 
 func (r *repository) GetActiveByStrategyID(ctx context.Context, strategyID int64) ([]*Subscription, error) {
 	query := `
-		SELECT s.id, s.investor_user_id, s.investor_account_id, s.offer_id, s.status, s.created_at, s.updated_at
-		FROM subscriptions s
-		JOIN offers o ON s.offer_id = o.id
-		WHERE o.strategy_id = $1 AND s.status = 'active'
-		ORDER BY s.created_at DESC
+		SELECT ` + subscriptionColumns + `
+		FROM subscriptions
+		WHERE status = 'active'
+		AND offer_id IN (SELECT id FROM offers WHERE strategy_id = $1)
+		ORDER BY created_at DESC
 	`
 
 	var subscriptions []*Subscription
-	err := r.db.SelectContext(ctx, &subscriptions, query, strategyID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &subscriptions, query, strategyID)
 	if err != nil {
 		r.logger.Error("Failed to get active subscriptions by strategy ID",
 			zap.Int64("strategy_id", strategyID),
@@ -255,14 +307,14 @@ func (r *repository) GetActiveByStrategyID(ctx context.Context, strategyID int64
 
 func (r *repository) GetByOfferID(ctx context.Context, offerID int64) ([]*Subscription, error) {
 	query := `
-		SELECT id, investor_user_id, investor_account_id, offer_id, status, created_at, updated_at
+		SELECT ` + subscriptionColumns + `
 		FROM subscriptions
 		WHERE offer_id = $1
 		ORDER BY created_at DESC
 	`
 
 	var subscriptions []*Subscription
-	err := r.db.SelectContext(ctx, &subscriptions, query, offerID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &subscriptions, query, offerID)
 	if err != nil {
 		r.logger.Error("Failed to get subscriptions by offer ID",
 			zap.Int64("offer_id", offerID),
@@ -283,7 +335,17 @@ func (r *repository) ArchiveByStrategyID(ctx context.Context, strategyID int64,
 		AND s.status = 'active'
 	`
 
-	result, err := r.db.ExecContext(ctx, query, strategyID)
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := dbtx.SetLocal(ctx, tx, dbtx.SettingStatusReason, reason); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, query, strategyID)
 	if err != nil {
 		r.logger.Error("Failed to archive subscriptions by strategy ID",
 			zap.Int64("strategy_id", strategyID),
@@ -292,6 +354,10 @@ func (r *repository) ArchiveByStrategyID(ctx context.Context, strategyID int64,
 		return fmt.Errorf("archive subscriptions by strategy id: %w", err)
 	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
 	rowsAffected, _ := result.RowsAffected()
 	r.logger.Info("Subscriptions archived by strategy ID",
 		zap.Int64("strategy_id", strategyID),
@@ -300,3 +366,218 @@ func (r *repository) ArchiveByStrategyID(ctx context.Context, strategyID int64,
 
 	return nil
 }
+
+func (r *repository) GetOfferTarget(ctx context.Context, offerID int64) (*OfferTarget, error) {
+	query := `
+		SELECT o.id AS offer_id, o.status AS offer_status,
+		       s.id AS strategy_id, s.status AS strategy_status,
+		       s.master_user_id, a.currency AS master_currency
+		FROM offers o
+		JOIN strategies s ON s.id = o.strategy_id
+		JOIN accounts a ON a.id = s.master_account_id
+		WHERE o.id = $1
+	`
+
+	var target OfferTarget
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &target, query, offerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get subscription offer target",
+			zap.Int64("offer_id", offerID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get subscription offer target: %w", err)
+	}
+
+	return &target, nil
+}
+
+// HasOpenSubscription проверяет, есть ли у инвестора на оффер подписка, которая ещё не архивирована
+func (r *repository) HasOpenSubscription(ctx context.Context, investorUserID, offerID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM subscriptions
+			WHERE investor_user_id = $1 AND offer_id = $2
+			AND status IN ('preparing', 'active', 'suspended')
+		)
+	`
+
+	var exists bool
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &exists, query, investorUserID, offerID)
+	if err != nil {
+		r.logger.Error("Failed to check open subscription",
+			zap.Int64("investor_user_id", investorUserID),
+			zap.Int64("offer_id", offerID),
+			zap.Error(err))
+		return false, fmt.Errorf("check open subscription: %w", err)
+	}
+
+	return exists, nil
+}
+
+// WithinTx выполняет fn в одной транзакции: смена статуса, хуки и запись события
+// фиксируются или откатываются вместе
+func (r *repository) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return dbtx.RunInTx(ctx, r.db, fn)
+}
+
+// DequeueRiskCheck забирает подписку, время проверки которой наступило; 0 — таких нет.
+// Вызывается внутри WithinTx: при ошибке проверки подписка остаётся в очереди.
+func (r *repository) DequeueRiskCheck(ctx context.Context) (int64, error) {
+	query := `
+		DELETE FROM subscription_risk_queue
+		WHERE subscription_id = (
+			SELECT subscription_id
+			FROM subscription_risk_queue
+			WHERE next_attempt_at <= now()
+			ORDER BY next_attempt_at, subscription_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING subscription_id
+	`
+
+	var id int64
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &id, query)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		r.logger.Error("Failed to dequeue subscription risk check", zap.Error(err))
+		return 0, fmt.Errorf("dequeue subscription risk check: %w", err)
+	}
+
+	return id, nil
+}
+
+// RetryRiskCheck откладывает проверку подписки после ошибки на attempts*backoff и
+// запоминает ошибку. После maxAttempts попыток подписка снимается с очереди.
+// Возвращает число сделанных попыток.
+func (r *repository) RetryRiskCheck(ctx context.Context, id int64, lastError string, maxAttempts int, backoff time.Duration) (int, error) {
+	query := `
+		UPDATE subscription_risk_queue
+		SET attempts = attempts + 1,
+		    next_attempt_at = now() + (attempts + 1) * make_interval(secs => $2),
+		    last_error = $3
+		WHERE subscription_id = $1
+		RETURNING attempts
+	`
+
+	var attempts int
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &attempts, query, id, backoff.Seconds(), lastError)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		r.logger.Error("Failed to retry subscription risk check",
+			zap.Int64("subscription_id", id),
+			zap.Error(err))
+		return 0, fmt.Errorf("retry subscription risk check: %w", err)
+	}
+
+	if attempts >= maxAttempts {
+		_, err = dbtx.Conn(ctx, r.db).ExecContext(ctx, `DELETE FROM subscription_risk_queue WHERE subscription_id = $1`, id)
+		if err != nil {
+			r.logger.Error("Failed to drop subscription risk check",
+				zap.Int64("subscription_id", id),
+				zap.Error(err))
+			return 0, fmt.Errorf("drop subscription risk check: %w", err)
+		}
+	}
+
+	return attempts, nil
+}
+
+// GetRiskSnapshot строит кривую капитала подписки: внесённое эквити счёта инвестора плюс
+// накопленная прибыль закрытых копий, её пик и прибыль копий, закрытых за сутки now (UTC)
+func (r *repository) GetRiskSnapshot(ctx context.Context, id int64, now time.Time) (*RiskSnapshot, error) {
+	query := `
+		SELECT s.id AS subscription_id, s.status,
+		       s.risk_max_drawdown_pct, s.risk_max_daily_loss, s.risk_min_equity,
+		       a.equity + COALESCE(c.total_profit, 0) AS equity,
+		       a.equity + GREATEST(COALESCE(c.peak_profit, 0), 0) AS peak_equity,
+		       COALESCE(d.daily_profit, 0) AS daily_profit
+		FROM subscriptions s
+		JOIN accounts a ON a.id = s.investor_account_id
+		LEFT JOIN LATERAL (
+			SELECT SUM(p.profit) AS total_profit, MAX(p.running) AS peak_profit
+			FROM (
+				SELECT ct.profit, SUM(ct.profit) OVER (ORDER BY ct.close_time, ct.id) AS running
+				FROM copied_trades ct
+				WHERE ct.subscription_id = s.id
+				AND ct.close_time IS NOT NULL AND ct.profit IS NOT NULL
+			) p
+		) c ON true
+		LEFT JOIN LATERAL (
+			SELECT SUM(ct.profit) AS daily_profit
+			FROM copied_trades ct
+			WHERE ct.subscription_id = s.id
+			AND ct.profit IS NOT NULL
+			AND ct.close_time >= date_trunc('day', $2::TIMESTAMPTZ AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+			AND ct.close_time <= $2
+		) d ON true
+		WHERE s.id = $1
+	`
+
+	var snapshot RiskSnapshot
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &snapshot, query, id, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get subscription risk snapshot",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get subscription risk snapshot: %w", err)
+	}
+
+	return &snapshot, nil
+}
+
+func (r *repository) CreateRiskEvent(ctx context.Context, snapshot *RiskSnapshot, breach *RiskBreach) (*RiskEvent, error) {
+	query := `
+		INSERT INTO subscription_risk_events (subscription_id, guard, limit_value, actual_value, equity)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, subscription_id, guard, limit_value, actual_value, equity, created_at
+	`
+
+	var event RiskEvent
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
+		snapshot.SubscriptionID,
+		breach.Guard,
+		breach.Limit,
+		breach.Actual,
+		snapshot.Equity,
+	).StructScan(&event)
+	if err != nil {
+		r.logger.Error("Failed to create subscription risk event",
+			zap.Int64("subscription_id", snapshot.SubscriptionID),
+			zap.String("guard", string(breach.Guard)),
+			zap.Error(err))
+		return nil, fmt.Errorf("create subscription risk event: %w", err)
+	}
+
+	return &event, nil
+}
+
+func (r *repository) GetRiskEvents(ctx context.Context, id int64) ([]*RiskEvent, error) {
+	query := `
+		SELECT id, subscription_id, guard, limit_value, actual_value, equity, created_at
+		FROM subscription_risk_events
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	events := []*RiskEvent{}
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &events, query, id)
+	if err != nil {
+		r.logger.Error("Failed to get subscription risk events",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return nil, fmt.Errorf("get subscription risk events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,89 @@
+package subscription
+
+import (
+	"math"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+// RiskLimits — защитные лимиты инвестора; при нарушении подписка приостанавливается.
+// Кривая капитала подписки начинается с эквити счёта инвестора (внесённого капитала)
+// и растёт на прибыль закрытых скопированных сделок.
+type RiskLimits struct {
+	MaxDrawdownPct *float64 `json:"max_drawdown_pct,omitempty" db:"risk_max_drawdown_pct"`
+	MaxDailyLoss   *float64 `json:"max_daily_loss,omitempty" db:"risk_max_daily_loss"`
+	MinEquity      *float64 `json:"min_equity,omitempty" db:"risk_min_equity"`
+}
+
+type RiskGuard string
+
+const (
+	RiskGuardMaxDrawdown  RiskGuard = "max_drawdown"
+	RiskGuardMaxDailyLoss RiskGuard = "max_daily_loss"
+	RiskGuardMinEquity    RiskGuard = "min_equity"
+)
+
+// riskReasonPrefix — префикс status_reason подписок, приостановленных по лимиту риска
+const riskReasonPrefix = "risk_guard:"
+
+// Проверка, завершившаяся ошибкой, повторяется с растущей паузой и после
+// riskCheckMaxAttempts попыток снимается с очереди
+const (
+	riskCheckMaxAttempts  = 5
+	riskCheckRetryBackoff = 30 * time.Second
+)
+
+// RiskSnapshot — состояние кривой капитала подписки на момент проверки лимитов
+type RiskSnapshot struct {
+	SubscriptionID int64                     `db:"subscription_id"`
+	Status         common.SubscriptionStatus `db:"status"`
+	RiskLimits
+	Equity      float64 `db:"equity"`
+	PeakEquity  float64 `db:"peak_equity"`
+	DailyProfit float64 `db:"daily_profit"`
+}
+
+// RiskBreach — нарушенный лимит и фактическое значение показателя
+type RiskBreach struct {
+	Guard  RiskGuard
+	Limit  float64
+	Actual float64
+}
+
+// Reason — машиночитаемая причина приостановки, например risk_guard:max_drawdown
+func (b *RiskBreach) Reason() string {
+	return riskReasonPrefix + string(b.Guard)
+}
+
+// Breach возвращает первый нарушенный лимит: минимальное эквити, просадка от пика,
+// дневной убыток; nil, если лимиты соблюдены
+func (s *RiskSnapshot) Breach() *RiskBreach {
+	if s.MinEquity != nil && s.Equity < *s.MinEquity {
+		return &RiskBreach{Guard: RiskGuardMinEquity, Limit: *s.MinEquity, Actual: s.Equity}
+	}
+
+	if s.MaxDrawdownPct != nil && s.PeakEquity > 0 {
+		drawdown := math.Round((s.PeakEquity-s.Equity)/s.PeakEquity*100*1e4) / 1e4
+		if drawdown >= *s.MaxDrawdownPct {
+			return &RiskBreach{Guard: RiskGuardMaxDrawdown, Limit: *s.MaxDrawdownPct, Actual: drawdown}
+		}
+	}
+
+	if s.MaxDailyLoss != nil && -s.DailyProfit >= *s.MaxDailyLoss {
+		return &RiskBreach{Guard: RiskGuardMaxDailyLoss, Limit: *s.MaxDailyLoss, Actual: -s.DailyProfit}
+	}
+
+	return nil
+}
+
+// RiskEvent — запись о нарушении лимита, после которого подписка была приостановлена
+type RiskEvent struct {
+	ID             int64     `json:"id" db:"id"`
+	SubscriptionID int64     `json:"subscription_id" db:"subscription_id"`
+	Guard          RiskGuard `json:"guard" db:"guard"`
+	LimitValue     float64   `json:"limit_value" db:"limit_value"`
+	ActualValue    float64   `json:"actual_value" db:"actual_value"`
+	Equity         float64   `json:"equity" db:"equity"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
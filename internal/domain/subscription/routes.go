@@ -9,9 +9,10 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 	{
 		subscriptions.POST("", h.Create)
 		subscriptions.GET("", h.List)
-		subscriptions.GET("/:uuid", h.GetByUUID)
-		subscriptions.PUT("/:uuid", h.Update)
-		subscriptions.POST("/:uuid/status", h.ChangeStatus)
-		subscriptions.GET("/:uuid/status-history", h.GetStatusHistory)
+		subscriptions.GET("/:id", h.GetByUUID)
+		subscriptions.PUT("/:id", h.Update)
+		subscriptions.POST("/:id/status", h.ChangeStatus)
+		subscriptions.GET("/:id/status-history", h.GetStatusHistory)
+		subscriptions.GET("/:id/risk-events", h.GetRiskEvents)
 	}
 }
@@ -0,0 +1,48 @@
+package subscription
+
+import (
+	"net/http"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+)
+
+var ErrInvalidStatusTransition = common.NewError(http.StatusConflict, "invalid_status_transition", "subscription status transition is not allowed")
+
+// statusTransitions — допустимые переходы статуса подписки. В архив можно перевести
+// подписку в любом статусе, кроме удалённой; удалить можно только архивную.
+var statusTransitions = map[common.SubscriptionStatus][]common.SubscriptionStatus{
+	common.SubscriptionStatusPreparing: {common.SubscriptionStatusActive, common.SubscriptionStatusArchived},
+	common.SubscriptionStatusActive:    {common.SubscriptionStatusSuspended, common.SubscriptionStatusArchived},
+	common.SubscriptionStatusSuspended: {common.SubscriptionStatusActive, common.SubscriptionStatusArchived},
+	common.SubscriptionStatusArchived:  {common.SubscriptionStatusDeleted},
+	common.SubscriptionStatusDeleted:   {},
+}
+
+// StatusTransitionDetails поясняет клиенту, почему переход отклонён
+type StatusTransitionDetails struct {
+	Status    common.SubscriptionStatus   `json:"status"`
+	Requested common.SubscriptionStatus   `json:"requested"`
+	Allowed   []common.SubscriptionStatus `json:"allowed"`
+}
+
+// AllowedTransitions возвращает статусы, в которые можно перейти из from
+func AllowedTransitions(from common.SubscriptionStatus) []common.SubscriptionStatus {
+	allowed := make([]common.SubscriptionStatus, len(statusTransitions[from]))
+	copy(allowed, statusTransitions[from])
+	return allowed
+}
+
+// CheckTransition возвращает ErrInvalidStatusTransition со списком допустимых статусов,
+// если из from нельзя перейти в to
+func CheckTransition(from, to common.SubscriptionStatus) error {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return nil
+		}
+	}
+	return ErrInvalidStatusTransition.WithDetails(StatusTransitionDetails{
+		Status:    from,
+		Requested: to,
+		Allowed:   AllowedTransitions(from),
+	})
+}
@@ -3,9 +3,12 @@ package subscription
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/domain/account"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
 	"go.uber.org/zap"
 )
 
@@ -14,18 +17,42 @@ type UseCase interface {
 	GetByID(ctx context.Context, id int64) (*Subscription, error)
 	List(ctx context.Context, filter *SubscriptionFilter) (*common.PaginatedResult[Subscription], error)
 	Update(ctx context.Context, id int64, req *UpdateSubscriptionRequest) (*Subscription, error)
-	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest, changedBy int64) (*Subscription, error)
+	ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Subscription, error)
 	GetStatusHistory(ctx context.Context, id int64) ([]*SubscriptionStatusHistory, error)
+	GetRiskEvents(ctx context.Context, id int64) ([]*RiskEvent, error)
+	EnforceRiskLimits(ctx context.Context) (int, error)
+}
+
+// StatusHook реагирует на смену статуса подписки. Хуки вызываются после
+// обновления статуса в той же транзакции запроса: ошибка хука откатывает смену статуса.
+// Биллинг списывает регистрационную комиссию при активации, модуль сделок закрывает
+// открытые копии при архивации.
+type StatusHook interface {
+	OnStatusChange(ctx context.Context, subscription *Subscription, from common.SubscriptionStatus) error
 }
 
 type useCase struct {
-	repo      Repository
-	auditRepo audit.Repository
-	logger    *zap.Logger
+	repo         Repository
+	accountRepo  account.Repository
+	currencyRepo currency.Repository
+	hooks        []StatusHook
+	logger       *zap.Logger
 }
 
-func NewUseCase(repo Repository, auditRepo audit.Repository, logger *zap.Logger) UseCase {
-	return &useCase{repo: repo, auditRepo: auditRepo, logger: logger}
+func NewUseCase(
+	repo Repository,
+	accountRepo account.Repository,
+	currencyRepo currency.Repository,
+	hooks []StatusHook,
+	logger *zap.Logger,
+) UseCase {
+	return &useCase{
+		repo:         repo,
+		accountRepo:  accountRepo,
+		currencyRepo: currencyRepo,
+		hooks:        hooks,
+		logger:       logger,
+	}
 }
 
 func (u *useCase) Create(ctx context.Context, req *CreateSubscriptionRequest) (*Subscription, error) {
@@ -34,18 +61,25 @@ func (u *useCase) Create(ctx context.Context, req *CreateSubscriptionRequest) (*
 		zap.Int64("investor_account_id", req.InvestorAccountID),
 		zap.Int64("offer_id", req.OfferID))
 
+	if _, err := auth.RequireRole(ctx, common.UserRoleInvestor); err != nil {
+		return nil, err
+	}
+	if _, err := auth.RequireUser(ctx, req.InvestorUserID); err != nil {
+		return nil, err
+	}
+
+	if err := req.SizingSettings().Validate(); err != nil {
+		return nil, err
+	}
+	if err := u.validateCreate(ctx, req); err != nil {
+		return nil, err
+	}
+
 	subscription, err := u.repo.Create(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("create subscription: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeSubscription,
-		EntityID:   subscription.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   subscription,
-	})
-
 	return subscription, nil
 }
 
@@ -66,55 +100,52 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateSubscriptionR
 
 	u.logger.Info("UseCase: Updating subscription", zap.Int64("id", id))
 
-	oldSubscription, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get subscription: %w", err)
+	if _, err := u.getOwned(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := req.SizingSettings().Validate(); err != nil {
+		return nil, err
+	}
+	if err := req.CopySettings().Validate(); err != nil {
+		return nil, err
 	}
+	// лимиты не проверяются задним числом: они сработают при следующем закрытии копии
 
 	subscription, err := u.repo.Update(ctx, id, req)
 	if err != nil {
 		return nil, fmt.Errorf("update subscription: %w", err)
 	}
-
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeSubscription,
-		EntityID:   subscription.ID,
-		Action:     audit.AuditActionUpdate,
-		OldValue:   oldSubscription,
-		NewValue:   subscription,
-	})
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
 
 	return subscription, nil
 }
 
-func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest, changedBy int64) (*Subscription, error) {
+func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusRequest) (*Subscription, error) {
 
 	u.logger.Info("UseCase: Changing subscription status",
 		zap.Int64("id", id),
 		zap.String("status", string(req.Status)))
 
-	oldSubscription, err := u.repo.GetByID(ctx, id)
+	current, err := u.getOwned(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("get subscription: %w", err)
+		return nil, err
+	}
+	if err := CheckTransition(current.Status, req.Status); err != nil {
+		return nil, err
 	}
 
-	subscription, err := u.repo.ChangeStatus(ctx, id, req, changedBy)
+	subscription, err := u.repo.ChangeStatus(ctx, id, current.Status, req)
 	if err != nil {
 		return nil, fmt.Errorf("change subscription status: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeSubscription,
-		EntityID:   subscription.ID,
-		Action:     audit.AuditActionStatusChange,
-		OldValue:   oldSubscription,
-		NewValue:   subscription,
-		Changes: map[string]interface{}{
-			"old_status":    oldSubscription.Status,
-			"new_status":    subscription.Status,
-			"status_reason": req.StatusReason,
-		},
-	})
+	for _, hook := range u.hooks {
+		if err := hook.OnStatusChange(ctx, subscription, current.Status); err != nil {
+			return nil, fmt.Errorf("subscription status hook: %w", err)
+		}
+	}
 
 	return subscription, nil
 }
@@ -122,5 +153,214 @@ func (u *useCase) ChangeStatus(ctx context.Context, id int64, req *ChangeStatusR
 func (u *useCase) GetStatusHistory(ctx context.Context, id int64) ([]*SubscriptionStatusHistory, error) {
 
 	u.logger.Info("UseCase: Getting subscription status history", zap.Int64("id", id))
-	return u.repo.GetStatusHistory(ctx, id)
+
+	subscription, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription: %w", err)
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	history, err := u.repo.GetStatusHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription status history: %w", err)
+	}
+
+	return history, nil
+}
+
+// validateCreate проверяет, что подписка оформляется на активный оффер активной
+// стратегии чужого мастера со своего инвесторского счёта, валюту которого можно
+// пересчитать в валюту счёта мастера, и что открытой подписки на оффер ещё нет
+func (u *useCase) validateCreate(ctx context.Context, req *CreateSubscriptionRequest) error {
+	target, err := u.repo.GetOfferTarget(ctx, req.OfferID)
+	if err != nil {
+		return fmt.Errorf("get offer: %w", err)
+	}
+	if target == nil {
+		return ErrOfferNotFound
+	}
+	if target.OfferStatus != common.OfferStatusActive {
+		return ErrOfferNotActive.WithDetails(map[string]string{"status": string(target.OfferStatus)})
+	}
+	if target.StrategyStatus != common.StrategyStatusActive {
+		return ErrStrategyNotActive.WithDetails(map[string]string{"status": string(target.StrategyStatus)})
+	}
+	if target.MasterUserID == req.InvestorUserID {
+		return ErrSelfSubscription
+	}
+
+	investorAccount, err := u.accountRepo.GetByID(ctx, req.InvestorAccountID)
+	if err != nil {
+		return fmt.Errorf("get investor account: %w", err)
+	}
+	if investorAccount == nil {
+		return account.ErrAccountNotFound
+	}
+	if investorAccount.UserID != req.InvestorUserID {
+		return ErrAccountNotOwned
+	}
+	if investorAccount.AccountType != account.AccountTypeInvestor {
+		return ErrNotInvestorAccount.WithDetails(map[string]string{"account_type": investorAccount.AccountType})
+	}
+
+	if investorAccount.Currency != target.MasterCurrency {
+		rate, err := u.currencyRepo.GetRate(ctx, investorAccount.Currency, target.MasterCurrency, time.Now())
+		if err != nil {
+			return fmt.Errorf("get fx rate: %w", err)
+		}
+		if rate == nil {
+			return ErrCurrencyNotConvertible.WithDetails(map[string]string{
+				"investor_currency": investorAccount.Currency,
+				"master_currency":   target.MasterCurrency,
+			})
+		}
+	}
+
+	exists, err := u.repo.HasOpenSubscription(ctx, req.InvestorUserID, req.OfferID)
+	if err != nil {
+		return fmt.Errorf("check open subscription: %w", err)
+	}
+	if exists {
+		return ErrAlreadySubscribed
+	}
+
+	return nil
+}
+
+// getOwned загружает подписку и проверяет, что ею управляет сам инвестор
+func (u *useCase) getOwned(ctx context.Context, id int64) (*Subscription, error) {
+	subscription, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription: %w", err)
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	if _, err := auth.RequireUser(ctx, subscription.InvestorUserID); err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (u *useCase) GetRiskEvents(ctx context.Context, id int64) ([]*RiskEvent, error) {
+	u.logger.Info("UseCase: Getting subscription risk events", zap.Int64("id", id))
+
+	subscription, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription: %w", err)
+	}
+	if subscription == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	events, err := u.repo.GetRiskEvents(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get subscription risk events: %w", err)
+	}
+
+	return events, nil
+}
+
+// EnforceRiskLimits проверяет лимиты подписок из subscription_risk_queue, пока в очереди
+// есть подписки, время проверки которых наступило, и приостанавливает нарушившие их
+// активные подписки. Ошибка проверки откладывает подписку, не прерывая обход. Возвращает
+// число приостановленных подписок (только для администратора).
+func (u *useCase) EnforceRiskLimits(ctx context.Context) (int, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+		return 0, err
+	}
+
+	suspended := 0
+	for {
+		var (
+			id    int64
+			event *RiskEvent
+		)
+		err := u.repo.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			id, err = u.repo.DequeueRiskCheck(ctx)
+			if err != nil || id == 0 {
+				return err
+			}
+			event, err = u.enforceRiskLimits(ctx, id)
+			return err
+		})
+		if err != nil && id == 0 {
+			return suspended, fmt.Errorf("enforce risk limits: %w", err)
+		}
+		if err != nil {
+			// Транзакция откатилась, подписка осталась в очереди: откладываем её,
+			// чтобы она не задерживала остальные проверки
+			if err := u.retryRiskCheck(ctx, id, err); err != nil {
+				return suspended, fmt.Errorf("enforce risk limits: %w", err)
+			}
+			continue
+		}
+		if id == 0 {
+			return suspended, nil
+		}
+
+		if event != nil {
+			suspended++
+			u.logger.Warn("Subscription suspended by risk guard",
+				zap.Int64("subscription_id", event.SubscriptionID),
+				zap.String("guard", string(event.Guard)),
+				zap.Float64("limit", event.LimitValue),
+				zap.Float64("actual", event.ActualValue),
+				zap.Float64("equity", event.Equity))
+		}
+	}
+}
+
+func (u *useCase) retryRiskCheck(ctx context.Context, id int64, checkErr error) error {
+	return u.repo.WithinTx(ctx, func(ctx context.Context) error {
+		attempts, err := u.repo.RetryRiskCheck(ctx, id, checkErr.Error(), riskCheckMaxAttempts, riskCheckRetryBackoff)
+		if err != nil {
+			return err
+		}
+
+		if attempts >= riskCheckMaxAttempts {
+			u.logger.Error("Subscription risk check dropped",
+				zap.Int64("subscription_id", id),
+				zap.Int("attempts", attempts),
+				zap.Error(checkErr))
+			return nil
+		}
+		u.logger.Warn("Subscription risk check will be retried",
+			zap.Int64("subscription_id", id),
+			zap.Int("attempts", attempts),
+			zap.Error(checkErr))
+		return nil
+	})
+}
+
+// enforceRiskLimits приостанавливает подписку через ChangeStatus и пишет событие
+// нарушения в той же транзакции; nil — лимиты соблюдены или подписка не активна
+func (u *useCase) enforceRiskLimits(ctx context.Context, id int64) (*RiskEvent, error) {
+	snapshot, err := u.repo.GetRiskSnapshot(ctx, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("get risk snapshot: %w", err)
+	}
+	if snapshot == nil || snapshot.Status != common.SubscriptionStatusActive {
+		return nil, nil
+	}
+
+	breach := snapshot.Breach()
+	if breach == nil {
+		return nil, nil
+	}
+
+	_, err = u.ChangeStatus(ctx, id, &ChangeStatusRequest{
+		Status:       common.SubscriptionStatusSuspended,
+		StatusReason: breach.Reason(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return u.repo.CreateRiskEvent(ctx, snapshot, breach)
 }
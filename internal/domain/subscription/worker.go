@@ -0,0 +1,36 @@
+package subscription
+
+import (
+	"context"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/worker"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const riskQueuePollInterval = 5 * time.Second
+
+// RiskWorker проверяет лимиты риска подписок из очереди subscription_risk_queue
+type RiskWorker struct {
+	useCase UseCase
+	logger  *zap.Logger
+}
+
+func NewRiskWorker(lc fx.Lifecycle, useCase UseCase, logger *zap.Logger) *RiskWorker {
+	w := &RiskWorker{useCase: useCase, logger: logger}
+	worker.Start(lc, logger, "Subscription risk worker", riskQueuePollInterval, w.runOnce)
+	return w
+}
+
+func (w *RiskWorker) runOnce(ctx context.Context) error {
+	suspended, err := w.useCase.EnforceRiskLimits(ctx)
+	if err != nil {
+		return err
+	}
+
+	if suspended > 0 {
+		w.logger.Info("Subscriptions suspended by risk guards", zap.Int("subscriptions", suspended))
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package trade
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
@@ -8,11 +9,49 @@ import (
 
 type TradeDirection string
 
+var (
+	ErrTradeNotFound      = common.NewError(http.StatusNotFound, "trade_not_found", "trade not found")
+	ErrTradeAlreadyClosed = common.NewError(http.StatusConflict, "trade_already_closed", "trade is already closed")
+	ErrInvalidCloseTime   = common.NewError(http.StatusBadRequest, "invalid_close_time", "close time is before open time")
+	ErrFanOutNotFound     = common.NewError(http.StatusNotFound, "fan_out_not_found", "automatic copy is not scheduled for this trade")
+
+	ErrMasterAccountMismatch = common.NewError(http.StatusBadRequest, "master_account_mismatch", "master account does not belong to strategy")
+)
+
 const (
 	TradeDirectionBuy  TradeDirection = "buy"
 	TradeDirectionSell TradeDirection = "sell"
 )
 
+type CopyOutcomeStatus string
+
+const (
+	CopyOutcomeCopied  CopyOutcomeStatus = "copied"
+	CopyOutcomeSkipped CopyOutcomeStatus = "skipped"
+	CopyOutcomeFailed  CopyOutcomeStatus = "failed"
+)
+
+type CopySkipReason string
+
+const (
+	CopySkipReasonInactive      CopySkipReason = "inactive"
+	CopySkipReasonAlreadyCopied CopySkipReason = "already_copied"
+	CopySkipReasonNotFound      CopySkipReason = "not_found"
+	// причины ниже задаются настройками копирования подписки
+	CopySkipReasonSymbolFiltered   CopySkipReason = "symbol_filtered"
+	CopySkipReasonMaxOpenPositions CopySkipReason = "max_open_positions"
+	CopySkipReasonEquityFloor      CopySkipReason = "equity_floor"
+)
+
+type FanOutStatus string
+
+const (
+	FanOutStatusPending    FanOutStatus = "pending"
+	FanOutStatusProcessing FanOutStatus = "processing"
+	FanOutStatusDone       FanOutStatus = "done"
+	FanOutStatusFailed     FanOutStatus = "failed"
+)
+
 type Trade struct {
 	ID              int64          `json:"id" db:"id"`
 	StrategyID      int64          `json:"strategy_id" db:"strategy_id"`
@@ -31,17 +70,19 @@ type Trade struct {
 }
 
 type CopiedTrade struct {
-	ID                int64      `json:"id" db:"id"`
-	TradeID           int64      `json:"trade_id" db:"trade_id"`
-	SubscriptionID    int64      `json:"subscription_id" db:"subscription_id"`
-	InvestorAccountID int64      `json:"investor_account_id" db:"investor_account_id"`
-	VolumeLots        float64    `json:"volume_lots" db:"volume_lots"`
-	Profit            *float64   `json:"profit,omitempty" db:"profit"`
-	Commission        *float64   `json:"commission,omitempty" db:"commission"`
-	Swap              *float64   `json:"swap,omitempty" db:"swap"`
-	OpenTime          time.Time  `json:"open_time" db:"open_time"`
-	CloseTime         *time.Time `json:"close_time,omitempty" db:"close_time"`
-	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	ID                int64              `json:"id" db:"id"`
+	TradeID           int64              `json:"trade_id" db:"trade_id"`
+	SubscriptionID    int64              `json:"subscription_id" db:"subscription_id"`
+	InvestorAccountID int64              `json:"investor_account_id" db:"investor_account_id"`
+	VolumeLots        float64            `json:"volume_lots" db:"volume_lots"`
+	SizingMode        *common.SizingMode `json:"sizing_mode,omitempty" db:"sizing_mode"`
+	SizingRatio       *float64           `json:"sizing_ratio,omitempty" db:"sizing_ratio"`
+	Profit            *float64           `json:"profit,omitempty" db:"profit"`
+	Commission        *float64           `json:"commission,omitempty" db:"commission"`
+	Swap              *float64           `json:"swap,omitempty" db:"swap"`
+	OpenTime          time.Time          `json:"open_time" db:"open_time"`
+	CloseTime         *time.Time         `json:"close_time,omitempty" db:"close_time"`
+	CreatedAt         time.Time          `json:"created_at" db:"created_at"`
 }
 
 type CreateTradeRequest struct {
@@ -55,11 +96,13 @@ type CreateTradeRequest struct {
 }
 
 type CreateCopiedTradeRequest struct {
-	TradeID           int64     `json:"trade_id" binding:"required"`
-	SubscriptionID    int64     `json:"subscription_id" binding:"required"`
-	InvestorAccountID int64     `json:"investor_account_id" binding:"required"`
-	VolumeLots        float64   `json:"volume_lots" binding:"required,gt=0"`
-	OpenTime          time.Time `json:"open_time" binding:"required"`
+	TradeID           int64              `json:"trade_id" binding:"required"`
+	SubscriptionID    int64              `json:"subscription_id" binding:"required"`
+	InvestorAccountID int64              `json:"investor_account_id" binding:"required"`
+	VolumeLots        float64            `json:"volume_lots" binding:"required,gt=0"`
+	SizingMode        *common.SizingMode `json:"sizing_mode,omitempty"`
+	SizingRatio       *float64           `json:"sizing_ratio,omitempty"`
+	OpenTime          time.Time          `json:"open_time" binding:"required"`
 }
 
 type TradeFilter struct {
@@ -74,6 +117,11 @@ type CopiedTradeFilter struct {
 	common.Pagination
 }
 
+type CloseTradeRequest struct {
+	ClosePrice float64    `json:"close_price" binding:"required,gt=0"`
+	CloseTime  *time.Time `json:"close_time,omitempty"`
+}
+
 type CopyTradeRequest struct {
 	SubscriptionIDs []int64 `json:"subscription_ids,omitempty"`
 }
@@ -96,8 +144,63 @@ type CopiedTradeListResponse struct {
 	TotalPages int           `json:"total_pages"`
 }
 
+// CopyOutcome описывает результат копирования сделки для одной подписки
+type CopyOutcome struct {
+	SubscriptionID int64             `json:"subscription_id"`
+	Status         CopyOutcomeStatus `json:"status"`
+	Reason         CopySkipReason    `json:"reason,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	CopiedTradeID  *int64            `json:"copied_trade_id,omitempty"`
+}
+
 // CopyTradeResponse представляет ответ после копирования сделки
 type CopyTradeResponse struct {
-	CopiedCount  int           `json:"copied_count"`
-	CopiedTrades []CopiedTrade `json:"copied_trades"`
-}
\ No newline at end of file
+	TradeID      int64          `json:"trade_id"`
+	CopiedCount  int            `json:"copied_count"`
+	SkippedCount int            `json:"skipped_count"`
+	FailedCount  int            `json:"failed_count"`
+	Results      []CopyOutcome  `json:"results"`
+	CopiedTrades []*CopiedTrade `json:"copied_trades"`
+}
+
+func (r *CopyTradeResponse) add(outcome CopyOutcome) {
+	switch outcome.Status {
+	case CopyOutcomeCopied:
+		r.CopiedCount++
+	case CopyOutcomeSkipped:
+		r.SkippedCount++
+	case CopyOutcomeFailed:
+		r.FailedCount++
+	}
+	r.Results = append(r.Results, outcome)
+}
+
+// CloseTradeResponse представляет ответ после закрытия сделки вместе со скопированными позициями
+type CloseTradeResponse struct {
+	Trade        *Trade         `json:"trade"`
+	ClosedCount  int            `json:"closed_count"`
+	CopiedTrades []*CopiedTrade `json:"copied_trades"`
+}
+
+// CopyFanOut представляет запись outbox автоматического копирования сделки
+type CopyFanOut struct {
+	ID           int64        `json:"id" db:"id"`
+	TradeID      int64        `json:"trade_id" db:"trade_id"`
+	Status       FanOutStatus `json:"status" db:"status"`
+	Attempts     int          `json:"attempts" db:"attempts"`
+	CopiedCount  int          `json:"copied_count" db:"copied_count"`
+	SkippedCount int          `json:"skipped_count" db:"skipped_count"`
+	FailedCount  int          `json:"failed_count" db:"failed_count"`
+	LastError    *string      `json:"last_error,omitempty" db:"last_error"`
+	AvailableAt  time.Time    `json:"available_at" db:"available_at"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+	ProcessedAt  *time.Time   `json:"processed_at,omitempty" db:"processed_at"`
+}
+
+// CopyFanOutStatusResponse представляет прогресс автоматического копирования сделки
+type CopyFanOutStatusResponse struct {
+	CopyFanOut
+	TargetSubscriptions int64 `json:"target_subscriptions" db:"target_subscriptions"`
+	CopiedTradesTotal   int64 `json:"copied_trades_total" db:"copied_trades_total"`
+}
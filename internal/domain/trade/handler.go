@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -37,6 +38,10 @@ func (h *Handler) Create(c *gin.Context) {
 
 	trade, err := h.useCase.Create(c.Request.Context(), &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to create trade", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -79,7 +84,7 @@ func (h *Handler) List(c *gin.Context) {
 
 // CopyTrade godoc
 // @Summary      Копировать сделку
-// @Description  Копирует сделку на указанные подписки
+// @Description  Идемпотентно копирует сделку на указанные подписки и возвращает результат по каждой подписке
 // @Tags         trades
 // @Accept       json
 // @Produce      json
@@ -87,6 +92,8 @@ func (h *Handler) List(c *gin.Context) {
 // @Param        request body CopyTradeRequest false "ID подписок для копирования"
 // @Success      201 {object} CopyTradeResponse
 // @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /trades/{id}/copy [post]
 func (h *Handler) CopyTrade(c *gin.Context) {
@@ -102,17 +109,92 @@ func (h *Handler) CopyTrade(c *gin.Context) {
 		req = CopyTradeRequest{}
 	}
 
-	copiedTrades, err := h.useCase.CopyTrade(c.Request.Context(), id, &req)
+	result, err := h.useCase.CopyTrade(c.Request.Context(), id, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to copy trade", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"copied_count":  len(copiedTrades),
-		"copied_trades": copiedTrades,
-	})
+	c.JSON(http.StatusCreated, result)
+}
+
+// CloseTrade godoc
+// @Summary      Закрыть сделку
+// @Description  Закрывает сделку мастера, рассчитывает прибыль и закрывает все открытые скопированные сделки
+// @Tags         trades
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID сделки"
+// @Param        request body CloseTradeRequest true "Цена и время закрытия"
+// @Success      200 {object} CloseTradeResponse
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /trades/{id}/close [post]
+func (h *Handler) CloseTrade(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid trade id"})
+		return
+	}
+
+	var req CloseTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.useCase.CloseTrade(c.Request.Context(), id, &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to close trade", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCopyStatus godoc
+// @Summary      Статус автоматического копирования
+// @Description  Возвращает прогресс автоматического копирования сделки на подписки
+// @Tags         trades
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "ID сделки"
+// @Success      200 {object} CopyFanOutStatusResponse
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /trades/{id}/copy-status [get]
+func (h *Handler) GetCopyStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid trade id"})
+		return
+	}
+
+	status, err := h.useCase.GetFanOutStatus(c.Request.Context(), id)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to get trade copy status", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
 }
 
 // ListCopiedTrades godoc
@@ -0,0 +1,26 @@
+package trade
+
+import (
+	"context"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/subscription"
+)
+
+// archiveHook закрывает открытые скопированные сделки, когда подписка уходит в архив
+type archiveHook struct {
+	copiedTradeRepo CopiedTradeRepository
+}
+
+func NewArchiveHook(copiedTradeRepo CopiedTradeRepository) subscription.StatusHook {
+	return &archiveHook{copiedTradeRepo: copiedTradeRepo}
+}
+
+func (h *archiveHook) OnStatusChange(ctx context.Context, sub *subscription.Subscription, from common.SubscriptionStatus) error {
+	if sub.Status != common.SubscriptionStatusArchived || from == common.SubscriptionStatusArchived {
+		return nil
+	}
+	_, err := h.copiedTradeRepo.CloseBySubscriptionID(ctx, sub.ID, time.Now())
+	return err
+}
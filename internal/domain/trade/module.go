@@ -8,7 +8,14 @@ var Module = fx.Options(
 	fx.Provide(
 		NewRepository,
 		NewCopiedTradeRepository,
+		NewOutboxRepository,
 		NewUseCase,
 		NewHandler,
+		NewFanOutWorker,
+		fx.Annotate(
+			NewArchiveHook,
+			fx.ResultTags(`group:"subscription_status_hooks"`),
+		),
 	),
+	fx.Invoke(func(*FanOutWorker) {}),
 )
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -20,16 +21,35 @@ type Repository interface {
 	GetByStrategyID(ctx context.Context, strategyID int64, filter *TradeFilter) ([]*Trade, error)
 	UpdateProfit(ctx context.Context, id int64, profit float64) error
 	CloseTrade(ctx context.Context, id int64, closePrice float64, closeTime time.Time) error
+	CloseWithCopiedTrades(ctx context.Context, id int64, closePrice float64, closeTime time.Time, profit float64) (*Trade, []*CopiedTrade, error)
 }
 
 type CopiedTradeRepository interface {
 	Create(ctx context.Context, req *CreateCopiedTradeRequest) (*CopiedTrade, error)
+	CreateBatch(ctx context.Context, reqs []*CreateCopiedTradeRequest) ([]CopyInsertResult, error)
 	GetByID(ctx context.Context, id int64) (*CopiedTrade, error)
 	List(ctx context.Context, filter *CopiedTradeFilter) (*common.PaginatedResult[CopiedTrade], error)
 	GetBySubscriptionID(ctx context.Context, subscriptionID int64) ([]*CopiedTrade, error)
 	GetByTradeID(ctx context.Context, tradeID int64) ([]*CopiedTrade, error)
 	UpdateProfit(ctx context.Context, id int64, profit float64) error
 	CloseTrade(ctx context.Context, id int64, closeTime time.Time) error
+	CloseBySubscriptionID(ctx context.Context, subscriptionID int64, closeTime time.Time) (int64, error)
+	CountOpenBySubscriptionID(ctx context.Context, subscriptionID int64) (int, error)
+}
+
+type OutboxRepository interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*CopyFanOut, error)
+	MarkDone(ctx context.Context, id int64, resp *CopyTradeResponse) error
+	MarkRetry(ctx context.Context, id int64, resp *CopyTradeResponse, lastError string, retryAt time.Time) error
+	MarkFailed(ctx context.Context, id int64, resp *CopyTradeResponse, lastError string) error
+	GetStatusByTradeID(ctx context.Context, tradeID int64) (*CopyFanOutStatusResponse, error)
+}
+
+// CopyInsertResult описывает результат вставки одной копии в пакетной операции
+type CopyInsertResult struct {
+	CopiedTrade *CopiedTrade
+	Conflict    bool
+	Err         error
 }
 
 type repository struct {
@@ -42,6 +62,12 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 }
 
 func (r *repository) Create(ctx context.Context, req *CreateTradeRequest) (*Trade, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO trades (strategy_id, master_account_id, symbol, volume_lots, direction, open_time, open_price)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -49,7 +75,7 @@ func (r *repository) Create(ctx context.Context, req *CreateTradeRequest) (*Trad
 	`
 
 	var trade Trade
-	err := r.db.QueryRowxContext(ctx, query,
+	err = tx.QueryRowxContext(ctx, query,
 		req.StrategyID,
 		req.MasterAccountID,
 		req.Symbol,
@@ -66,6 +92,29 @@ func (r *repository) Create(ctx context.Context, req *CreateTradeRequest) (*Trad
 		return nil, fmt.Errorf("create trade: %w", err)
 	}
 
+	enqueueQuery := `
+		INSERT INTO trade_copy_outbox (trade_id)
+		SELECT $1
+		FROM strategies
+		WHERE id = $2 AND status = 'active' AND auto_copy
+	`
+
+	result, err := tx.ExecContext(ctx, enqueueQuery, trade.ID, trade.StrategyID)
+	if err != nil {
+		r.logger.Error("Failed to enqueue trade fan-out",
+			zap.Int64("trade_id", trade.ID),
+			zap.Error(err))
+		return nil, fmt.Errorf("enqueue trade fan-out: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	if enqueued, _ := result.RowsAffected(); enqueued > 0 {
+		r.logger.Info("Trade fan-out enqueued", zap.Int64("trade_id", trade.ID))
+	}
+
 	r.logger.Info("Trade created",
 		zap.Int64("id", trade.ID),
 		zap.Int64("strategy_id", trade.StrategyID),
@@ -82,7 +131,7 @@ func (r *repository) GetByID(ctx context.Context, id int64) (*Trade, error) {
 	`
 
 	var trade Trade
-	err := r.db.GetContext(ctx, &trade, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &trade, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -130,7 +179,7 @@ func (r *repository) List(ctx context.Context, filter *TradeFilter) (*common.Pag
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM trades %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count trades", zap.Error(err))
 		return nil, fmt.Errorf("count trades: %w", err)
@@ -147,7 +196,7 @@ func (r *repository) List(ctx context.Context, filter *TradeFilter) (*common.Pag
 	args = append(args, filter.Limit, filter.Offset)
 
 	var trades []Trade
-	err = r.db.SelectContext(ctx, &trades, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &trades, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list trades", zap.Error(err))
 		return nil, fmt.Errorf("list trades: %w", err)
@@ -197,7 +246,7 @@ func (r *repository) GetByStrategyID(ctx context.Context, strategyID int64, filt
 	`, whereClause)
 
 	var trades []*Trade
-	err := r.db.SelectContext(ctx, &trades, query, args...)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &trades, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to get trades by strategy ID",
 			zap.Int64("strategy_id", strategyID),
@@ -211,7 +260,7 @@ func (r *repository) GetByStrategyID(ctx context.Context, strategyID int64, filt
 func (r *repository) UpdateProfit(ctx context.Context, id int64, profit float64) error {
 	query := `UPDATE trades SET profit = $1 WHERE id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, profit, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, profit, id)
 	if err != nil {
 		r.logger.Error("Failed to update trade profit",
 			zap.Int64("id", id),
@@ -235,7 +284,7 @@ func (r *repository) UpdateProfit(ctx context.Context, id int64, profit float64)
 func (r *repository) CloseTrade(ctx context.Context, id int64, closePrice float64, closeTime time.Time) error {
 	query := `UPDATE trades SET close_price = $1, close_time = $2 WHERE id = $3`
 
-	result, err := r.db.ExecContext(ctx, query, closePrice, closeTime, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, closePrice, closeTime, id)
 	if err != nil {
 		r.logger.Error("Failed to close trade",
 			zap.Int64("id", id),
@@ -256,6 +305,61 @@ func (r *repository) CloseTrade(ctx context.Context, id int64, closePrice float6
 	return nil
 }
 
+func (r *repository) CloseWithCopiedTrades(ctx context.Context, id int64, closePrice float64, closeTime time.Time, profit float64) (*Trade, []*CopiedTrade, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tradeQuery := `
+		UPDATE trades
+		SET close_price = $1, close_time = $2, profit = $3
+		WHERE id = $4 AND close_time IS NULL
+		RETURNING id, strategy_id, master_account_id, symbol, volume_lots, direction, open_time, close_time, open_price, close_price, profit, commission, swap, created_at
+	`
+
+	var trade Trade
+	err = tx.QueryRowxContext(ctx, tradeQuery, closePrice, closeTime, profit, id).StructScan(&trade)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrTradeAlreadyClosed
+		}
+		r.logger.Error("Failed to close trade",
+			zap.Int64("id", id),
+			zap.Float64("close_price", closePrice),
+			zap.Error(err))
+		return nil, nil, fmt.Errorf("close trade: %w", err)
+	}
+
+	copiedQuery := `
+		UPDATE copied_trades
+		SET close_time = $1, profit = ROUND(($2 * volume_lots / $3)::NUMERIC, 2)
+		WHERE trade_id = $4 AND close_time IS NULL
+		RETURNING id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
+	`
+
+	var copiedTrades []*CopiedTrade
+	err = tx.SelectContext(ctx, &copiedTrades, copiedQuery, closeTime, profit, trade.VolumeLots, id)
+	if err != nil {
+		r.logger.Error("Failed to close copied trades",
+			zap.Int64("trade_id", id),
+			zap.Error(err))
+		return nil, nil, fmt.Errorf("close copied trades: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	r.logger.Info("Trade closed with copied trades",
+		zap.Int64("id", id),
+		zap.Float64("profit", profit),
+		zap.Int("copied_closed", len(copiedTrades)))
+
+	return &trade, copiedTrades, nil
+}
+
 type copiedTradeRepository struct {
 	db     *sqlx.DB
 	logger *zap.Logger
@@ -267,17 +371,19 @@ func NewCopiedTradeRepository(db *sqlx.DB, logger *zap.Logger) CopiedTradeReposi
 
 func (r *copiedTradeRepository) Create(ctx context.Context, req *CreateCopiedTradeRequest) (*CopiedTrade, error) {
 	query := `
-		INSERT INTO copied_trades (trade_id, subscription_id, investor_account_id, volume_lots, open_time)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, trade_id, subscription_id, investor_account_id, volume_lots, profit, commission, swap, open_time, close_time, created_at
+		INSERT INTO copied_trades (trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, open_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
 	`
 
 	var copiedTrade CopiedTrade
-	err := r.db.QueryRowxContext(ctx, query,
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query,
 		req.TradeID,
 		req.SubscriptionID,
 		req.InvestorAccountID,
 		req.VolumeLots,
+		req.SizingMode,
+		req.SizingRatio,
 		req.OpenTime,
 	).StructScan(&copiedTrade)
 	if err != nil {
@@ -295,15 +401,80 @@ func (r *copiedTradeRepository) Create(ctx context.Context, req *CreateCopiedTra
 	return &copiedTrade, nil
 }
 
+// CreateBatch вставляет копии в одной транзакции; ошибка одной строки откатывается до savepoint и не затрагивает остальные
+func (r *copiedTradeRepository) CreateBatch(ctx context.Context, reqs []*CreateCopiedTradeRequest) ([]CopyInsertResult, error) {
+	results := make([]CopyInsertResult, len(reqs))
+	if len(reqs) == 0 {
+		return results, nil
+	}
+
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO copied_trades (trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, open_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (trade_id, subscription_id) DO NOTHING
+		RETURNING id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
+	`
+
+	for i, req := range reqs {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT copied_trade"); err != nil {
+			return nil, fmt.Errorf("create savepoint: %w", err)
+		}
+
+		var copiedTrade CopiedTrade
+		err := tx.QueryRowxContext(ctx, query,
+			req.TradeID,
+			req.SubscriptionID,
+			req.InvestorAccountID,
+			req.VolumeLots,
+			req.SizingMode,
+			req.SizingRatio,
+			req.OpenTime,
+		).StructScan(&copiedTrade)
+
+		switch {
+		case err == sql.ErrNoRows:
+			results[i].Conflict = true
+		case err != nil:
+			r.logger.Error("Failed to create copied trade",
+				zap.Int64("trade_id", req.TradeID),
+				zap.Int64("subscription_id", req.SubscriptionID),
+				zap.Error(err))
+			results[i].Err = fmt.Errorf("create copied trade: %w", err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT copied_trade"); err != nil {
+				return nil, fmt.Errorf("rollback to savepoint: %w", err)
+			}
+			continue
+		default:
+			results[i].CopiedTrade = &copiedTrade
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT copied_trade"); err != nil {
+			return nil, fmt.Errorf("release savepoint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return results, nil
+}
+
 func (r *copiedTradeRepository) GetByID(ctx context.Context, id int64) (*CopiedTrade, error) {
 	query := `
-		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, profit, commission, swap, open_time, close_time, created_at
+		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
 		FROM copied_trades
 		WHERE id = $1
 	`
 
 	var copiedTrade CopiedTrade
-	err := r.db.GetContext(ctx, &copiedTrade, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &copiedTrade, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -345,14 +516,14 @@ func (r *copiedTradeRepository) List(ctx context.Context, filter *CopiedTradeFil
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM copied_trades %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count copied trades", zap.Error(err))
 		return nil, fmt.Errorf("count copied trades: %w", err)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, profit, commission, swap, open_time, close_time, created_at
+		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
 		FROM copied_trades
 		%s
 		ORDER BY open_time DESC
@@ -362,7 +533,7 @@ func (r *copiedTradeRepository) List(ctx context.Context, filter *CopiedTradeFil
 	args = append(args, filter.Limit, filter.Offset)
 
 	var copiedTrades []CopiedTrade
-	err = r.db.SelectContext(ctx, &copiedTrades, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &copiedTrades, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list copied trades", zap.Error(err))
 		return nil, fmt.Errorf("list copied trades: %w", err)
@@ -379,14 +550,14 @@ func (r *copiedTradeRepository) List(ctx context.Context, filter *CopiedTradeFil
 
 func (r *copiedTradeRepository) GetBySubscriptionID(ctx context.Context, subscriptionID int64) ([]*CopiedTrade, error) {
 	query := `
-		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, profit, commission, swap, open_time, close_time, created_at
+		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
 		FROM copied_trades
 		WHERE subscription_id = $1
 		ORDER BY open_time DESC
 	`
 
 	var copiedTrades []*CopiedTrade
-	err := r.db.SelectContext(ctx, &copiedTrades, query, subscriptionID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &copiedTrades, query, subscriptionID)
 	if err != nil {
 		r.logger.Error("Failed to get copied trades by subscription ID",
 			zap.Int64("subscription_id", subscriptionID),
@@ -399,14 +570,14 @@ func (r *copiedTradeRepository) GetBySubscriptionID(ctx context.Context, subscri
 
 func (r *copiedTradeRepository) GetByTradeID(ctx context.Context, tradeID int64) ([]*CopiedTrade, error) {
 	query := `
-		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, profit, commission, swap, open_time, close_time, created_at
+		SELECT id, trade_id, subscription_id, investor_account_id, volume_lots, sizing_mode, sizing_ratio, profit, commission, swap, open_time, close_time, created_at
 		FROM copied_trades
 		WHERE trade_id = $1
 		ORDER BY created_at DESC
 	`
 
 	var copiedTrades []*CopiedTrade
-	err := r.db.SelectContext(ctx, &copiedTrades, query, tradeID)
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &copiedTrades, query, tradeID)
 	if err != nil {
 		r.logger.Error("Failed to get copied trades by trade ID",
 			zap.Int64("trade_id", tradeID),
@@ -420,7 +591,7 @@ func (r *copiedTradeRepository) GetByTradeID(ctx context.Context, tradeID int64)
 func (r *copiedTradeRepository) UpdateProfit(ctx context.Context, id int64, profit float64) error {
 	query := `UPDATE copied_trades SET profit = $1 WHERE id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, profit, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, profit, id)
 	if err != nil {
 		r.logger.Error("Failed to update copied trade profit",
 			zap.Int64("id", id),
@@ -444,7 +615,7 @@ func (r *copiedTradeRepository) UpdateProfit(ctx context.Context, id int64, prof
 func (r *copiedTradeRepository) CloseTrade(ctx context.Context, id int64, closeTime time.Time) error {
 	query := `UPDATE copied_trades SET close_time = $1 WHERE id = $2`
 
-	result, err := r.db.ExecContext(ctx, query, closeTime, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, closeTime, id)
 	if err != nil {
 		r.logger.Error("Failed to close copied trade",
 			zap.Int64("id", id),
@@ -461,3 +632,195 @@ func (r *copiedTradeRepository) CloseTrade(ctx context.Context, id int64, closeT
 
 	return nil
 }
+
+// CloseBySubscriptionID закрывает открытые копии подписки, отвязывая их от сделки мастера.
+// Прибыль фиксируется так же, как в CloseWithCopiedTrades: прибыль сделки мастера
+// пропорционально объёму копии. Котировок в системе нет, поэтому копия ещё открытой
+// сделки мастера закрывается по цене открытия с нулевой прибылью.
+func (r *copiedTradeRepository) CloseBySubscriptionID(ctx context.Context, subscriptionID int64, closeTime time.Time) (int64, error) {
+	query := `
+		UPDATE copied_trades ct
+		SET close_time = $1,
+		    profit = ROUND((COALESCE(t.profit, 0) * ct.volume_lots / t.volume_lots)::NUMERIC, 2)
+		FROM trades t
+		WHERE t.id = ct.trade_id
+		  AND ct.subscription_id = $2
+		  AND ct.close_time IS NULL
+	`
+
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, closeTime, subscriptionID)
+	if err != nil {
+		r.logger.Error("Failed to close subscription copied trades",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return 0, fmt.Errorf("close subscription copied trades: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	r.logger.Info("Subscription copied trades closed",
+		zap.Int64("subscription_id", subscriptionID),
+		zap.Int64("count", rowsAffected))
+
+	return rowsAffected, nil
+}
+
+func (r *copiedTradeRepository) CountOpenBySubscriptionID(ctx context.Context, subscriptionID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM copied_trades WHERE subscription_id = $1 AND close_time IS NULL`
+
+	var count int
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &count, query, subscriptionID)
+	if err != nil {
+		r.logger.Error("Failed to count open copied trades",
+			zap.Int64("subscription_id", subscriptionID),
+			zap.Error(err))
+		return 0, fmt.Errorf("count open copied trades: %w", err)
+	}
+
+	return count, nil
+}
+
+type outboxRepository struct {
+	db     *sqlx.DB
+	logger *zap.Logger
+}
+
+func NewOutboxRepository(db *sqlx.DB, logger *zap.Logger) OutboxRepository {
+	return &outboxRepository{db: db, logger: logger}
+}
+
+// Claim забирает готовые к обработке записи, включая зависшие в processing дольше lease
+func (r *outboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*CopyFanOut, error) {
+	query := `
+		UPDATE trade_copy_outbox
+		SET status = 'processing', attempts = attempts + 1, updated_at = now()
+		WHERE id IN (
+			SELECT id
+			FROM trade_copy_outbox
+			WHERE (status = 'pending' AND available_at <= now())
+			   OR (status = 'processing' AND updated_at < now() - make_interval(secs => $2))
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, trade_id, status, attempts, copied_count, skipped_count, failed_count, last_error, available_at, created_at, updated_at, processed_at
+	`
+
+	var items []*CopyFanOut
+	err := dbtx.Conn(ctx, r.db).SelectContext(ctx, &items, query, limit, lease.Seconds())
+	if err != nil {
+		r.logger.Error("Failed to claim trade fan-outs", zap.Error(err))
+		return nil, fmt.Errorf("claim trade fan-outs: %w", err)
+	}
+
+	return items, nil
+}
+
+// fanOutCounts сводит итог всех попыток по результату последней: подписки, скопированные
+// в прошлых попытках, в повторной пропускаются как already_copied и считаются
+// скопированными, пропуски и ошибки берутся из последней попытки. Без результата
+// (попытка прервалась до копирования) возвращает NULL — счётчики не меняются.
+func fanOutCounts(resp *CopyTradeResponse) []interface{} {
+	if resp == nil {
+		return []interface{}{nil, nil, nil}
+	}
+
+	copied, skipped := resp.CopiedCount, resp.SkippedCount
+	for _, outcome := range resp.Results {
+		if outcome.Status == CopyOutcomeSkipped && outcome.Reason == CopySkipReasonAlreadyCopied {
+			copied++
+			skipped--
+		}
+	}
+	return []interface{}{copied, skipped, resp.FailedCount}
+}
+
+func (r *outboxRepository) MarkDone(ctx context.Context, id int64, resp *CopyTradeResponse) error {
+	query := `
+		UPDATE trade_copy_outbox
+		SET status = 'done', copied_count = $1, skipped_count = $2, failed_count = $3,
+		    last_error = NULL, processed_at = now(), updated_at = now()
+		WHERE id = $4
+	`
+
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, append(fanOutCounts(resp), id)...)
+	if err != nil {
+		r.logger.Error("Failed to mark trade fan-out done",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("mark trade fan-out done: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id int64, resp *CopyTradeResponse, lastError string, retryAt time.Time) error {
+	query := `
+		UPDATE trade_copy_outbox
+		SET status = 'pending', copied_count = COALESCE($1::INT, copied_count),
+		    skipped_count = COALESCE($2::INT, skipped_count), failed_count = COALESCE($3::INT, failed_count),
+		    last_error = $4, available_at = $5, updated_at = now()
+		WHERE id = $6
+	`
+
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, append(fanOutCounts(resp), lastError, retryAt, id)...)
+	if err != nil {
+		r.logger.Error("Failed to reschedule trade fan-out",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("reschedule trade fan-out: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, resp *CopyTradeResponse, lastError string) error {
+	query := `
+		UPDATE trade_copy_outbox
+		SET status = 'failed', copied_count = COALESCE($1::INT, copied_count),
+		    skipped_count = COALESCE($2::INT, skipped_count), failed_count = COALESCE($3::INT, failed_count),
+		    last_error = $4, processed_at = now(), updated_at = now()
+		WHERE id = $5
+	`
+
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, append(fanOutCounts(resp), lastError, id)...)
+	if err != nil {
+		r.logger.Error("Failed to mark trade fan-out failed",
+			zap.Int64("id", id),
+			zap.Error(err))
+		return fmt.Errorf("mark trade fan-out failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *outboxRepository) GetStatusByTradeID(ctx context.Context, tradeID int64) (*CopyFanOutStatusResponse, error) {
+	query := `
+		SELECT
+			ob.id, ob.trade_id, ob.status, ob.attempts, ob.copied_count, ob.skipped_count, ob.failed_count,
+			ob.last_error, ob.available_at, ob.created_at, ob.updated_at, ob.processed_at,
+			(
+				SELECT COUNT(*)
+				FROM subscriptions s
+				JOIN offers o ON o.id = s.offer_id
+				WHERE o.strategy_id = t.strategy_id AND s.status = 'active'
+			) AS target_subscriptions,
+			(SELECT COUNT(*) FROM copied_trades ct WHERE ct.trade_id = ob.trade_id) AS copied_trades_total
+		FROM trade_copy_outbox ob
+		JOIN trades t ON t.id = ob.trade_id
+		WHERE ob.trade_id = $1
+	`
+
+	var status CopyFanOutStatusResponse
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &status, query, tradeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get trade fan-out status",
+			zap.Int64("trade_id", tradeID),
+			zap.Error(err))
+		return nil, fmt.Errorf("get trade fan-out status: %w", err)
+	}
+
+	return &status, nil
+}
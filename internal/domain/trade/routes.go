@@ -10,6 +10,8 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 		trades.POST("", h.Create)
 		trades.GET("", h.List)
 		trades.POST("/:id/copy", h.CopyTrade)
+		trades.POST("/:id/close", h.CloseTrade)
+		trades.GET("/:id/copy-status", h.GetCopyStatus)
 	}
 
 	rg.GET("/copied-trades", h.ListCopiedTrades)
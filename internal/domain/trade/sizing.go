@@ -0,0 +1,63 @@
+package trade
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/subscription"
+)
+
+const lotStep = 0.01
+
+type copySizing struct {
+	Mode       common.SizingMode
+	Ratio      float64
+	VolumeLots float64
+}
+
+// calculateCopyVolume пересчитывает объём сделки мастера по настройкам подписки
+func calculateCopyVolume(masterVolume float64, settings subscription.SizingSettings, masterEquity, investorEquity float64) (*copySizing, error) {
+	multiplier := settings.VolumeMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	mode := settings.SizingMode
+	var ratio float64
+	switch mode {
+	case common.SizingModeFixedLot:
+		if settings.FixedLot == nil {
+			return nil, fmt.Errorf("fixed lot is not configured")
+		}
+		ratio = *settings.FixedLot / masterVolume
+	case common.SizingModeEquityRatio:
+		if masterEquity <= 0 {
+			return nil, fmt.Errorf("master account equity is not set")
+		}
+		ratio = investorEquity / masterEquity * multiplier
+	default:
+		mode = common.SizingModeMultiplier
+		ratio = multiplier
+	}
+
+	volume := masterVolume * ratio
+	if settings.MinLot != nil && volume < *settings.MinLot {
+		volume = *settings.MinLot
+	}
+	if settings.MaxLot != nil && volume > *settings.MaxLot {
+		volume = *settings.MaxLot
+	}
+
+	volume = math.Floor(volume/lotStep+1e-9) * lotStep
+	volume = math.Round(volume*100) / 100
+	if volume < lotStep {
+		return nil, fmt.Errorf("computed volume %.4f is below minimal lot", masterVolume*ratio)
+	}
+
+	return &copySizing{
+		Mode:       mode,
+		Ratio:      math.Round(ratio*1e8) / 1e8,
+		VolumeLots: volume,
+	}, nil
+}
@@ -3,10 +3,13 @@ package trade
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/domain/ledger"
+	"github.com/finlleyl/cp_database/internal/domain/strategy"
 	"github.com/finlleyl/cp_database/internal/domain/subscription"
 	"go.uber.org/zap"
 )
@@ -15,30 +18,38 @@ type UseCase interface {
 	Create(ctx context.Context, req *CreateTradeRequest) (*Trade, error)
 	GetByID(ctx context.Context, id int64) (*Trade, error)
 	List(ctx context.Context, filter *TradeFilter) (*common.PaginatedResult[Trade], error)
-	CopyTrade(ctx context.Context, tradeID int64, req *CopyTradeRequest) ([]*CopiedTrade, error)
+	CopyTrade(ctx context.Context, tradeID int64, req *CopyTradeRequest) (*CopyTradeResponse, error)
+	CloseTrade(ctx context.Context, id int64, req *CloseTradeRequest) (*CloseTradeResponse, error)
+	GetFanOutStatus(ctx context.Context, tradeID int64) (*CopyFanOutStatusResponse, error)
 	ListCopiedTrades(ctx context.Context, filter *CopiedTradeFilter) (*common.PaginatedResult[CopiedTrade], error)
 }
 
 type useCase struct {
 	repo             Repository
 	copiedTradeRepo  CopiedTradeRepository
+	outboxRepo       OutboxRepository
+	strategyRepo     strategy.Repository
 	subscriptionRepo subscription.Repository
-	auditRepo        audit.Repository
+	ledgerRepo       ledger.Repository
 	logger           *zap.Logger
 }
 
 func NewUseCase(
 	repo Repository,
 	copiedTradeRepo CopiedTradeRepository,
+	outboxRepo OutboxRepository,
+	strategyRepo strategy.Repository,
 	subscriptionRepo subscription.Repository,
-	auditRepo audit.Repository,
+	ledgerRepo ledger.Repository,
 	logger *zap.Logger,
 ) UseCase {
 	return &useCase{
 		repo:             repo,
 		copiedTradeRepo:  copiedTradeRepo,
+		outboxRepo:       outboxRepo,
+		strategyRepo:     strategyRepo,
 		subscriptionRepo: subscriptionRepo,
-		auditRepo:        auditRepo,
+		ledgerRepo:       ledgerRepo,
 		logger:           logger,
 	}
 }
@@ -50,18 +61,19 @@ func (u *useCase) Create(ctx context.Context, req *CreateTradeRequest) (*Trade,
 		zap.String("direction", string(req.Direction)),
 		zap.Float64("volume_lots", req.VolumeLots))
 
+	masterStrategy, err := u.authorizeMaster(ctx, req.StrategyID)
+	if err != nil {
+		return nil, err
+	}
+	if masterStrategy.MasterAccountID != req.MasterAccountID {
+		return nil, ErrMasterAccountMismatch
+	}
+
 	trade, err := u.repo.Create(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("create trade: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeTrade,
-		EntityID:   trade.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   trade,
-	})
-
 	return trade, nil
 }
 
@@ -76,7 +88,7 @@ func (u *useCase) List(ctx context.Context, filter *TradeFilter) (*common.Pagina
 	return u.repo.List(ctx, filter)
 }
 
-func (u *useCase) CopyTrade(ctx context.Context, tradeID int64, req *CopyTradeRequest) ([]*CopiedTrade, error) {
+func (u *useCase) CopyTrade(ctx context.Context, tradeID int64, req *CopyTradeRequest) (*CopyTradeResponse, error) {
 	u.logger.Info("UseCase: Copying trade",
 		zap.Int64("trade_id", tradeID),
 		zap.Any("subscription_ids", req.SubscriptionIDs))
@@ -86,56 +98,245 @@ func (u *useCase) CopyTrade(ctx context.Context, tradeID int64, req *CopyTradeRe
 		return nil, fmt.Errorf("get trade: %w", err)
 	}
 	if trade == nil {
-		return nil, fmt.Errorf("trade not found")
+		return nil, ErrTradeNotFound
+	}
+	if _, err := u.authorizeMaster(ctx, trade.StrategyID); err != nil {
+		return nil, err
+	}
+	if trade.CloseTime != nil {
+		return nil, ErrTradeAlreadyClosed
+	}
+
+	response := &CopyTradeResponse{
+		TradeID:      trade.ID,
+		Results:      []CopyOutcome{},
+		CopiedTrades: []*CopiedTrade{},
+	}
+
+	active, err := u.subscriptionRepo.GetActiveByStrategyID(ctx, trade.StrategyID)
+	if err != nil {
+		return nil, fmt.Errorf("get active subscriptions: %w", err)
 	}
 
 	var subscriptions []*subscription.Subscription
 	if len(req.SubscriptionIDs) > 0 {
+		activeByID := make(map[int64]*subscription.Subscription, len(active))
+		for _, sub := range active {
+			activeByID[sub.ID] = sub
+		}
 
+		seen := make(map[int64]bool, len(req.SubscriptionIDs))
 		for _, subID := range req.SubscriptionIDs {
-			sub, err := u.subscriptionRepo.GetByID(ctx, subID)
-			if err != nil {
-				u.logger.Warn("Failed to get subscription", zap.Error(err))
+			if seen[subID] {
 				continue
 			}
-			if sub != nil && sub.Status == common.SubscriptionStatusActive {
+			seen[subID] = true
+
+			if sub, ok := activeByID[subID]; ok {
 				subscriptions = append(subscriptions, sub)
+				continue
+			}
+
+			sub, err := u.subscriptionRepo.GetByID(ctx, subID)
+			switch {
+			case err != nil:
+				response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeFailed, Error: err.Error()})
+			case sub != nil && sub.Status != common.SubscriptionStatusActive:
+				response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonInactive})
+			default:
+				response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonNotFound})
 			}
 		}
 	} else {
+		subscriptions = active
+	}
 
-		subscriptions, err = u.subscriptionRepo.GetActiveByStrategyID(ctx, trade.StrategyID)
-		if err != nil {
-			return nil, fmt.Errorf("get active subscriptions: %w", err)
-		}
+	existing, err := u.copiedTradeRepo.GetByTradeID(ctx, trade.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get existing copied trades: %w", err)
+	}
+	alreadyCopied := make(map[int64]bool, len(existing))
+	for _, ct := range existing {
+		alreadyCopied[ct.SubscriptionID] = true
 	}
 
-	var copiedTrades []*CopiedTrade
+	// Текущее эквити счёта — его остаток в журнале: внесённый капитал с прибылью и комиссиями
+	masterBalance, err := u.ledgerRepo.GetBalance(ctx, trade.MasterAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("get master account balance: %w", err)
+	}
+	var masterEquity float64
+	if masterBalance != nil {
+		masterEquity = masterBalance.Balance
+	}
+
+	var copyReqs []*CreateCopiedTradeRequest
 	for _, sub := range subscriptions {
-		copyReq := &CreateCopiedTradeRequest{
-			TradeID:           trade.ID,
-			SubscriptionID:    sub.ID,
-			InvestorAccountID: sub.InvestorAccountID,
-			VolumeLots:        trade.VolumeLots,
-			OpenTime:          time.Now(),
+		if alreadyCopied[sub.ID] {
+			response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonAlreadyCopied})
+			continue
+		}
+
+		if !sub.AllowsSymbol(trade.Symbol) {
+			response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonSymbolFiltered})
+			continue
 		}
 
-		created, err := u.copiedTradeRepo.Create(ctx, copyReq)
+		if sub.MaxOpenPositions != nil {
+			openPositions, err := u.copiedTradeRepo.CountOpenBySubscriptionID(ctx, sub.ID)
+			if err != nil {
+				response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeFailed, Error: err.Error()})
+				continue
+			}
+			if openPositions >= *sub.MaxOpenPositions {
+				response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonMaxOpenPositions})
+				continue
+			}
+		}
+
+		var investorEquity float64
+		if sub.SizingMode == common.SizingModeEquityRatio || sub.StopCopyEquity != nil {
+			investorBalance, err := u.ledgerRepo.GetBalance(ctx, sub.InvestorAccountID)
+			if err != nil {
+				response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeFailed, Error: err.Error()})
+				continue
+			}
+			if investorBalance != nil {
+				investorEquity = investorBalance.Balance
+			}
+		}
+
+		if sub.StopCopyEquity != nil && investorEquity < *sub.StopCopyEquity {
+			response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonEquityFloor})
+			continue
+		}
+
+		sizing, err := calculateCopyVolume(trade.VolumeLots, sub.SizingSettings, masterEquity, investorEquity)
 		if err != nil {
-			u.logger.Error("Failed to create copied trade",
-				zap.Error(err),
-				zap.Int64("subscription_id", sub.ID))
+			response.add(CopyOutcome{SubscriptionID: sub.ID, Status: CopyOutcomeFailed, Error: err.Error()})
 			continue
 		}
 
-		copiedTrades = append(copiedTrades, created)
+		copyReqs = append(copyReqs, &CreateCopiedTradeRequest{
+			TradeID:           trade.ID,
+			SubscriptionID:    sub.ID,
+			InvestorAccountID: sub.InvestorAccountID,
+			VolumeLots:        sizing.VolumeLots,
+			SizingMode:        &sizing.Mode,
+			SizingRatio:       &sizing.Ratio,
+			OpenTime:          time.Now(),
+		})
+	}
+
+	results, err := u.copiedTradeRepo.CreateBatch(ctx, copyReqs)
+	if err != nil {
+		return nil, fmt.Errorf("create copied trades: %w", err)
+	}
+
+	for i, result := range results {
+		subID := copyReqs[i].SubscriptionID
+		switch {
+		case result.Err != nil:
+			response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeFailed, Error: result.Err.Error()})
+		case result.Conflict:
+			response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeSkipped, Reason: CopySkipReasonAlreadyCopied})
+		default:
+			response.add(CopyOutcome{SubscriptionID: subID, Status: CopyOutcomeCopied, CopiedTradeID: &result.CopiedTrade.ID})
+			response.CopiedTrades = append(response.CopiedTrades, result.CopiedTrade)
+		}
 	}
 
 	u.logger.Info("Trade copied",
 		zap.Int64("trade_id", tradeID),
-		zap.Int("copied_count", len(copiedTrades)))
+		zap.Int("copied_count", response.CopiedCount),
+		zap.Int("skipped_count", response.SkippedCount),
+		zap.Int("failed_count", response.FailedCount))
+
+	return response, nil
+}
+
+func (u *useCase) CloseTrade(ctx context.Context, id int64, req *CloseTradeRequest) (*CloseTradeResponse, error) {
+	u.logger.Info("UseCase: Closing trade",
+		zap.Int64("id", id),
+		zap.Float64("close_price", req.ClosePrice))
+
+	trade, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get trade: %w", err)
+	}
+	if trade == nil {
+		return nil, ErrTradeNotFound
+	}
+	if _, err := u.authorizeMaster(ctx, trade.StrategyID); err != nil {
+		return nil, err
+	}
+	if trade.CloseTime != nil {
+		return nil, ErrTradeAlreadyClosed
+	}
+
+	closeTime := time.Now()
+	if req.CloseTime != nil {
+		closeTime = *req.CloseTime
+	}
+	if closeTime.Before(trade.OpenTime) {
+		return nil, ErrInvalidCloseTime
+	}
+
+	profit := calculateProfit(trade.Direction, trade.VolumeLots, trade.OpenPrice, req.ClosePrice)
+
+	closed, copiedTrades, err := u.repo.CloseWithCopiedTrades(ctx, id, req.ClosePrice, closeTime, profit)
+	if err != nil {
+		return nil, fmt.Errorf("close trade: %w", err)
+	}
+
+	return &CloseTradeResponse{
+		Trade:        closed,
+		ClosedCount:  len(copiedTrades),
+		CopiedTrades: copiedTrades,
+	}, nil
+}
+
+// authorizeMaster проверяет, что операцию над сделками стратегии выполняет её мастер
+func (u *useCase) authorizeMaster(ctx context.Context, strategyID int64) (*strategy.Strategy, error) {
+	if _, err := auth.RequireRole(ctx, common.UserRoleMaster); err != nil {
+		return nil, err
+	}
+
+	s, err := u.strategyRepo.GetBaseByID(ctx, strategyID)
+	if err != nil {
+		return nil, fmt.Errorf("get strategy: %w", err)
+	}
+	if s == nil {
+		return nil, strategy.ErrStrategyNotFound
+	}
+
+	if _, err := auth.RequireUser(ctx, s.MasterUserID); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func calculateProfit(direction TradeDirection, volumeLots, openPrice, closePrice float64) float64 {
+	diff := closePrice - openPrice
+	if direction == TradeDirectionSell {
+		diff = -diff
+	}
+	return math.Round(diff*volumeLots*100) / 100
+}
+
+func (u *useCase) GetFanOutStatus(ctx context.Context, tradeID int64) (*CopyFanOutStatusResponse, error) {
+	u.logger.Info("UseCase: Getting trade fan-out status", zap.Int64("trade_id", tradeID))
+
+	status, err := u.outboxRepo.GetStatusByTradeID(ctx, tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("get fan-out status: %w", err)
+	}
+	if status == nil {
+		return nil, ErrFanOutNotFound
+	}
 
-	return copiedTrades, nil
+	return status, nil
 }
 
 func (u *useCase) ListCopiedTrades(ctx context.Context, filter *CopiedTradeFilter) (*common.PaginatedResult[CopiedTrade], error) {
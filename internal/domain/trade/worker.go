@@ -0,0 +1,93 @@
+package trade
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/domain/common"
+	"github.com/finlleyl/cp_database/internal/worker"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	fanOutPollInterval = 2 * time.Second
+	fanOutBatchSize    = 20
+	fanOutLease        = 5 * time.Minute
+	fanOutMaxAttempts  = 5
+	fanOutRetryBackoff = 30 * time.Second
+)
+
+// FanOutWorker разбирает trade_copy_outbox и копирует сделки на активные подписки
+type FanOutWorker struct {
+	useCase    UseCase
+	outboxRepo OutboxRepository
+	logger     *zap.Logger
+}
+
+func NewFanOutWorker(lc fx.Lifecycle, useCase UseCase, outboxRepo OutboxRepository, logger *zap.Logger) *FanOutWorker {
+	w := &FanOutWorker{useCase: useCase, outboxRepo: outboxRepo, logger: logger}
+	worker.Start(lc, logger, "Trade fan-out worker", fanOutPollInterval, w.processBatch)
+	return w
+}
+
+func (w *FanOutWorker) processBatch(ctx context.Context) error {
+	items, err := w.outboxRepo.Claim(ctx, fanOutBatchSize, fanOutLease)
+	if err != nil {
+		return fmt.Errorf("claim trade fan-outs: %w", err)
+	}
+
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.process(ctx, item)
+	}
+	return nil
+}
+
+func (w *FanOutWorker) process(ctx context.Context, item *CopyFanOut) {
+	resp, err := w.useCase.CopyTrade(ctx, item.TradeID, &CopyTradeRequest{})
+	if err != nil {
+		if _, ok := common.AsError(err); ok || item.Attempts >= fanOutMaxAttempts {
+			w.logger.Error("Trade fan-out failed",
+				zap.Int64("trade_id", item.TradeID),
+				zap.Int("attempts", item.Attempts),
+				zap.Error(err))
+			_ = w.outboxRepo.MarkFailed(ctx, item.ID, nil, err.Error())
+			return
+		}
+		w.retry(ctx, item, nil, err.Error())
+		return
+	}
+
+	if resp.FailedCount > 0 {
+		lastError := fmt.Sprintf("%d subscriptions failed", resp.FailedCount)
+		if item.Attempts >= fanOutMaxAttempts {
+			_ = w.outboxRepo.MarkFailed(ctx, item.ID, resp, lastError)
+			return
+		}
+		w.retry(ctx, item, resp, lastError)
+		return
+	}
+
+	if err := w.outboxRepo.MarkDone(ctx, item.ID, resp); err != nil {
+		return
+	}
+
+	w.logger.Info("Trade fan-out done",
+		zap.Int64("trade_id", item.TradeID),
+		zap.Int("copied_count", resp.CopiedCount),
+		zap.Int("skipped_count", resp.SkippedCount))
+}
+
+func (w *FanOutWorker) retry(ctx context.Context, item *CopyFanOut, resp *CopyTradeResponse, lastError string) {
+	retryAt := time.Now().Add(time.Duration(item.Attempts) * fanOutRetryBackoff)
+	w.logger.Warn("Trade fan-out will be retried",
+		zap.Int64("trade_id", item.TradeID),
+		zap.Int("attempts", item.Attempts),
+		zap.Time("retry_at", retryAt),
+		zap.String("error", lastError))
+	_ = w.outboxRepo.MarkRetry(ctx, item.ID, resp, lastError, retryAt)
+}
@@ -1,11 +1,14 @@
 package user
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/finlleyl/cp_database/internal/domain/common"
 )
 
+var ErrInvalidCredentials = common.NewError(http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
+
 type User struct {
 	ID        int64           `json:"id" db:"id"`
 	Name      string          `json:"name" db:"name"`
@@ -17,15 +20,35 @@ type User struct {
 }
 
 type CreateUserRequest struct {
-	Name  string          `json:"name" binding:"required"`
-	Email string          `json:"email" binding:"required,email"`
-	Role  common.UserRole `json:"role" binding:"required,oneof=master investor"`
+	Name     string          `json:"name" binding:"required"`
+	Email    string          `json:"email" binding:"required,email"`
+	Role     common.UserRole `json:"role" binding:"required,oneof=master investor both"`
+	Password string          `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+}
+
+type Credentials struct {
+	UserID       int64           `db:"user_id"`
+	Role         common.UserRole `db:"role"`
+	PasswordHash string          `db:"password_hash"`
+}
+
+type IssueTokenRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// TokenResponse представляет выданный токен доступа
+type TokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
 
 type UpdateUserRequest struct {
-	Name  *string          `json:"name,omitempty"`
-	Email *string          `json:"email,omitempty"`
-	Role  *common.UserRole `json:"role,omitempty"`
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+	// Role меняет только администратор; он же назначает роль admin
+	Role *common.UserRole `json:"role,omitempty" binding:"omitempty,oneof=master investor both admin"`
 }
 
 type UserFilter struct {
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -111,7 +112,7 @@ func (h *Handler) List(c *gin.Context) {
 
 // Update godoc
 // @Summary      Обновить пользователя
-// @Description  Обновляет данные пользователя по ID
+// @Description  Обновляет данные пользователя по ID. Доступно самому пользователю и администратору; роль меняет только администратор
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -119,6 +120,8 @@ func (h *Handler) List(c *gin.Context) {
 // @Param        request body UpdateUserRequest true "Данные для обновления"
 // @Success      200 {object} User
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /users/{id} [put]
 func (h *Handler) Update(c *gin.Context) {
@@ -136,6 +139,10 @@ func (h *Handler) Update(c *gin.Context) {
 
 	user, err := h.useCase.Update(c.Request.Context(), id, &req)
 	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to update user", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -146,13 +153,15 @@ func (h *Handler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary      Удалить пользователя
-// @Description  Удаляет пользователя по ID (мягкое удаление)
+// @Description  Удаляет пользователя по ID (мягкое удаление). Доступно самому пользователю и администратору
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id path int true "ID пользователя"
 // @Success      204 "No Content"
 // @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
 // @Failure      500 {object} map[string]string
 // @Router       /users/{id} [delete]
 func (h *Handler) Delete(c *gin.Context) {
@@ -163,6 +172,10 @@ func (h *Handler) Delete(c *gin.Context) {
 	}
 
 	if err := h.useCase.Delete(c.Request.Context(), id); err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
 		h.logger.Error("Failed to delete user", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -170,3 +183,36 @@ func (h *Handler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// IssueToken godoc
+// @Summary      Получить токен доступа
+// @Description  Проверяет email и пароль и выдаёт JWT для заголовка Authorization
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body IssueTokenRequest true "Учётные данные"
+// @Success      200 {object} TokenResponse
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /auth/token [post]
+func (h *Handler) IssueToken(c *gin.Context) {
+	var req IssueTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.useCase.IssueToken(c.Request.Context(), &req)
+	if err != nil {
+		if domainErr, ok := common.AsError(err); ok {
+			c.JSON(domainErr.Status, domainErr)
+			return
+		}
+		h.logger.Error("Failed to issue token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
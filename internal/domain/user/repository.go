@@ -7,17 +7,19 @@ import (
 	"math"
 	"strings"
 
+	"github.com/finlleyl/cp_database/internal/dbtx"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
-	Create(ctx context.Context, req *CreateUserRequest) (*User, error)
+	Create(ctx context.Context, req *CreateUserRequest, passwordHash string) (*User, error)
 	GetByID(ctx context.Context, id int64) (*User, error)
 	List(ctx context.Context, filter *UserFilter) (*common.PaginatedResult[User], error)
 	Update(ctx context.Context, id int64, req *UpdateUserRequest) (*User, error)
 	Delete(ctx context.Context, id int64) error
+	GetCredentialsByEmail(ctx context.Context, email string) (*Credentials, error)
 }
 
 type repository struct {
@@ -29,7 +31,13 @@ func NewRepository(db *sqlx.DB, logger *zap.Logger) Repository {
 	return &repository{db: db, logger: logger}
 }
 
-func (r *repository) Create(ctx context.Context, req *CreateUserRequest) (*User, error) {
+func (r *repository) Create(ctx context.Context, req *CreateUserRequest, passwordHash string) (*User, error) {
+	tx, err := dbtx.Begin(ctx, r.db)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO users (name, email, role)
 		VALUES ($1, $2, $3)
@@ -37,7 +45,7 @@ func (r *repository) Create(ctx context.Context, req *CreateUserRequest) (*User,
 	`
 
 	var user User
-	err := r.db.QueryRowxContext(ctx, query, req.Name, req.Email, req.Role).StructScan(&user)
+	err = tx.QueryRowxContext(ctx, query, req.Name, req.Email, req.Role).StructScan(&user)
 	if err != nil {
 		r.logger.Error("Failed to create user",
 			zap.String("email", req.Email),
@@ -45,6 +53,20 @@ func (r *repository) Create(ctx context.Context, req *CreateUserRequest) (*User,
 		return nil, fmt.Errorf("create user: %w", err)
 	}
 
+	if passwordHash != "" {
+		credentialsQuery := `INSERT INTO user_credentials (user_id, password_hash) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, credentialsQuery, user.ID, passwordHash); err != nil {
+			r.logger.Error("Failed to save user credentials",
+				zap.Int64("id", user.ID),
+				zap.Error(err))
+			return nil, fmt.Errorf("save user credentials: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
 	r.logger.Info("User created",
 		zap.Int64("id", user.ID),
 		zap.String("email", user.Email))
@@ -60,7 +82,7 @@ func (r *repository) GetByID(ctx context.Context, id int64) (*User, error) {
 	`
 
 	var user User
-	err := r.db.GetContext(ctx, &user, query, id)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &user, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -102,7 +124,7 @@ func (r *repository) List(ctx context.Context, filter *UserFilter) (*common.Pagi
 
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM users %s", whereClause)
 	var total int64
-	err := r.db.GetContext(ctx, &total, countQuery, args...)
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &total, countQuery, args...)
 	if err != nil {
 		r.logger.Error("Failed to count users", zap.Error(err))
 		return nil, fmt.Errorf("count users: %w", err)
@@ -119,7 +141,7 @@ func (r *repository) List(ctx context.Context, filter *UserFilter) (*common.Pagi
 	args = append(args, filter.Limit, filter.Offset)
 
 	var users []User
-	err = r.db.SelectContext(ctx, &users, query, args...)
+	err = dbtx.Conn(ctx, r.db).SelectContext(ctx, &users, query, args...)
 	if err != nil {
 		r.logger.Error("Failed to list users", zap.Error(err))
 		return nil, fmt.Errorf("list users: %w", err)
@@ -174,7 +196,7 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateUserReques
 	`, strings.Join(setClauses, ", "), argIndex)
 
 	var user User
-	err := r.db.QueryRowxContext(ctx, query, args...).StructScan(&user)
+	err := dbtx.Conn(ctx, r.db).QueryRowxContext(ctx, query, args...).StructScan(&user)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found: %d", id)
@@ -193,7 +215,7 @@ func (r *repository) Update(ctx context.Context, id int64, req *UpdateUserReques
 func (r *repository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, id)
 	if err != nil {
 		r.logger.Error("Failed to delete user",
 			zap.Int64("id", id),
@@ -210,3 +232,24 @@ func (r *repository) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+func (r *repository) GetCredentialsByEmail(ctx context.Context, email string) (*Credentials, error) {
+	query := `
+		SELECT u.id AS user_id, u.role, c.password_hash
+		FROM users u
+		JOIN user_credentials c ON c.user_id = u.id
+		WHERE u.email = $1
+	`
+
+	var credentials Credentials
+	err := dbtx.Conn(ctx, r.db).GetContext(ctx, &credentials, query, email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		r.logger.Error("Failed to get user credentials", zap.Error(err))
+		return nil, fmt.Errorf("get user credentials: %w", err)
+	}
+
+	return &credentials, nil
+}
@@ -13,4 +13,6 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 		users.PUT("/:id", h.Update)
 		users.DELETE("/:id", h.Delete)
 	}
+
+	rg.POST("/auth/token", h.IssueToken)
 }
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/finlleyl/cp_database/internal/domain/audit"
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/domain/common"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UseCase interface {
@@ -15,34 +16,37 @@ type UseCase interface {
 	List(ctx context.Context, filter *UserFilter) (*common.PaginatedResult[User], error)
 	Update(ctx context.Context, id int64, req *UpdateUserRequest) (*User, error)
 	Delete(ctx context.Context, id int64) error
+	IssueToken(ctx context.Context, req *IssueTokenRequest) (*TokenResponse, error)
 }
 
 type useCase struct {
-	repo      Repository
-	auditRepo audit.Repository
-	logger    *zap.Logger
+	repo         Repository
+	tokenManager *auth.TokenManager
+	logger       *zap.Logger
 }
 
-func NewUseCase(repo Repository, auditRepo audit.Repository, logger *zap.Logger) UseCase {
-	return &useCase{repo: repo, auditRepo: auditRepo, logger: logger}
+func NewUseCase(repo Repository, tokenManager *auth.TokenManager, logger *zap.Logger) UseCase {
+	return &useCase{repo: repo, tokenManager: tokenManager, logger: logger}
 }
 
 func (u *useCase) Create(ctx context.Context, req *CreateUserRequest) (*User, error) {
 
 	u.logger.Info("UseCase: Creating user", zap.String("name", req.Name))
 
-	user, err := u.repo.Create(ctx, req)
+	var passwordHash string
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("hash password: %w", err)
+		}
+		passwordHash = string(hash)
+	}
+
+	user, err := u.repo.Create(ctx, req, passwordHash)
 	if err != nil {
 		return nil, fmt.Errorf("create user: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeUser,
-		EntityID:   user.ID,
-		Action:     audit.AuditActionCreate,
-		NewValue:   user,
-	})
-
 	return user, nil
 }
 
@@ -63,9 +67,13 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateUserRequest)
 
 	u.logger.Info("UseCase: Updating user", zap.Int64("id", id))
 
-	oldUser, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return nil, fmt.Errorf("get user: %w", err)
+	if _, err := authorizeSelfOrAdmin(ctx, id); err != nil {
+		return nil, err
+	}
+	if req.Role != nil {
+		if _, err := auth.RequireRole(ctx, common.UserRoleAdmin); err != nil {
+			return nil, err
+		}
 	}
 
 	user, err := u.repo.Update(ctx, id, req)
@@ -73,14 +81,6 @@ func (u *useCase) Update(ctx context.Context, id int64, req *UpdateUserRequest)
 		return nil, fmt.Errorf("update user: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeUser,
-		EntityID:   id,
-		Action:     audit.AuditActionUpdate,
-		OldValue:   oldUser,
-		NewValue:   user,
-	})
-
 	return user, nil
 }
 
@@ -88,21 +88,48 @@ func (u *useCase) Delete(ctx context.Context, id int64) error {
 
 	u.logger.Info("UseCase: Deleting user", zap.Int64("id", id))
 
-	oldUser, err := u.repo.GetByID(ctx, id)
-	if err != nil {
-		return fmt.Errorf("get user: %w", err)
+	if _, err := authorizeSelfOrAdmin(ctx, id); err != nil {
+		return err
 	}
 
 	if err := u.repo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("delete user: %w", err)
 	}
 
-	_, _ = u.auditRepo.Create(ctx, &audit.AuditCreateRequest{
-		EntityType: audit.EntityTypeUser,
-		EntityID:   id,
-		Action:     audit.AuditActionDelete,
-		OldValue:   oldUser,
-	})
-
 	return nil
 }
+
+// authorizeSelfOrAdmin пропускает владельца учётной записи и администратора
+func authorizeSelfOrAdmin(ctx context.Context, id int64) (*auth.Actor, error) {
+	if actor, err := auth.RequireRole(ctx, common.UserRoleAdmin); err == nil {
+		return actor, nil
+	}
+	return auth.RequireUser(ctx, id)
+}
+
+func (u *useCase) IssueToken(ctx context.Context, req *IssueTokenRequest) (*TokenResponse, error) {
+	u.logger.Info("UseCase: Issuing token", zap.String("email", req.Email))
+
+	credentials, err := u.repo.GetCredentialsByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("get credentials: %w", err)
+	}
+	if credentials == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(credentials.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, expiresAt, err := u.tokenManager.Issue(credentials.UserID, credentials.Role)
+	if err != nil {
+		return nil, fmt.Errorf("issue token: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresAt:   expiresAt,
+	}, nil
+}
@@ -1,10 +1,18 @@
 package httpserver
 
 import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
@@ -65,3 +73,187 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Authentication разбирает Bearer-токен и кладёт пользователя в контекст запроса.
+// Запросы без токена пропускаются дальше, невалидный токен отклоняется сразу.
+func Authentication(tm *auth.TokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(auth.ErrUnauthorized.Status, auth.ErrUnauthorized)
+			return
+		}
+
+		claims, err := tm.Verify(strings.TrimSpace(token))
+		if err != nil {
+			c.AbortWithStatusJSON(auth.ErrUnauthorized.Status, auth.ErrUnauthorized.WithDetails(err.Error()))
+			return
+		}
+		userID, err := claims.UserID()
+		if err != nil {
+			c.AbortWithStatusJSON(auth.ErrUnauthorized.Status, auth.ErrUnauthorized.WithDetails(err.Error()))
+			return
+		}
+
+		ctx := auth.WithActor(c.Request.Context(), &auth.Actor{UserID: userID, Role: claims.Role})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequireAuthForMutations требует аутентификацию для всех изменяющих запросов,
+// кроме перечисленных публичных маршрутов (регистрация, выпуск токена).
+func RequireAuthForMutations(public ...string) gin.HandlerFunc {
+	publicRoutes := make(map[string]struct{}, len(public))
+	for _, route := range public {
+		publicRoutes[route] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			c.Next()
+			return
+		}
+		if _, ok := publicRoutes[c.Request.Method+" "+route]; ok {
+			c.Next()
+			return
+		}
+
+		if _, ok := auth.ActorFromContext(c.Request.Context()); !ok {
+			c.AbortWithStatusJSON(auth.ErrUnauthorized.Status, auth.ErrUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
+// bufferedWriter придерживает ответ, пока не станет известен исход транзакции запроса
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// RequestTransaction оборачивает каждый изменяющий запрос в транзакцию и
+// выставляет в ней app.current_user_id, app.request_ip, app.user_agent и
+// app.source, чтобы триггеры аудита знали автора и источник изменений. Транзакция фиксируется,
+// только если обработчик ответил статусом ниже 400.
+func RequestTransaction(db *sqlx.DB, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			logger.Error("Failed to begin request transaction", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		userID := ""
+		if actor, ok := auth.ActorFromContext(ctx); ok && !actor.System {
+			userID = strconv.FormatInt(actor.UserID, 10)
+		}
+		_, err = tx.ExecContext(ctx, `
+			SELECT set_config($1, $2, true),
+			       set_config($3, $4, true),
+			       set_config($5, $6, true),
+			       set_config($7, $8, true)
+		`,
+			dbtx.SettingUserID, userID,
+			dbtx.SettingRequestIP, c.ClientIP(),
+			dbtx.SettingUserAgent, c.Request.UserAgent(),
+			dbtx.SettingSource, string(audit.SourceAPI),
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			logger.Error("Failed to set request session variables", zap.Error(err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		finished := false
+		defer func() {
+			if !finished {
+				_ = tx.Rollback()
+				c.Writer = original
+			}
+		}()
+
+		txCtx := dbtx.WithTx(ctx, tx)
+		c.Request = c.Request.WithContext(txCtx)
+
+		c.Next()
+
+		finished = true
+		c.Writer = original
+
+		if writer.status >= http.StatusBadRequest || len(c.Errors) > 0 {
+			_ = tx.Rollback()
+			writer.flush()
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			logger.Error("Failed to commit request transaction", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		writer.flush()
+		dbtx.RunAfterCommit(txCtx)
+	}
+}
@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/finlleyl/cp_database/internal/auth"
 	"github.com/finlleyl/cp_database/internal/config"
 	"github.com/finlleyl/cp_database/internal/domain/account"
 	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/batchimport"
+	"github.com/finlleyl/cp_database/internal/domain/billing"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
+	"github.com/finlleyl/cp_database/internal/domain/ledger"
+	"github.com/finlleyl/cp_database/internal/domain/metrics"
 	"github.com/finlleyl/cp_database/internal/domain/offer"
 	"github.com/finlleyl/cp_database/internal/domain/statistics"
 	"github.com/finlleyl/cp_database/internal/domain/strategy"
@@ -16,11 +21,12 @@ import (
 	"github.com/finlleyl/cp_database/internal/domain/trade"
 	"github.com/finlleyl/cp_database/internal/domain/user"
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
-func NewRouter(logger *zap.Logger) *gin.Engine {
+func NewRouter(logger *zap.Logger, tokenManager *auth.TokenManager, db *sqlx.DB) *gin.Engine {
 	r := gin.New()
 
 	r.Use(
@@ -29,6 +35,12 @@ func NewRouter(logger *zap.Logger) *gin.Engine {
 		GinLogger(logger),
 		RequestID(),
 		CORSMiddleware(),
+		Authentication(tokenManager),
+		RequireAuthForMutations(
+			"POST /api/v1/auth/token",
+			"POST /api/v1/users",
+		),
+		RequestTransaction(db, logger),
 	)
 
 	return r
@@ -45,6 +57,10 @@ func RegisterAllRoutes(
 	statisticsHandler *statistics.Handler,
 	batchImportHandler *batchimport.Handler,
 	auditHandler *audit.Handler,
+	billingHandler *billing.Handler,
+	ledgerHandler *ledger.Handler,
+	currencyHandler *currency.Handler,
+	metricsHandler *metrics.Handler,
 ) {
 	params := RouteParams{
 		UserHandler:         userHandler,
@@ -56,6 +72,10 @@ func RegisterAllRoutes(
 		StatisticsHandler:   statisticsHandler,
 		BatchImportHandler:  batchImportHandler,
 		AuditHandler:        auditHandler,
+		BillingHandler:      billingHandler,
+		LedgerHandler:       ledgerHandler,
+		CurrencyHandler:     currencyHandler,
+		MetricsHandler:      metricsHandler,
 	}
 	RegisterRoutes(r, params)
 }
@@ -102,4 +122,4 @@ var Module = fx.Options(
 		RegisterAllRoutes,
 		func(*http.Server) {},
 	),
-)
\ No newline at end of file
+)
@@ -6,6 +6,10 @@ import (
 	"github.com/finlleyl/cp_database/internal/domain/account"
 	"github.com/finlleyl/cp_database/internal/domain/audit"
 	"github.com/finlleyl/cp_database/internal/domain/batchimport"
+	"github.com/finlleyl/cp_database/internal/domain/billing"
+	"github.com/finlleyl/cp_database/internal/domain/currency"
+	"github.com/finlleyl/cp_database/internal/domain/ledger"
+	"github.com/finlleyl/cp_database/internal/domain/metrics"
 	"github.com/finlleyl/cp_database/internal/domain/offer"
 	"github.com/finlleyl/cp_database/internal/domain/statistics"
 	"github.com/finlleyl/cp_database/internal/domain/strategy"
@@ -27,6 +31,10 @@ type RouteParams struct {
 	StatisticsHandler   *statistics.Handler
 	BatchImportHandler  *batchimport.Handler
 	AuditHandler        *audit.Handler
+	BillingHandler      *billing.Handler
+	LedgerHandler       *ledger.Handler
+	CurrencyHandler     *currency.Handler
+	MetricsHandler      *metrics.Handler
 }
 
 func healthRoute(c *gin.Context) {
@@ -57,5 +65,9 @@ func RegisterRoutes(r *gin.Engine, params RouteParams) {
 		statistics.RegisterRoutes(v1, params.StatisticsHandler)
 		batchimport.RegisterRoutes(v1, params.BatchImportHandler)
 		audit.RegisterRoutes(v1, params.AuditHandler)
+		billing.RegisterRoutes(v1, params.BillingHandler)
+		ledger.RegisterRoutes(v1, params.LedgerHandler)
+		currency.RegisterRoutes(v1, params.CurrencyHandler)
+		metrics.RegisterRoutes(v1, params.MetricsHandler)
 	}
 }
@@ -0,0 +1,71 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/finlleyl/cp_database/internal/auth"
+	"github.com/finlleyl/cp_database/internal/dbtx"
+	"github.com/finlleyl/cp_database/internal/domain/audit"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// RunFunc — один проход фонового обработчика
+type RunFunc func(ctx context.Context) error
+
+type poller struct {
+	name     string
+	interval time.Duration
+	run      RunFunc
+	logger   *zap.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start вызывает run каждые interval, пока работает приложение. Проход выполняется
+// от имени системного пользователя с источником изменений worker; ошибка пишется
+// в лог, и опрос продолжается со следующего тика.
+func Start(lc fx.Lifecycle, logger *zap.Logger, name string, interval time.Duration, run RunFunc) {
+	p := &poller{name: name, interval: interval, run: run, logger: logger}
+
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			workerCtx := dbtx.WithSettings(auth.WithSystemActor(context.Background()), map[string]string{
+				dbtx.SettingSource: string(audit.SourceWorker),
+			})
+			ctx, cancel := context.WithCancel(workerCtx)
+			p.cancel = cancel
+			p.wg.Add(1)
+			go p.poll(ctx)
+			logger.Info(name + " started")
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			logger.Info(name + " stopping")
+			p.cancel()
+			p.wg.Wait()
+			return nil
+		},
+	})
+}
+
+func (p *poller) poll(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.run(ctx); err != nil && ctx.Err() == nil {
+				p.logger.Error(p.name+" run failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,17 @@
+ALTER TABLE copied_trades
+    DROP COLUMN IF EXISTS sizing_ratio,
+    DROP COLUMN IF EXISTS sizing_mode;
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS chk_subscriptions_fixed_lot,
+    DROP CONSTRAINT IF EXISTS chk_subscriptions_lot_range,
+    DROP COLUMN IF EXISTS max_lot,
+    DROP COLUMN IF EXISTS min_lot,
+    DROP COLUMN IF EXISTS volume_multiplier,
+    DROP COLUMN IF EXISTS fixed_lot,
+    DROP COLUMN IF EXISTS sizing_mode;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS equity;
+
+DROP TYPE IF EXISTS sizing_mode;
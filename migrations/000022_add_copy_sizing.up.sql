@@ -0,0 +1,19 @@
+CREATE TYPE sizing_mode AS ENUM ('fixed_lot', 'multiplier', 'equity_ratio');
+
+ALTER TABLE accounts
+    ADD COLUMN equity NUMERIC(18,2) NOT NULL DEFAULT 0 CHECK (equity >= 0);
+
+ALTER TABLE subscriptions
+    ADD COLUMN sizing_mode       sizing_mode NOT NULL DEFAULT 'multiplier',
+    ADD COLUMN fixed_lot         NUMERIC(12,4) CHECK (fixed_lot > 0),
+    ADD COLUMN volume_multiplier NUMERIC(12,4) NOT NULL DEFAULT 1 CHECK (volume_multiplier > 0),
+    ADD COLUMN min_lot           NUMERIC(12,4) CHECK (min_lot > 0),
+    ADD COLUMN max_lot           NUMERIC(12,4) CHECK (max_lot > 0),
+    ADD CONSTRAINT chk_subscriptions_lot_range
+        CHECK (min_lot IS NULL OR max_lot IS NULL OR min_lot <= max_lot),
+    ADD CONSTRAINT chk_subscriptions_fixed_lot
+        CHECK (sizing_mode <> 'fixed_lot' OR fixed_lot IS NOT NULL);
+
+ALTER TABLE copied_trades
+    ADD COLUMN sizing_mode  sizing_mode,
+    ADD COLUMN sizing_ratio NUMERIC(18,8);
@@ -0,0 +1,2 @@
+ALTER TABLE copied_trades
+    DROP CONSTRAINT IF EXISTS uq_copied_trades_trade_subscription;
@@ -0,0 +1,9 @@
+-- Удаляем дубликаты копий, оставляя самую раннюю запись для пары (сделка, подписка)
+DELETE FROM copied_trades ct
+USING copied_trades dup
+WHERE ct.trade_id = dup.trade_id
+  AND ct.subscription_id = dup.subscription_id
+  AND ct.id > dup.id;
+
+ALTER TABLE copied_trades
+    ADD CONSTRAINT uq_copied_trades_trade_subscription UNIQUE (trade_id, subscription_id);
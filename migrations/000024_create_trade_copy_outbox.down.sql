@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_trade_copy_outbox_status_available_at;
+DROP TABLE IF EXISTS trade_copy_outbox;
+DROP TYPE IF EXISTS copy_fanout_status;
+
+ALTER TABLE strategies
+    DROP COLUMN IF EXISTS auto_copy;
@@ -0,0 +1,27 @@
+ALTER TABLE strategies
+    ADD COLUMN auto_copy BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TYPE copy_fanout_status AS ENUM ('pending', 'processing', 'done', 'failed');
+
+CREATE TABLE trade_copy_outbox (
+    id            BIGSERIAL PRIMARY KEY,
+    trade_id      BIGINT NOT NULL UNIQUE,
+    status        copy_fanout_status NOT NULL DEFAULT 'pending',
+    attempts      INT NOT NULL DEFAULT 0,
+    copied_count  INT NOT NULL DEFAULT 0,
+    skipped_count INT NOT NULL DEFAULT 0,
+    failed_count  INT NOT NULL DEFAULT 0,
+    last_error    TEXT,
+    available_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at  TIMESTAMPTZ,
+
+    CONSTRAINT fk_trade_copy_outbox_trade
+        FOREIGN KEY (trade_id)
+        REFERENCES trades (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_trade_copy_outbox_status_available_at ON trade_copy_outbox (status, available_at);
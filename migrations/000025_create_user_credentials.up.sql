@@ -0,0 +1,13 @@
+-- Хэши паролей хранятся отдельно от users, чтобы не попадать в audit_log
+CREATE TABLE user_credentials (
+    user_id       BIGINT PRIMARY KEY,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_user_credentials_user
+        FOREIGN KEY (user_id)
+        REFERENCES users (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
@@ -0,0 +1,57 @@
+CREATE OR REPLACE FUNCTION fn_audit_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_old_row JSONB;
+    v_new_row JSONB;
+    v_entity_pk TEXT;
+    v_operation audit_operation;
+BEGIN
+
+    IF TG_OP = 'INSERT' THEN
+        v_operation := 'insert';
+        v_new_row := to_jsonb(NEW);
+        v_old_row := NULL;
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'UPDATE' THEN
+        v_operation := 'update';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := to_jsonb(NEW);
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'DELETE' THEN
+        v_operation := 'delete';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := NULL;
+        v_entity_pk := OLD.id::TEXT;
+    END IF;
+
+
+    INSERT INTO audit_log (
+        entity_name,
+        entity_pk,
+        operation,
+        changed_by,
+        changed_at,
+        old_row,
+        new_row
+    ) VALUES (
+        TG_TABLE_NAME,
+        v_entity_pk,
+        v_operation,
+        current_setting('app.current_user_id', true)::BIGINT,
+        now(),
+        v_old_row,
+        v_new_row
+    );
+
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE audit_log
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address;
@@ -0,0 +1,63 @@
+ALTER TABLE audit_log
+    ADD COLUMN ip_address TEXT,
+    ADD COLUMN user_agent TEXT;
+
+-- После SET LOCAL current_setting возвращает пустую строку, а не NULL,
+-- поэтому значения приводятся через NULLIF
+CREATE OR REPLACE FUNCTION fn_audit_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_old_row JSONB;
+    v_new_row JSONB;
+    v_entity_pk TEXT;
+    v_operation audit_operation;
+BEGIN
+
+    IF TG_OP = 'INSERT' THEN
+        v_operation := 'insert';
+        v_new_row := to_jsonb(NEW);
+        v_old_row := NULL;
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'UPDATE' THEN
+        v_operation := 'update';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := to_jsonb(NEW);
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'DELETE' THEN
+        v_operation := 'delete';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := NULL;
+        v_entity_pk := OLD.id::TEXT;
+    END IF;
+
+
+    INSERT INTO audit_log (
+        entity_name,
+        entity_pk,
+        operation,
+        changed_by,
+        changed_at,
+        old_row,
+        new_row,
+        ip_address,
+        user_agent
+    ) VALUES (
+        TG_TABLE_NAME,
+        v_entity_pk,
+        v_operation,
+        NULLIF(current_setting('app.current_user_id', true), '')::BIGINT,
+        now(),
+        v_old_row,
+        v_new_row,
+        NULLIF(current_setting('app.request_ip', true), ''),
+        NULLIF(current_setting('app.user_agent', true), '')
+    );
+
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
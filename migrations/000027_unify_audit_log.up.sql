@@ -0,0 +1,205 @@
+ALTER TABLE audit_log
+    ADD COLUMN source        TEXT NOT NULL DEFAULT 'system',
+    ADD COLUMN diff          JSONB,
+    ADD COLUMN status_reason TEXT;
+
+-- Записи, сделанные до появления source, помечаются как legacy
+UPDATE audit_log SET source = 'legacy';
+
+ALTER TABLE audit_log
+    ADD CONSTRAINT chk_audit_log_source
+        CHECK (source IN ('api', 'worker', 'import', 'restore', 'system', 'legacy'));
+
+CREATE INDEX idx_audit_log_source ON audit_log (source);
+
+
+-- Поле за полем сравнивает две версии строки; updated_at не считается изменением
+CREATE OR REPLACE FUNCTION fn_audit_diff(p_old JSONB, p_new JSONB)
+RETURNS JSONB AS $$
+    SELECT COALESCE(
+        jsonb_object_agg(
+            k.key,
+            jsonb_build_object('old', p_old -> k.key, 'new', p_new -> k.key)
+        ),
+        '{}'::JSONB
+    )
+    FROM (
+        SELECT jsonb_object_keys(COALESCE(p_old, '{}'::JSONB)) AS key
+        UNION
+        SELECT jsonb_object_keys(COALESCE(p_new, '{}'::JSONB))
+    ) k
+    WHERE k.key <> 'updated_at'
+      AND (p_old -> k.key) IS DISTINCT FROM (p_new -> k.key);
+$$ LANGUAGE sql IMMUTABLE;
+
+
+CREATE OR REPLACE FUNCTION fn_audit_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_old_row JSONB;
+    v_new_row JSONB;
+    v_entity_pk TEXT;
+    v_operation audit_operation;
+    v_status_reason TEXT;
+BEGIN
+
+    IF TG_OP = 'INSERT' THEN
+        v_operation := 'insert';
+        v_new_row := to_jsonb(NEW);
+        v_old_row := NULL;
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'UPDATE' THEN
+        v_operation := 'update';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := to_jsonb(NEW);
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'DELETE' THEN
+        v_operation := 'delete';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := NULL;
+        v_entity_pk := OLD.id::TEXT;
+    END IF;
+
+    -- Обновление без фактических изменений не попадает в журнал
+    IF TG_OP = 'UPDATE' AND fn_audit_diff(v_old_row, v_new_row) = '{}'::JSONB THEN
+        RETURN NEW;
+    END IF;
+
+    IF v_new_row ? 'status' AND (v_old_row -> 'status') IS DISTINCT FROM (v_new_row -> 'status') THEN
+        v_status_reason := NULLIF(current_setting('app.status_reason', true), '');
+    END IF;
+
+
+    INSERT INTO audit_log (
+        entity_name,
+        entity_pk,
+        operation,
+        changed_by,
+        changed_at,
+        old_row,
+        new_row,
+        ip_address,
+        user_agent,
+        source,
+        diff,
+        status_reason
+    ) VALUES (
+        TG_TABLE_NAME,
+        v_entity_pk,
+        v_operation,
+        NULLIF(current_setting('app.current_user_id', true), '')::BIGINT,
+        now(),
+        v_old_row,
+        v_new_row,
+        NULLIF(current_setting('app.request_ip', true), ''),
+        NULLIF(current_setting('app.user_agent', true), ''),
+        COALESCE(NULLIF(current_setting('app.source', true), ''), 'system'),
+        fn_audit_diff(v_old_row, v_new_row),
+        v_status_reason
+    );
+
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- Одинаковы ли две версии строки: JSON Go-структуры и to_jsonb строки таблицы
+-- совпадают по каждому общему ключу. Время сравнивается как момент, поэтому
+-- '...Z' из Go и '...+00:00' из Postgres считаются равными.
+CREATE OR REPLACE FUNCTION fn_audit_rows_match(p_a JSONB, p_b JSONB)
+RETURNS BOOLEAN AS $$
+DECLARE
+    v_key    TEXT;
+    v_a      JSONB;
+    v_b      JSONB;
+    v_shared INT := 0;
+BEGIN
+    IF p_a IS NULL OR p_b IS NULL THEN
+        RETURN p_a IS NULL AND p_b IS NULL;
+    END IF;
+
+    FOR v_key, v_a IN SELECT key, value FROM jsonb_each(p_a) LOOP
+        CONTINUE WHEN NOT p_b ? v_key;
+        v_b := p_b -> v_key;
+        v_shared := v_shared + 1;
+        CONTINUE WHEN v_a = v_b;
+
+        IF jsonb_typeof(v_a) = 'string' AND jsonb_typeof(v_b) = 'string' THEN
+            BEGIN
+                CONTINUE WHEN (v_a #>> '{}')::TIMESTAMPTZ = (v_b #>> '{}')::TIMESTAMPTZ;
+            EXCEPTION WHEN data_exception THEN
+                NULL;
+            END;
+        END IF;
+
+        RETURN FALSE;
+    END LOOP;
+
+    RETURN v_shared > 0;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+
+-- Раньше use case'ы дублировали каждую запись триггера своей строкой с JSON
+-- Go-структуры. Дубль идёт сразу за строкой триггера (та же сущность, та же
+-- операция, разница не больше p_window) и описывает то же состояние строки:
+-- old_row и new_row совпадают целиком по общим полям. Две настоящие правки
+-- подряд отличаются хотя бы одним полем и дублями не считаются. Функция
+-- удаляет такие дубли, переносит changed_by в строку триггера и возвращает
+-- количество удалённых строк; её можно запускать повторно.
+CREATE OR REPLACE FUNCTION fn_audit_dedupe_legacy(p_window INTERVAL DEFAULT INTERVAL '5 seconds')
+RETURNS BIGINT AS $$
+DECLARE
+    v_deleted BIGINT;
+BEGIN
+    CREATE TEMP TABLE tmp_audit_duplicates AS
+    SELECT dup.id AS duplicate_id, dup.kept_id, dup.changed_by
+    FROM (
+        SELECT
+            a.id,
+            a.changed_by,
+            a.changed_at,
+            a.new_row,
+            a.old_row,
+            LAG(a.id)         OVER w AS kept_id,
+            LAG(a.changed_at) OVER w AS kept_at,
+            LAG(a.new_row)    OVER w AS kept_new_row,
+            LAG(a.old_row)    OVER w AS kept_old_row
+        FROM audit_log a
+        WHERE a.source = 'legacy'
+        WINDOW w AS (PARTITION BY a.entity_name, a.entity_pk, a.operation ORDER BY a.id)
+    ) dup
+    WHERE dup.kept_id IS NOT NULL
+      AND dup.changed_at - dup.kept_at BETWEEN INTERVAL '0' AND p_window
+      AND fn_audit_rows_match(dup.old_row, dup.kept_old_row)
+      AND fn_audit_rows_match(dup.new_row, dup.kept_new_row);
+
+    UPDATE audit_log a
+    SET changed_by = d.changed_by
+    FROM tmp_audit_duplicates d
+    WHERE a.id = d.kept_id
+      AND a.changed_by IS NULL
+      AND d.changed_by IS NOT NULL;
+
+    DELETE FROM audit_log a
+    USING tmp_audit_duplicates d
+    WHERE a.id = d.duplicate_id;
+
+    GET DIAGNOSTICS v_deleted = ROW_COUNT;
+
+    DROP TABLE tmp_audit_duplicates;
+
+    RETURN v_deleted;
+END;
+$$ LANGUAGE plpgsql;
+
+SELECT fn_audit_dedupe_legacy();
+
+UPDATE audit_log
+SET diff = fn_audit_diff(old_row, new_row)
+WHERE diff IS NULL;
@@ -0,0 +1,83 @@
+CREATE OR REPLACE FUNCTION fn_audit_trigger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_old_row JSONB;
+    v_new_row JSONB;
+    v_entity_pk TEXT;
+    v_operation audit_operation;
+    v_status_reason TEXT;
+BEGIN
+
+    IF TG_OP = 'INSERT' THEN
+        v_operation := 'insert';
+        v_new_row := to_jsonb(NEW);
+        v_old_row := NULL;
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'UPDATE' THEN
+        v_operation := 'update';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := to_jsonb(NEW);
+        v_entity_pk := NEW.id::TEXT;
+    ELSIF TG_OP = 'DELETE' THEN
+        v_operation := 'delete';
+        v_old_row := to_jsonb(OLD);
+        v_new_row := NULL;
+        v_entity_pk := OLD.id::TEXT;
+    END IF;
+
+    -- Обновление без фактических изменений не попадает в журнал
+    IF TG_OP = 'UPDATE' AND fn_audit_diff(v_old_row, v_new_row) = '{}'::JSONB THEN
+        RETURN NEW;
+    END IF;
+
+    IF v_new_row ? 'status' AND (v_old_row -> 'status') IS DISTINCT FROM (v_new_row -> 'status') THEN
+        v_status_reason := NULLIF(current_setting('app.status_reason', true), '');
+    END IF;
+
+
+    INSERT INTO audit_log (
+        entity_name,
+        entity_pk,
+        operation,
+        changed_by,
+        changed_at,
+        old_row,
+        new_row,
+        ip_address,
+        user_agent,
+        source,
+        diff,
+        status_reason
+    ) VALUES (
+        TG_TABLE_NAME,
+        v_entity_pk,
+        v_operation,
+        NULLIF(current_setting('app.current_user_id', true), '')::BIGINT,
+        now(),
+        v_old_row,
+        v_new_row,
+        NULLIF(current_setting('app.request_ip', true), ''),
+        NULLIF(current_setting('app.user_agent', true), ''),
+        COALESCE(NULLIF(current_setting('app.source', true), ''), 'system'),
+        fn_audit_diff(v_old_row, v_new_row),
+        v_status_reason
+    );
+
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+ALTER TABLE audit_log
+    DROP CONSTRAINT IF EXISTS fk_audit_log_restored_from,
+    DROP COLUMN IF EXISTS restored_from_id;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check
+        CHECK (role IN ('master', 'investor', 'both'));
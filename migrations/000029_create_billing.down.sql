@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS uq_commissions_period;
+DROP INDEX IF EXISTS uq_commissions_registration;
+
+ALTER TABLE commissions
+    DROP CONSTRAINT IF EXISTS chk_commissions_period;
+
+DROP TABLE IF EXISTS subscription_billing_state;
+
+ALTER TABLE offers
+    DROP COLUMN IF EXISTS fee_interval;
+
+DROP TYPE IF EXISTS fee_interval;
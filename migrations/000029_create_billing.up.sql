@@ -0,0 +1,69 @@
+CREATE TYPE fee_interval AS ENUM ('daily', 'weekly', 'monthly');
+
+ALTER TABLE offers
+    ADD COLUMN fee_interval fee_interval NOT NULL DEFAULT 'monthly';
+
+-- Курсор биллинга: до какого момента подписке уже начислены комиссии за обслуживание
+-- и за результат. Строка появляется при активации подписки.
+CREATE TABLE subscription_billing_state (
+    subscription_id BIGINT PRIMARY KEY,
+    activated_at    TIMESTAMPTZ NOT NULL,
+    billed_until    TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_subscription_billing_state_subscription
+        FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_subscription_billing_state_billed_until ON subscription_billing_state (billed_until);
+
+INSERT INTO subscription_billing_state (subscription_id, activated_at, billed_until)
+SELECT id, created_at, created_at
+FROM subscriptions
+WHERE status IN ('active', 'suspended');
+
+ALTER TABLE commissions
+    ADD CONSTRAINT chk_commissions_period
+        CHECK (period_from IS NULL OR period_to IS NULL OR period_from <= period_to);
+
+-- Регистрационная комиссия списывается один раз: оставляем самую раннюю запись подписки
+DELETE FROM commissions c
+USING commissions dup
+WHERE c.type = 'registration'
+  AND dup.type = 'registration'
+  AND c.subscription_id = dup.subscription_id
+  AND c.id > dup.id;
+
+-- Периодические комиссии с одинаковым началом периода сливаем в самую раннюю запись:
+-- суммы складываются, период продлевается до самого позднего конца
+WITH grouped AS (
+    SELECT MIN(id) AS keep_id, SUM(amount) AS amount, MAX(period_to) AS period_to
+    FROM commissions
+    WHERE type <> 'registration'
+    GROUP BY subscription_id, type, period_from
+    HAVING COUNT(*) > 1
+)
+UPDATE commissions c
+SET amount = g.amount, period_to = g.period_to
+FROM grouped g
+WHERE c.id = g.keep_id;
+
+DELETE FROM commissions c
+USING commissions dup
+WHERE c.type <> 'registration'
+  AND c.subscription_id = dup.subscription_id
+  AND c.type = dup.type
+  AND c.period_from IS NOT DISTINCT FROM dup.period_from
+  AND c.id > dup.id;
+
+-- Повторный запуск биллинга за тот же период не должен списывать комиссию дважды
+CREATE UNIQUE INDEX uq_commissions_registration
+    ON commissions (subscription_id)
+    WHERE type = 'registration';
+
+CREATE UNIQUE INDEX uq_commissions_period
+    ON commissions (subscription_id, type, period_from)
+    WHERE type <> 'registration';
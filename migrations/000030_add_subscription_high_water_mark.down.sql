@@ -0,0 +1,2 @@
+ALTER TABLE subscription_billing_state
+    DROP COLUMN IF EXISTS high_water_mark;
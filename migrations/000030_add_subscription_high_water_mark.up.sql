@@ -0,0 +1,20 @@
+-- Пик накопленной прибыли закрытых скопированных сделок, с которого уже взята
+-- комиссия за результат. Новая комиссия берётся только с прибыли выше пика.
+ALTER TABLE subscription_billing_state
+    ADD COLUMN high_water_mark NUMERIC(18,2) NOT NULL DEFAULT 0;
+
+UPDATE subscription_billing_state b
+SET high_water_mark = peak.value
+FROM (
+    SELECT subscription_id, GREATEST(MAX(running_profit), 0) AS value
+    FROM (
+        SELECT ct.subscription_id,
+               SUM(ct.profit) OVER (PARTITION BY ct.subscription_id ORDER BY ct.close_time, ct.id) AS running_profit
+        FROM copied_trades ct
+        JOIN subscription_billing_state s ON s.subscription_id = ct.subscription_id
+        WHERE ct.close_time IS NOT NULL
+          AND ct.close_time < s.billed_until
+    ) r
+    GROUP BY subscription_id
+) peak
+WHERE peak.subscription_id = b.subscription_id;
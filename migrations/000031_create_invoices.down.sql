@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_commissions_invoice_id;
+
+ALTER TABLE commissions
+    DROP CONSTRAINT IF EXISTS fk_commissions_invoice,
+    DROP COLUMN IF EXISTS invoice_id;
+
+DROP TABLE IF EXISTS invoices;
+
+DROP SEQUENCE IF EXISTS invoice_number_seq;
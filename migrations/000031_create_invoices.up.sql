@@ -0,0 +1,34 @@
+CREATE SEQUENCE invoice_number_seq;
+
+-- Счёт за период биллинга: группирует комиссии подписки в нумерованный документ
+CREATE TABLE invoices (
+    id              BIGSERIAL PRIMARY KEY,
+    number          TEXT NOT NULL UNIQUE,
+    subscription_id BIGINT NOT NULL,
+    period_from     TIMESTAMPTZ NOT NULL,
+    period_to       TIMESTAMPTZ NOT NULL,
+    currency        CHAR(3) NOT NULL,
+    total_amount    NUMERIC(18,2) NOT NULL CHECK (total_amount >= 0),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT chk_invoices_period CHECK (period_from <= period_to),
+    CONSTRAINT uq_invoices_subscription_period UNIQUE (subscription_id, period_from),
+
+    CONSTRAINT fk_invoices_subscription
+        FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id)
+        ON UPDATE CASCADE
+        ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_invoices_period_to ON invoices (period_to);
+
+ALTER TABLE commissions
+    ADD COLUMN invoice_id BIGINT,
+    ADD CONSTRAINT fk_commissions_invoice
+        FOREIGN KEY (invoice_id)
+        REFERENCES invoices (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL;
+
+CREATE INDEX idx_commissions_invoice_id ON commissions (invoice_id);
@@ -0,0 +1,18 @@
+DROP TRIGGER IF EXISTS accounts_ledger_trg ON accounts;
+DROP FUNCTION IF EXISTS trg_accounts_ledger();
+
+DROP TRIGGER IF EXISTS copied_trades_ledger_trg ON copied_trades;
+DROP FUNCTION IF EXISTS trg_copied_trades_ledger();
+
+DROP FUNCTION IF EXISTS fn_ledger_post(ledger_entry_type, BIGINT, NUMERIC, BIGINT, ledger_system_account, BIGINT, BIGINT, TEXT, TIMESTAMPTZ);
+
+DROP TABLE IF EXISTS account_balances;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
+
+DROP FUNCTION IF EXISTS trg_ledger_immutable();
+DROP FUNCTION IF EXISTS trg_ledger_entries_check_balanced();
+DROP FUNCTION IF EXISTS trg_ledger_entries_apply_balance();
+
+DROP TYPE IF EXISTS ledger_system_account;
+DROP TYPE IF EXISTS ledger_entry_type;
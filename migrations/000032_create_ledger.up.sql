@@ -0,0 +1,248 @@
+CREATE TYPE ledger_entry_type AS ENUM ('deposit', 'withdrawal', 'trade_profit', 'commission');
+
+-- Внешние контрагенты двойной записи, у которых нет счёта в accounts;
+-- fx — клиринговый счёт для операций между счетами в разных валютах
+CREATE TYPE ledger_system_account AS ENUM ('external', 'market', 'fx');
+
+CREATE TABLE ledger_transactions (
+    id              BIGSERIAL PRIMARY KEY,
+    type            ledger_entry_type NOT NULL,
+    copied_trade_id BIGINT,
+    commission_id   BIGINT,
+    description     TEXT,
+    created_by      BIGINT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_ledger_transactions_copied_trade
+        FOREIGN KEY (copied_trade_id)
+        REFERENCES copied_trades (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL,
+
+    CONSTRAINT fk_ledger_transactions_commission
+        FOREIGN KEY (commission_id)
+        REFERENCES commissions (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX uq_ledger_transactions_commission ON ledger_transactions (commission_id);
+CREATE INDEX idx_ledger_transactions_copied_trade_id ON ledger_transactions (copied_trade_id);
+
+-- Проводки: у каждой транзакции сумма amount по всем проводкам равна нулю.
+-- Положительная сумма — зачисление на счёт, отрицательная — списание.
+CREATE TABLE ledger_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL,
+    account_id     BIGINT,
+    system_account ledger_system_account,
+    amount         NUMERIC(18,2) NOT NULL CHECK (amount <> 0),
+    balance_after  NUMERIC(18,2),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT chk_ledger_entries_party
+        CHECK ((account_id IS NULL) <> (system_account IS NULL)),
+
+    CONSTRAINT fk_ledger_entries_transaction
+        FOREIGN KEY (transaction_id)
+        REFERENCES ledger_transactions (id)
+        ON UPDATE CASCADE
+        ON DELETE RESTRICT,
+
+    CONSTRAINT fk_ledger_entries_account
+        FOREIGN KEY (account_id)
+        REFERENCES accounts (id)
+        ON UPDATE CASCADE
+        ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+CREATE INDEX idx_ledger_entries_account_id_id ON ledger_entries (account_id, id);
+
+CREATE TABLE account_balances (
+    account_id BIGINT PRIMARY KEY,
+    balance    NUMERIC(18,2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_account_balances_account
+        FOREIGN KEY (account_id)
+        REFERENCES accounts (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+
+-- Проводка по счёту сразу меняет его баланс и запоминает остаток после операции
+CREATE OR REPLACE FUNCTION trg_ledger_entries_apply_balance()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.account_id IS NOT NULL THEN
+        INSERT INTO account_balances (account_id, balance)
+        VALUES (NEW.account_id, NEW.amount)
+        ON CONFLICT (account_id) DO UPDATE
+            SET balance = account_balances.balance + EXCLUDED.balance,
+                updated_at = now()
+        RETURNING balance INTO NEW.balance_after;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_apply_balance_trg
+BEFORE INSERT ON ledger_entries
+FOR EACH ROW EXECUTE FUNCTION trg_ledger_entries_apply_balance();
+
+
+-- Сбалансированность транзакции проверяется при коммите, когда записаны все проводки
+CREATE OR REPLACE FUNCTION trg_ledger_entries_check_balanced()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_sum NUMERIC;
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO v_sum
+    FROM ledger_entries
+    WHERE transaction_id = NEW.transaction_id;
+
+    IF v_sum <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction % is not balanced: %', NEW.transaction_id, v_sum
+            USING ERRCODE = '23514';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced_trg
+AFTER INSERT ON ledger_entries
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION trg_ledger_entries_check_balanced();
+
+
+-- Журнал только дополняется: исправления оформляются новой транзакцией
+CREATE OR REPLACE FUNCTION trg_ledger_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_immutable_trg
+BEFORE UPDATE OR DELETE ON ledger_entries
+FOR EACH ROW EXECUTE FUNCTION trg_ledger_immutable();
+
+
+-- Двухсторонняя проводка: p_amount зачисляется на p_account_id и списывается
+-- с контрагента (счёта p_counter_account_id или системного p_counter_system)
+CREATE OR REPLACE FUNCTION fn_ledger_post(
+    p_type               ledger_entry_type,
+    p_account_id         BIGINT,
+    p_amount             NUMERIC,
+    p_counter_account_id BIGINT,
+    p_counter_system     ledger_system_account,
+    p_copied_trade_id    BIGINT,
+    p_commission_id      BIGINT,
+    p_description        TEXT,
+    p_created_at         TIMESTAMPTZ DEFAULT now()
+)
+RETURNS BIGINT AS $$
+DECLARE
+    v_transaction_id BIGINT;
+BEGIN
+    IF p_amount IS NULL OR p_amount = 0 THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO ledger_transactions (type, copied_trade_id, commission_id, description, created_by, created_at)
+    VALUES (
+        p_type,
+        p_copied_trade_id,
+        p_commission_id,
+        p_description,
+        NULLIF(current_setting('app.current_user_id', true), '')::BIGINT,
+        p_created_at
+    )
+    RETURNING id INTO v_transaction_id;
+
+    INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+    VALUES (v_transaction_id, p_account_id, p_amount, p_created_at);
+
+    INSERT INTO ledger_entries (transaction_id, account_id, system_account, amount, created_at)
+    VALUES (
+        v_transaction_id,
+        p_counter_account_id,
+        CASE WHEN p_counter_account_id IS NULL THEN p_counter_system END,
+        -p_amount,
+        p_created_at
+    );
+
+    RETURN v_transaction_id;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- Закрытие скопированной сделки: прибыль зачисляется инвестору со счёта market.
+-- Последующее изменение прибыли проводится разницей.
+CREATE OR REPLACE FUNCTION trg_copied_trades_ledger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_delta NUMERIC;
+BEGIN
+    IF NEW.close_time IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    IF TG_OP = 'INSERT' OR OLD.close_time IS NULL THEN
+        v_delta := COALESCE(NEW.profit, 0);
+    ELSE
+        v_delta := COALESCE(NEW.profit, 0) - COALESCE(OLD.profit, 0);
+    END IF;
+
+    PERFORM fn_ledger_post('trade_profit', NEW.investor_account_id, v_delta, NULL, 'market',
+                           NEW.id, NULL, NULL);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER copied_trades_ledger_trg
+AFTER INSERT OR UPDATE OF close_time, profit ON copied_trades
+FOR EACH ROW EXECUTE FUNCTION trg_copied_trades_ledger();
+
+
+-- Эквити счёта — внесённый капитал: открытие счёта проводится пополнением с external,
+-- последующее изменение эквити — пополнением или выводом разницы
+CREATE OR REPLACE FUNCTION trg_accounts_ledger()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_delta NUMERIC;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        v_delta := NEW.equity;
+    ELSE
+        v_delta := NEW.equity - OLD.equity;
+    END IF;
+
+    PERFORM fn_ledger_post(CASE WHEN v_delta > 0 THEN 'deposit' ELSE 'withdrawal' END::ledger_entry_type,
+                           NEW.id, v_delta, NULL, 'external', NULL, NULL, 'equity');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER accounts_ledger_trg
+AFTER INSERT OR UPDATE OF equity ON accounts
+FOR EACH ROW EXECUTE FUNCTION trg_accounts_ledger();
+
+
+-- Перенос истории: эквити счетов и закрытые скопированные сделки. Комиссии переносятся
+-- в 000041, где есть пересчёт по курсу между валютами счетов инвестора и мастера.
+SELECT fn_ledger_post('deposit', a.id, a.equity, NULL, 'external',
+                      NULL, NULL, 'equity', a.created_at)
+FROM accounts a
+WHERE a.equity > 0
+ORDER BY a.created_at, a.id;
+
+SELECT fn_ledger_post('trade_profit', ct.investor_account_id, ct.profit, NULL, 'market',
+                      ct.id, NULL, NULL, ct.close_time)
+FROM copied_trades ct
+WHERE ct.close_time IS NOT NULL
+  AND ct.profit IS NOT NULL
+  AND ct.profit <> 0
+ORDER BY ct.close_time, ct.id;
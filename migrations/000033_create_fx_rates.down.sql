@@ -0,0 +1,75 @@
+DROP FUNCTION IF EXISTS fn_get_strategy_total_profit(BIGINT, CHAR(3));
+
+CREATE OR REPLACE FUNCTION fn_get_strategy_total_profit(p_strategy_id BIGINT)
+RETURNS NUMERIC AS $$
+DECLARE
+    v_profit NUMERIC(18,2);
+BEGIN
+    SELECT COALESCE(SUM(ct.profit), 0)
+    INTO v_profit
+    FROM copied_trades ct
+    JOIN subscriptions sub ON sub.id = ct.subscription_id
+    JOIN offers o ON o.id = sub.offer_id
+    WHERE o.strategy_id = p_strategy_id;
+
+    RETURN v_profit;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+DROP FUNCTION IF EXISTS fn_get_investor_portfolio(BIGINT, CHAR(3));
+
+CREATE OR REPLACE FUNCTION fn_get_investor_portfolio(
+    p_investor_user_id BIGINT
+)
+RETURNS TABLE (
+    subscription_id      BIGINT,
+    strategy_id          BIGINT,
+    strategy_title       TEXT,
+    total_profit         NUMERIC(18,2),
+    copied_trades_count  BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT
+        v.subscription_id,
+        v.strategy_id,
+        v.strategy_title,
+        v.total_profit,
+        v.copied_trades_count
+    FROM vw_investor_portfolio v
+    WHERE v.investor_user_id = p_investor_user_id;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+DROP FUNCTION IF EXISTS fn_get_strategy_leaderboard(INT, CHAR(3));
+
+CREATE OR REPLACE FUNCTION fn_get_strategy_leaderboard(
+    p_limit INT
+)
+RETURNS TABLE (
+    strategy_id        BIGINT,
+    title              TEXT,
+    total_profit       NUMERIC(18,2),
+    total_commissions  NUMERIC(18,2),
+    active_subscriptions INT
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT
+        sp.strategy_id,
+        sp.title,
+        sp.total_profit,
+        sp.total_commissions,
+        sp.active_subscriptions
+    FROM vw_strategy_performance sp
+    ORDER BY sp.total_profit DESC
+    LIMIT p_limit;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+DROP FUNCTION IF EXISTS fn_fx_convert(NUMERIC, CHAR(3), CHAR(3), TIMESTAMPTZ);
+DROP FUNCTION IF EXISTS fn_fx_rate(CHAR(3), CHAR(3), TIMESTAMPTZ);
+
+DROP TABLE IF EXISTS fx_rates;
+
+-- Значение 'fx_rates' в import_job_type остаётся: PostgreSQL не удаляет значения перечислений
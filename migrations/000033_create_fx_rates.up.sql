@@ -0,0 +1,200 @@
+ALTER TYPE import_job_type ADD VALUE IF NOT EXISTS 'fx_rates';
+
+-- Курс на дату: 1 единица base_currency = rate единиц quote_currency
+CREATE TABLE fx_rates (
+    base_currency  CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate_date      DATE NOT NULL,
+    rate           NUMERIC(20,10) NOT NULL CHECK (rate > 0),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (base_currency, quote_currency, rate_date),
+    CONSTRAINT chk_fx_rates_pair CHECK (base_currency <> quote_currency)
+);
+
+-- Исторический курс: последний известный на дату p_at (UTC) прямой или обратный курс пары,
+-- при его отсутствии — кросс-курс через USD. NULL, если курса нет.
+CREATE OR REPLACE FUNCTION fn_fx_rate(
+    p_base  CHAR(3),
+    p_quote CHAR(3),
+    p_at    TIMESTAMPTZ
+)
+RETURNS NUMERIC AS $$
+DECLARE
+    v_date  DATE := (p_at AT TIME ZONE 'UTC')::DATE;
+    v_rate  NUMERIC;
+    v_left  NUMERIC;
+    v_right NUMERIC;
+BEGIN
+    IF upper(p_base) = upper(p_quote) THEN
+        RETURN 1;
+    END IF;
+
+    SELECT r.rate
+    INTO v_rate
+    FROM (
+        SELECT fx.rate, fx.rate_date, 0 AS priority
+        FROM fx_rates fx
+        WHERE fx.base_currency = upper(p_base)
+          AND fx.quote_currency = upper(p_quote)
+          AND fx.rate_date <= v_date
+        UNION ALL
+        SELECT 1 / fx.rate, fx.rate_date, 1
+        FROM fx_rates fx
+        WHERE fx.base_currency = upper(p_quote)
+          AND fx.quote_currency = upper(p_base)
+          AND fx.rate_date <= v_date
+    ) r
+    ORDER BY r.rate_date DESC, r.priority
+    LIMIT 1;
+
+    IF v_rate IS NOT NULL OR upper(p_base) = 'USD' OR upper(p_quote) = 'USD' THEN
+        RETURN v_rate;
+    END IF;
+
+    v_left := fn_fx_rate(p_base, 'USD', p_at);
+    v_right := fn_fx_rate('USD', p_quote, p_at);
+
+    RETURN v_left * v_right;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Пересчёт суммы в валюту p_to по курсу на дату p_at. p_to = NULL оставляет сумму без изменений.
+-- Отсутствие курса — ошибка с SQLSTATE FX404, чтобы не смешивать валюты молча.
+CREATE OR REPLACE FUNCTION fn_fx_convert(
+    p_amount NUMERIC,
+    p_from   CHAR(3),
+    p_to     CHAR(3),
+    p_at     TIMESTAMPTZ
+)
+RETURNS NUMERIC AS $$
+DECLARE
+    v_rate NUMERIC;
+BEGIN
+    IF p_amount IS NULL OR p_to IS NULL THEN
+        RETURN p_amount;
+    END IF;
+
+    v_rate := fn_fx_rate(p_from, p_to, p_at);
+    IF v_rate IS NULL THEN
+        RAISE EXCEPTION 'no exchange rate % -> % on %', p_from, upper(p_to), (p_at AT TIME ZONE 'UTC')::DATE
+            USING ERRCODE = 'FX404';
+    END IF;
+
+    RETURN p_amount * v_rate;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Агрегаты статистики пересчитываются в запрошенную валюту: прибыль — по курсу на дату
+-- закрытия сделки (для открытых — на текущую), комиссии — на конец периода начисления.
+DROP FUNCTION IF EXISTS fn_get_strategy_leaderboard(INT);
+
+CREATE OR REPLACE FUNCTION fn_get_strategy_leaderboard(
+    p_limit    INT,
+    p_currency CHAR(3)
+)
+RETURNS TABLE (
+    strategy_id        BIGINT,
+    title              TEXT,
+    total_profit       NUMERIC(18,2),
+    total_commissions  NUMERIC(18,2),
+    active_subscriptions INT
+) AS $$
+BEGIN
+    RETURN QUERY
+    WITH profits AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(ct.profit, a.currency, p_currency, COALESCE(ct.close_time, now()))) AS amount
+        FROM copied_trades ct
+        JOIN subscriptions sub ON sub.id = ct.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = ct.investor_account_id
+        WHERE ct.profit IS NOT NULL
+        GROUP BY o.strategy_id
+    ),
+    fees AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(c.amount, a.currency, p_currency, COALESCE(c.period_to, c.created_at))) AS amount
+        FROM commissions c
+        JOIN subscriptions sub ON sub.id = c.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = sub.investor_account_id
+        GROUP BY o.strategy_id
+    )
+    SELECT
+        sp.strategy_id,
+        sp.title,
+        ROUND(COALESCE(p.amount, 0), 2)::NUMERIC(18,2),
+        ROUND(COALESCE(f.amount, 0), 2)::NUMERIC(18,2),
+        COALESCE(sp.active_subscriptions, 0)
+    FROM vw_strategy_performance sp
+    LEFT JOIN profits p ON p.id = sp.strategy_id
+    LEFT JOIN fees f ON f.id = sp.strategy_id
+    ORDER BY COALESCE(p.amount, 0) DESC
+    LIMIT p_limit;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+DROP FUNCTION IF EXISTS fn_get_investor_portfolio(BIGINT);
+
+CREATE OR REPLACE FUNCTION fn_get_investor_portfolio(
+    p_investor_user_id BIGINT,
+    p_currency         CHAR(3)
+)
+RETURNS TABLE (
+    subscription_id      BIGINT,
+    strategy_id          BIGINT,
+    strategy_title       TEXT,
+    total_profit         NUMERIC(18,2),
+    copied_trades_count  BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+    SELECT
+        sub.id,
+        s.id,
+        s.title,
+        ROUND(COALESCE(SUM(fn_fx_convert(ct.profit, a.currency, p_currency, COALESCE(ct.close_time, now()))), 0), 2)::NUMERIC(18,2),
+        COUNT(ct.id)
+    FROM subscriptions sub
+    JOIN offers o ON o.id = sub.offer_id
+    JOIN strategies s ON s.id = o.strategy_id
+    LEFT JOIN copied_trades ct ON ct.subscription_id = sub.id
+    LEFT JOIN accounts a ON a.id = ct.investor_account_id
+    WHERE sub.investor_user_id = p_investor_user_id
+    GROUP BY sub.id, s.id, s.title;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+-- Без валюты прибыль считается в валюте счёта мастера стратегии
+DROP FUNCTION IF EXISTS fn_get_strategy_total_profit(BIGINT);
+
+CREATE OR REPLACE FUNCTION fn_get_strategy_total_profit(
+    p_strategy_id BIGINT,
+    p_currency    CHAR(3) DEFAULT NULL
+)
+RETURNS NUMERIC AS $$
+DECLARE
+    v_currency CHAR(3) := p_currency;
+    v_profit   NUMERIC(18,2);
+BEGIN
+    IF v_currency IS NULL THEN
+        SELECT a.currency
+        INTO v_currency
+        FROM strategies s
+        JOIN accounts a ON a.id = s.master_account_id
+        WHERE s.id = p_strategy_id;
+    END IF;
+
+    SELECT COALESCE(ROUND(SUM(fn_fx_convert(ct.profit, a.currency, v_currency, COALESCE(ct.close_time, now()))), 2), 0)
+    INTO v_profit
+    FROM copied_trades ct
+    JOIN subscriptions sub ON sub.id = ct.subscription_id
+    JOIN offers o ON o.id = sub.offer_id
+    JOIN accounts a ON a.id = ct.investor_account_id
+    WHERE o.strategy_id = p_strategy_id;
+
+    RETURN v_profit;
+END;
+$$ LANGUAGE plpgsql STABLE;
@@ -0,0 +1,56 @@
+-- Метрики доходности и риска стратегии по закрытым сделкам мастера.
+-- Считаются воркером приложения; проценты хранятся в процентах (12.5 = 12,5%).
+CREATE TABLE strategy_metrics (
+    strategy_id      BIGINT PRIMARY KEY,
+    capital          NUMERIC(18,2),
+    closed_trades    INTEGER NOT NULL DEFAULT 0 CHECK (closed_trades >= 0),
+    total_profit     NUMERIC(18,2) NOT NULL DEFAULT 0,
+    roi              NUMERIC(14,4),
+    max_drawdown_pct NUMERIC(8,4) CHECK (max_drawdown_pct >= 0),
+    sharpe_ratio     NUMERIC(14,4),
+    sortino_ratio    NUMERIC(14,4),
+    win_rate         NUMERIC(7,4) CHECK (win_rate BETWEEN 0 AND 100),
+    profit_factor    NUMERIC(14,4) CHECK (profit_factor >= 0),
+    risk_score       SMALLINT CHECK (risk_score BETWEEN 1 AND 10),
+    last_trade_close TIMESTAMPTZ,
+    computed_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_strategy_metrics_strategy
+        FOREIGN KEY (strategy_id)
+        REFERENCES strategies (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_strategy_metrics_roi ON strategy_metrics(roi DESC);
+CREATE INDEX idx_strategy_metrics_max_drawdown_pct ON strategy_metrics(max_drawdown_pct);
+CREATE INDEX idx_strategy_metrics_risk_score ON strategy_metrics(risk_score);
+
+-- Поиск закрытых сделок стратегии для пересчёта метрик
+CREATE INDEX idx_trades_strategy_closed ON trades(strategy_id, close_time) WHERE close_time IS NOT NULL;
+
+-- Представление дополняется метриками; для стратегий без статистики счётчики равны нулю
+DROP VIEW IF EXISTS vw_strategy_performance;
+
+CREATE VIEW vw_strategy_performance AS
+SELECT
+    s.id                                   AS strategy_id,
+    s.title,
+    s.status,
+    COALESCE(ss.total_subscriptions, 0)    AS total_subscriptions,
+    COALESCE(ss.active_subscriptions, 0)   AS active_subscriptions,
+    COALESCE(ss.total_copied_trades, 0)    AS total_copied_trades,
+    COALESCE(ss.total_profit, 0)           AS total_profit,
+    COALESCE(ss.total_commissions, 0)      AS total_commissions,
+    COALESCE(ss.updated_at, s.updated_at)  AS updated_at,
+    m.roi,
+    m.max_drawdown_pct,
+    m.sharpe_ratio,
+    m.sortino_ratio,
+    m.win_rate,
+    m.profit_factor,
+    m.risk_score,
+    m.computed_at                          AS metrics_updated_at
+FROM strategies s
+LEFT JOIN strategy_stats ss ON ss.strategy_id = s.id
+LEFT JOIN strategy_metrics m ON m.strategy_id = s.id;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trades_equity_rollup_trg ON trades;
+DROP FUNCTION IF EXISTS trg_trades_equity_rollup();
+
+DROP TABLE IF EXISTS strategy_equity_daily;
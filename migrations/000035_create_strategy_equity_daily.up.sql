@@ -0,0 +1,63 @@
+-- Дневной свод закрытых сделок мастера для кривой капитала стратегии.
+-- День — дата закрытия сделки в UTC; недели и месяцы собираются из дней при запросе.
+CREATE TABLE strategy_equity_daily (
+    strategy_id  BIGINT NOT NULL,
+    day          DATE NOT NULL,
+    profit       NUMERIC(18,2) NOT NULL DEFAULT 0,
+    trades_count INTEGER NOT NULL DEFAULT 0 CHECK (trades_count >= 0),
+
+    PRIMARY KEY (strategy_id, day),
+
+    CONSTRAINT fk_strategy_equity_daily_strategy
+        FOREIGN KEY (strategy_id)
+        REFERENCES strategies (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+-- Свод обновляется приращениями: вклад старой версии сделки вычитается, новой — прибавляется
+CREATE OR REPLACE FUNCTION trg_trades_equity_rollup()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_day DATE;
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND OLD.strategy_id = NEW.strategy_id
+       AND OLD.close_time IS NOT DISTINCT FROM NEW.close_time
+       AND OLD.profit IS NOT DISTINCT FROM NEW.profit THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.close_time IS NOT NULL AND OLD.profit IS NOT NULL THEN
+        v_day := (OLD.close_time AT TIME ZONE 'UTC')::DATE;
+
+        UPDATE strategy_equity_daily
+        SET profit = profit - OLD.profit,
+            trades_count = trades_count - 1
+        WHERE strategy_id = OLD.strategy_id AND day = v_day;
+
+        DELETE FROM strategy_equity_daily
+        WHERE strategy_id = OLD.strategy_id AND day = v_day AND trades_count = 0;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.close_time IS NOT NULL AND NEW.profit IS NOT NULL THEN
+        INSERT INTO strategy_equity_daily AS d (strategy_id, day, profit, trades_count)
+        VALUES (NEW.strategy_id, (NEW.close_time AT TIME ZONE 'UTC')::DATE, NEW.profit, 1)
+        ON CONFLICT (strategy_id, day) DO UPDATE
+        SET profit = d.profit + EXCLUDED.profit,
+            trades_count = d.trades_count + 1;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trades_equity_rollup_trg
+AFTER INSERT OR UPDATE OR DELETE ON trades
+FOR EACH ROW EXECUTE FUNCTION trg_trades_equity_rollup();
+
+INSERT INTO strategy_equity_daily (strategy_id, day, profit, trades_count)
+SELECT strategy_id, (close_time AT TIME ZONE 'UTC')::DATE, SUM(profit), COUNT(*)
+FROM trades
+WHERE close_time IS NOT NULL AND profit IS NOT NULL
+GROUP BY strategy_id, (close_time AT TIME ZONE 'UTC')::DATE;
@@ -0,0 +1,47 @@
+DROP FUNCTION IF EXISTS fn_get_strategy_leaderboard(CHAR(3), TEXT, TIMESTAMPTZ, TIMESTAMPTZ, strategy_status);
+
+CREATE OR REPLACE FUNCTION fn_get_strategy_leaderboard(
+    p_limit    INT,
+    p_currency CHAR(3)
+)
+RETURNS TABLE (
+    strategy_id        BIGINT,
+    title              TEXT,
+    total_profit       NUMERIC(18,2),
+    total_commissions  NUMERIC(18,2),
+    active_subscriptions INT
+) AS $$
+BEGIN
+    RETURN QUERY
+    WITH profits AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(ct.profit, a.currency, p_currency, COALESCE(ct.close_time, now()))) AS amount
+        FROM copied_trades ct
+        JOIN subscriptions sub ON sub.id = ct.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = ct.investor_account_id
+        WHERE ct.profit IS NOT NULL
+        GROUP BY o.strategy_id
+    ),
+    fees AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(c.amount, a.currency, p_currency, COALESCE(c.period_to, c.created_at))) AS amount
+        FROM commissions c
+        JOIN subscriptions sub ON sub.id = c.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = sub.investor_account_id
+        GROUP BY o.strategy_id
+    )
+    SELECT
+        sp.strategy_id,
+        sp.title,
+        ROUND(COALESCE(p.amount, 0), 2)::NUMERIC(18,2),
+        ROUND(COALESCE(f.amount, 0), 2)::NUMERIC(18,2),
+        COALESCE(sp.active_subscriptions, 0)
+    FROM vw_strategy_performance sp
+    LEFT JOIN profits p ON p.id = sp.strategy_id
+    LEFT JOIN fees f ON f.id = sp.strategy_id
+    ORDER BY COALESCE(p.amount, 0) DESC
+    LIMIT p_limit;
+END;
+$$ LANGUAGE plpgsql STABLE;
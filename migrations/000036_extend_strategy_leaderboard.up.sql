@@ -0,0 +1,110 @@
+DROP FUNCTION IF EXISTS fn_get_strategy_leaderboard(INT, CHAR(3));
+
+-- Рейтинг стратегий за окно [p_from, p_to); p_from = NULL — с начала истории.
+-- В окно попадают сделки, закрытые в нём, и комиссии по концу периода начисления;
+-- активные подписки и стратегии учитываются, если созданы до p_to.
+-- profit и commissions пересчитываются в p_currency; roi — прибыль сделок мастера
+-- к капиталу из strategy_metrics, %; risk_adjusted — roi, делённый на risk_score.
+-- Места нумеруются без пропусков по p_rank_by, при равенстве — по ID стратегии.
+CREATE OR REPLACE FUNCTION fn_get_strategy_leaderboard(
+    p_currency CHAR(3),
+    p_rank_by  TEXT,
+    p_from     TIMESTAMPTZ,
+    p_to       TIMESTAMPTZ,
+    p_status   strategy_status DEFAULT NULL
+)
+RETURNS TABLE (
+    strategy_id          BIGINT,
+    title                TEXT,
+    status               strategy_status,
+    total_profit         NUMERIC(18,2),
+    roi                  NUMERIC,
+    total_commissions    NUMERIC(18,2),
+    active_subscriptions INT,
+    risk_score           SMALLINT,
+    risk_adjusted        NUMERIC,
+    rank                 INT
+) AS $$
+#variable_conflict use_column
+BEGIN
+    RETURN QUERY
+    WITH candidates AS (
+        SELECT s.id, s.title, s.status, m.capital, m.risk_score
+        FROM strategies s
+        LEFT JOIN strategy_metrics m ON m.strategy_id = s.id
+        WHERE s.created_at < p_to
+          AND (p_status IS NULL OR s.status = p_status)
+    ),
+    profits AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(ct.profit, a.currency, p_currency, ct.close_time)) AS amount
+        FROM copied_trades ct
+        JOIN subscriptions sub ON sub.id = ct.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = ct.investor_account_id
+        WHERE ct.profit IS NOT NULL
+          AND ct.close_time < p_to
+          AND (p_from IS NULL OR ct.close_time >= p_from)
+        GROUP BY o.strategy_id
+    ),
+    master_profits AS (
+        SELECT t.strategy_id AS id, SUM(t.profit) AS amount
+        FROM trades t
+        WHERE t.close_time < p_to
+          AND (p_from IS NULL OR t.close_time >= p_from)
+        GROUP BY t.strategy_id
+    ),
+    fees AS (
+        SELECT o.strategy_id AS id,
+               SUM(fn_fx_convert(c.amount, a.currency, p_currency, COALESCE(c.period_to, c.created_at))) AS amount
+        FROM commissions c
+        JOIN subscriptions sub ON sub.id = c.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        JOIN accounts a ON a.id = sub.investor_account_id
+        WHERE COALESCE(c.period_to, c.created_at) < p_to
+          AND (p_from IS NULL OR COALESCE(c.period_to, c.created_at) >= p_from)
+        GROUP BY o.strategy_id
+    ),
+    subs AS (
+        SELECT o.strategy_id AS id, COUNT(*)::INT AS cnt
+        FROM subscriptions sub
+        JOIN offers o ON o.id = sub.offer_id
+        WHERE sub.status = 'active'
+          AND sub.created_at < p_to
+        GROUP BY o.strategy_id
+    ),
+    scored AS (
+        SELECT c.id, c.title, c.status, c.risk_score,
+               ROUND(COALESCE(p.amount, 0), 2)::NUMERIC(18,2) AS profit,
+               ROUND(COALESCE(mp.amount, 0) / NULLIF(c.capital, 0) * 100, 4) AS roi,
+               ROUND(COALESCE(f.amount, 0), 2)::NUMERIC(18,2) AS commissions,
+               COALESCE(sb.cnt, 0) AS subscriptions
+        FROM candidates c
+        LEFT JOIN profits p ON p.id = c.id
+        LEFT JOIN master_profits mp ON mp.id = c.id
+        LEFT JOIN fees f ON f.id = c.id
+        LEFT JOIN subs sb ON sb.id = c.id
+    )
+    SELECT
+        sc.id,
+        sc.title,
+        sc.status,
+        sc.profit,
+        sc.roi,
+        sc.commissions,
+        sc.subscriptions,
+        sc.risk_score,
+        ROUND(sc.roi / NULLIF(sc.risk_score, 0), 4),
+        (ROW_NUMBER() OVER (
+            ORDER BY CASE p_rank_by
+                         WHEN 'roi' THEN sc.roi
+                         WHEN 'active_subscriptions' THEN sc.subscriptions
+                         WHEN 'commissions' THEN sc.commissions
+                         WHEN 'risk_adjusted' THEN sc.roi / NULLIF(sc.risk_score, 0)
+                         ELSE sc.profit
+                     END DESC NULLS LAST,
+                     sc.id
+        ))::INT
+    FROM scored sc;
+END;
+$$ LANGUAGE plpgsql STABLE;
@@ -0,0 +1,115 @@
+DROP TRIGGER IF EXISTS subscriptions_enqueue_stats_ins_trg ON subscriptions;
+DROP TRIGGER IF EXISTS subscriptions_enqueue_stats_upd_trg ON subscriptions;
+DROP TRIGGER IF EXISTS subscriptions_enqueue_stats_del_trg ON subscriptions;
+DROP TRIGGER IF EXISTS copied_trades_enqueue_stats_ins_trg ON copied_trades;
+DROP TRIGGER IF EXISTS copied_trades_enqueue_stats_upd_trg ON copied_trades;
+DROP TRIGGER IF EXISTS copied_trades_enqueue_stats_del_trg ON copied_trades;
+DROP TRIGGER IF EXISTS commissions_enqueue_stats_ins_trg ON commissions;
+DROP TRIGGER IF EXISTS commissions_enqueue_stats_upd_trg ON commissions;
+DROP TRIGGER IF EXISTS commissions_enqueue_stats_del_trg ON commissions;
+
+DROP FUNCTION IF EXISTS trg_subscription_rows_enqueue_stats();
+DROP FUNCTION IF EXISTS trg_subscriptions_enqueue_stats();
+
+-- Стратегии, которые воркер не успел пересчитать
+SELECT fn_refresh_strategy_stats(strategy_id) FROM strategy_stats_queue;
+
+DROP TABLE IF EXISTS strategy_stats_queue;
+
+
+CREATE OR REPLACE FUNCTION trg_subscriptions_refresh_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_strategy_id BIGINT;
+    v_offer_id BIGINT;
+BEGIN
+
+    IF TG_OP = 'DELETE' THEN
+        v_offer_id := OLD.offer_id;
+    ELSE
+        v_offer_id := NEW.offer_id;
+    END IF;
+
+
+    SELECT strategy_id INTO v_strategy_id
+    FROM offers
+    WHERE id = v_offer_id;
+
+    IF v_strategy_id IS NOT NULL THEN
+        PERFORM fn_refresh_strategy_stats(v_strategy_id);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE OR REPLACE FUNCTION trg_copied_trades_refresh_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_strategy_id BIGINT;
+    v_subscription_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_subscription_id := OLD.subscription_id;
+    ELSE
+        v_subscription_id := NEW.subscription_id;
+    END IF;
+
+    v_strategy_id := fn_get_strategy_id_by_subscription(v_subscription_id);
+
+    IF v_strategy_id IS NOT NULL THEN
+        PERFORM fn_refresh_strategy_stats(v_strategy_id);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE OR REPLACE FUNCTION trg_commissions_refresh_stats()
+RETURNS TRIGGER AS $$
+DECLARE
+    v_strategy_id BIGINT;
+    v_subscription_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        v_subscription_id := OLD.subscription_id;
+    ELSE
+        v_subscription_id := NEW.subscription_id;
+    END IF;
+
+    v_strategy_id := fn_get_strategy_id_by_subscription(v_subscription_id);
+
+    IF v_strategy_id IS NOT NULL THEN
+        PERFORM fn_refresh_strategy_stats(v_strategy_id);
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    ELSE
+        RETURN NEW;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER subscriptions_refresh_stats_trg
+AFTER INSERT OR UPDATE OR DELETE ON subscriptions
+FOR EACH ROW EXECUTE FUNCTION trg_subscriptions_refresh_stats();
+
+CREATE TRIGGER copied_trades_refresh_stats_trg
+AFTER INSERT OR UPDATE OR DELETE ON copied_trades
+FOR EACH ROW EXECUTE FUNCTION trg_copied_trades_refresh_stats();
+
+CREATE TRIGGER commissions_refresh_stats_trg
+AFTER INSERT OR UPDATE OR DELETE ON commissions
+FOR EACH ROW EXECUTE FUNCTION trg_commissions_refresh_stats();
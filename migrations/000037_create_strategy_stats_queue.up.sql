@@ -0,0 +1,127 @@
+-- Очередь стратегий, у которых изменились подписки, скопированные сделки или комиссии.
+-- Строковые триггеры с полным пересчётом заменяются триггерами на оператор: они только
+-- отмечают стратегии, а strategy_stats пересчитывает воркер приложения.
+CREATE TABLE strategy_stats_queue (
+    strategy_id  BIGINT PRIMARY KEY,
+    enqueued_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_strategy_stats_queue_strategy
+        FOREIGN KEY (strategy_id)
+        REFERENCES strategies (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_strategy_stats_queue_enqueued_at ON strategy_stats_queue(enqueued_at);
+
+DROP TRIGGER IF EXISTS subscriptions_refresh_stats_trg ON subscriptions;
+DROP TRIGGER IF EXISTS copied_trades_refresh_stats_trg ON copied_trades;
+DROP TRIGGER IF EXISTS commissions_refresh_stats_trg ON commissions;
+
+DROP FUNCTION IF EXISTS trg_subscriptions_refresh_stats();
+DROP FUNCTION IF EXISTS trg_copied_trades_refresh_stats();
+DROP FUNCTION IF EXISTS trg_commissions_refresh_stats();
+
+
+-- Строка очереди обновляется, а не пропускается: блокировка держится до конца транзакции,
+-- и воркер (FOR UPDATE SKIP LOCKED) не заберёт стратегию раньше, чем изменения станут видны.
+-- Стратегии блокируются по возрастанию ID, чтобы параллельные транзакции не взаимоблокировались.
+CREATE OR REPLACE FUNCTION trg_subscriptions_enqueue_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO strategy_stats_queue (strategy_id)
+        SELECT DISTINCT o.strategy_id
+        FROM old_rows r
+        JOIN offers o ON o.id = r.offer_id
+        ORDER BY o.strategy_id
+        ON CONFLICT (strategy_id) DO UPDATE SET enqueued_at = strategy_stats_queue.enqueued_at;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO strategy_stats_queue (strategy_id)
+        SELECT DISTINCT o.strategy_id
+        FROM new_rows r
+        JOIN offers o ON o.id = r.offer_id
+        ORDER BY o.strategy_id
+        ON CONFLICT (strategy_id) DO UPDATE SET enqueued_at = strategy_stats_queue.enqueued_at;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- Общая функция для copied_trades и commissions: стратегия определяется по subscription_id
+CREATE OR REPLACE FUNCTION trg_subscription_rows_enqueue_stats()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO strategy_stats_queue (strategy_id)
+        SELECT DISTINCT o.strategy_id
+        FROM old_rows r
+        JOIN subscriptions sub ON sub.id = r.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        ORDER BY o.strategy_id
+        ON CONFLICT (strategy_id) DO UPDATE SET enqueued_at = strategy_stats_queue.enqueued_at;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO strategy_stats_queue (strategy_id)
+        SELECT DISTINCT o.strategy_id
+        FROM new_rows r
+        JOIN subscriptions sub ON sub.id = r.subscription_id
+        JOIN offers o ON o.id = sub.offer_id
+        ORDER BY o.strategy_id
+        ON CONFLICT (strategy_id) DO UPDATE SET enqueued_at = strategy_stats_queue.enqueued_at;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+
+CREATE TRIGGER subscriptions_enqueue_stats_ins_trg
+AFTER INSERT ON subscriptions
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscriptions_enqueue_stats();
+
+CREATE TRIGGER subscriptions_enqueue_stats_upd_trg
+AFTER UPDATE ON subscriptions
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscriptions_enqueue_stats();
+
+CREATE TRIGGER subscriptions_enqueue_stats_del_trg
+AFTER DELETE ON subscriptions
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscriptions_enqueue_stats();
+
+CREATE TRIGGER copied_trades_enqueue_stats_ins_trg
+AFTER INSERT ON copied_trades
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
+
+CREATE TRIGGER copied_trades_enqueue_stats_upd_trg
+AFTER UPDATE ON copied_trades
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
+
+CREATE TRIGGER copied_trades_enqueue_stats_del_trg
+AFTER DELETE ON copied_trades
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
+
+CREATE TRIGGER commissions_enqueue_stats_ins_trg
+AFTER INSERT ON commissions
+REFERENCING NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
+
+CREATE TRIGGER commissions_enqueue_stats_upd_trg
+AFTER UPDATE ON commissions
+REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
+
+CREATE TRIGGER commissions_enqueue_stats_del_trg
+AFTER DELETE ON commissions
+REFERENCING OLD TABLE AS old_rows
+FOR EACH STATEMENT EXECUTE FUNCTION trg_subscription_rows_enqueue_stats();
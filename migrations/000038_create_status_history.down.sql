@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS subscriptions_status_history_trg ON subscriptions;
+DROP TRIGGER IF EXISTS strategies_status_history_trg ON strategies;
+DROP TRIGGER IF EXISTS offers_status_history_trg ON offers;
+
+DROP FUNCTION IF EXISTS trg_record_status_history();
+
+DROP TABLE IF EXISTS offer_status_history;
+DROP TABLE IF EXISTS strategy_status_history;
+DROP TABLE IF EXISTS subscription_status_history;
@@ -0,0 +1,141 @@
+-- История статусов подписок, стратегий и офферов. Записи создаёт триггер в той же
+-- транзакции, что и смену статуса; причина и автор берутся из app.status_reason и
+-- app.current_user_id, как в audit_log.
+CREATE TABLE subscription_status_history (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT NOT NULL,
+    old_status       subscription_status NOT NULL,
+    new_status       subscription_status NOT NULL,
+    reason           TEXT,
+    changed_by       BIGINT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_subscription_status_history_subscription
+        FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_subscription_status_history_user
+        FOREIGN KEY (changed_by)
+        REFERENCES users (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE TABLE strategy_status_history (
+    id           BIGSERIAL PRIMARY KEY,
+    strategy_id  BIGINT NOT NULL,
+    old_status   strategy_status NOT NULL,
+    new_status   strategy_status NOT NULL,
+    reason       TEXT,
+    changed_by   BIGINT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_strategy_status_history_strategy
+        FOREIGN KEY (strategy_id)
+        REFERENCES strategies (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_strategy_status_history_user
+        FOREIGN KEY (changed_by)
+        REFERENCES users (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE TABLE offer_status_history (
+    id          BIGSERIAL PRIMARY KEY,
+    offer_id    BIGINT NOT NULL,
+    old_status  offer_status NOT NULL,
+    new_status  offer_status NOT NULL,
+    reason      TEXT,
+    changed_by  BIGINT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_offer_status_history_offer
+        FOREIGN KEY (offer_id)
+        REFERENCES offers (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE,
+
+    CONSTRAINT fk_offer_status_history_user
+        FOREIGN KEY (changed_by)
+        REFERENCES users (id)
+        ON UPDATE CASCADE
+        ON DELETE SET NULL
+);
+
+CREATE INDEX idx_subscription_status_history_subscription ON subscription_status_history(subscription_id, created_at DESC);
+CREATE INDEX idx_strategy_status_history_strategy ON strategy_status_history(strategy_id, created_at DESC);
+CREATE INDEX idx_offer_status_history_offer ON offer_status_history(offer_id, created_at DESC);
+
+
+-- Аргументы триггера: таблица истории и колонка со ссылкой на сущность
+CREATE OR REPLACE FUNCTION trg_record_status_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    EXECUTE format(
+        'INSERT INTO %I (%I, old_status, new_status, reason, changed_by) VALUES ($1, $2, $3, $4, $5)',
+        TG_ARGV[0], TG_ARGV[1]
+    )
+    USING NEW.id,
+          OLD.status,
+          NEW.status,
+          NULLIF(current_setting('app.status_reason', true), ''),
+          NULLIF(current_setting('app.current_user_id', true), '')::BIGINT;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER subscriptions_status_history_trg
+AFTER UPDATE OF status ON subscriptions
+FOR EACH ROW
+WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION trg_record_status_history('subscription_status_history', 'subscription_id');
+
+CREATE TRIGGER strategies_status_history_trg
+AFTER UPDATE OF status ON strategies
+FOR EACH ROW
+WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION trg_record_status_history('strategy_status_history', 'strategy_id');
+
+CREATE TRIGGER offers_status_history_trg
+AFTER UPDATE OF status ON offers
+FOR EACH ROW
+WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION trg_record_status_history('offer_status_history', 'offer_id');
+
+
+-- Прошлые смены статусов восстанавливаются из журнала аудита
+INSERT INTO subscription_status_history (subscription_id, old_status, new_status, reason, changed_by, created_at)
+SELECT s.id, (l.old_row ->> 'status')::subscription_status, (l.new_row ->> 'status')::subscription_status,
+       l.status_reason, l.changed_by, l.changed_at
+FROM audit_log l
+JOIN subscriptions s ON s.id::TEXT = l.entity_pk
+WHERE l.entity_name = 'subscriptions'
+  AND l.operation = 'update'
+  AND (l.old_row ->> 'status') IS DISTINCT FROM (l.new_row ->> 'status')
+ORDER BY l.id;
+
+INSERT INTO strategy_status_history (strategy_id, old_status, new_status, reason, changed_by, created_at)
+SELECT s.id, (l.old_row ->> 'status')::strategy_status, (l.new_row ->> 'status')::strategy_status,
+       l.status_reason, l.changed_by, l.changed_at
+FROM audit_log l
+JOIN strategies s ON s.id::TEXT = l.entity_pk
+WHERE l.entity_name = 'strategies'
+  AND l.operation = 'update'
+  AND (l.old_row ->> 'status') IS DISTINCT FROM (l.new_row ->> 'status')
+ORDER BY l.id;
+
+INSERT INTO offer_status_history (offer_id, old_status, new_status, reason, changed_by, created_at)
+SELECT o.id, (l.old_row ->> 'status')::offer_status, (l.new_row ->> 'status')::offer_status,
+       l.status_reason, l.changed_by, l.changed_at
+FROM audit_log l
+JOIN offers o ON o.id::TEXT = l.entity_pk
+WHERE l.entity_name = 'offers'
+  AND l.operation = 'update'
+  AND (l.old_row ->> 'status') IS DISTINCT FROM (l.new_row ->> 'status')
+ORDER BY l.id;
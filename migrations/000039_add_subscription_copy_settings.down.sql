@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_copied_trades_subscription_open;
+
+ALTER TABLE subscriptions
+    DROP CONSTRAINT IF EXISTS chk_subscriptions_symbol_lists,
+    DROP COLUMN IF EXISTS stop_copy_equity,
+    DROP COLUMN IF EXISTS max_open_positions,
+    DROP COLUMN IF EXISTS denied_symbols,
+    DROP COLUMN IF EXISTS allowed_symbols;
@@ -0,0 +1,13 @@
+-- Фильтры копирования подписки: списки разрешённых и запрещённых символов,
+-- лимит открытых позиций и порог эквити, ниже которого копирование останавливается.
+ALTER TABLE subscriptions
+    ADD COLUMN allowed_symbols    TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN denied_symbols     TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN max_open_positions INTEGER CHECK (max_open_positions > 0),
+    ADD COLUMN stop_copy_equity   NUMERIC(18,2) CHECK (stop_copy_equity > 0),
+    ADD CONSTRAINT chk_subscriptions_symbol_lists
+        CHECK (NOT (allowed_symbols && denied_symbols));
+
+CREATE INDEX idx_copied_trades_subscription_open
+    ON copied_trades (subscription_id)
+    WHERE close_time IS NULL;
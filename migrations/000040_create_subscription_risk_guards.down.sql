@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS copied_trades_enqueue_risk_upd_trg ON copied_trades;
+DROP TRIGGER IF EXISTS copied_trades_enqueue_risk_ins_trg ON copied_trades;
+
+DROP FUNCTION IF EXISTS trg_copied_trades_enqueue_risk();
+
+DROP TABLE IF EXISTS subscription_risk_events;
+DROP TABLE IF EXISTS subscription_risk_queue;
+
+ALTER TABLE subscriptions
+    DROP COLUMN IF EXISTS risk_min_equity,
+    DROP COLUMN IF EXISTS risk_max_daily_loss,
+    DROP COLUMN IF EXISTS risk_max_drawdown_pct;
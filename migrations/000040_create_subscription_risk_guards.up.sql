@@ -0,0 +1,68 @@
+-- Защитные лимиты инвестора. Лимиты проверяет воркер по очереди subscription_risk_queue,
+-- в которую триггер ставит подписку при закрытии её скопированной сделки с прибылью.
+-- При нарушении подписка приостанавливается, а нарушение пишется в subscription_risk_events.
+ALTER TABLE subscriptions
+    ADD COLUMN risk_max_drawdown_pct NUMERIC(5,2) CHECK (risk_max_drawdown_pct > 0 AND risk_max_drawdown_pct <= 100),
+    ADD COLUMN risk_max_daily_loss   NUMERIC(18,2) CHECK (risk_max_daily_loss > 0),
+    ADD COLUMN risk_min_equity       NUMERIC(18,2) CHECK (risk_min_equity > 0);
+
+-- Проверка, завершившаяся ошибкой, откладывается до next_attempt_at и после
+-- нескольких попыток снимается с очереди, чтобы не задерживать остальные
+CREATE TABLE subscription_risk_queue (
+    subscription_id  BIGINT PRIMARY KEY,
+    enqueued_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error       TEXT,
+
+    CONSTRAINT fk_subscription_risk_queue_subscription
+        FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_subscription_risk_queue_next_attempt_at ON subscription_risk_queue(next_attempt_at);
+
+CREATE TABLE subscription_risk_events (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  BIGINT NOT NULL,
+    guard            TEXT NOT NULL CHECK (guard IN ('max_drawdown', 'max_daily_loss', 'min_equity')),
+    limit_value      NUMERIC(18,4) NOT NULL,
+    actual_value     NUMERIC(18,4) NOT NULL,
+    equity           NUMERIC(18,2) NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    CONSTRAINT fk_subscription_risk_events_subscription
+        FOREIGN KEY (subscription_id)
+        REFERENCES subscriptions (id)
+        ON UPDATE CASCADE
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_subscription_risk_events_subscription_created_at
+    ON subscription_risk_events(subscription_id, created_at DESC);
+
+-- Закрытие копии при архивации подписки прибыль не фиксирует и в очередь не попадает
+CREATE OR REPLACE FUNCTION trg_copied_trades_enqueue_risk()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO subscription_risk_queue (subscription_id)
+    VALUES (NEW.subscription_id)
+    ON CONFLICT (subscription_id) DO UPDATE SET enqueued_at = subscription_risk_queue.enqueued_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER copied_trades_enqueue_risk_ins_trg
+    AFTER INSERT ON copied_trades
+    FOR EACH ROW
+    WHEN (NEW.close_time IS NOT NULL AND NEW.profit IS NOT NULL)
+    EXECUTE FUNCTION trg_copied_trades_enqueue_risk();
+
+CREATE TRIGGER copied_trades_enqueue_risk_upd_trg
+    AFTER UPDATE OF close_time, profit ON copied_trades
+    FOR EACH ROW
+    WHEN (NEW.close_time IS NOT NULL AND NEW.profit IS NOT NULL
+          AND (OLD.close_time IS NULL OR OLD.profit IS DISTINCT FROM NEW.profit))
+    EXECUTE FUNCTION trg_copied_trades_enqueue_risk();
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS commissions_ledger_trg ON commissions;
+DROP FUNCTION IF EXISTS trg_commissions_ledger();
+
+DROP FUNCTION IF EXISTS fn_ledger_post_commission(BIGINT, BIGINT, commission_type, NUMERIC, TIMESTAMPTZ);
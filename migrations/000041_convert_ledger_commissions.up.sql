@@ -0,0 +1,80 @@
+-- Комиссия начисляется в валюте счёта инвестора. Если счёт мастера в другой валюте,
+-- операция проходит через клиринговый счёт fx: у инвестора списывается сумма комиссии,
+-- мастеру зачисляется она же по курсу на дату начисления, и каждая валюта сходится в ноль
+-- отдельно. Без курса комиссия остаётся на fx до ручного разбора.
+CREATE OR REPLACE FUNCTION fn_ledger_post_commission(
+    p_commission_id   BIGINT,
+    p_subscription_id BIGINT,
+    p_type            commission_type,
+    p_amount          NUMERIC,
+    p_created_at      TIMESTAMPTZ
+)
+RETURNS BIGINT AS $$
+DECLARE
+    v_investor_account_id BIGINT;
+    v_investor_currency   CHAR(3);
+    v_master_account_id   BIGINT;
+    v_master_currency     CHAR(3);
+    v_master_amount       NUMERIC(18,2);
+    v_transaction_id      BIGINT;
+BEGIN
+    SELECT ia.id, ia.currency, ma.id, ma.currency
+    INTO v_investor_account_id, v_investor_currency, v_master_account_id, v_master_currency
+    FROM subscriptions s
+    JOIN accounts ia ON ia.id = s.investor_account_id
+    JOIN offers o ON o.id = s.offer_id
+    JOIN strategies st ON st.id = o.strategy_id
+    JOIN accounts ma ON ma.id = st.master_account_id
+    WHERE s.id = p_subscription_id;
+
+    IF v_investor_currency = v_master_currency THEN
+        RETURN fn_ledger_post('commission', v_investor_account_id, -p_amount, v_master_account_id, NULL,
+                              NULL, p_commission_id, p_type || ' fee', p_created_at);
+    END IF;
+
+    v_transaction_id := fn_ledger_post('commission', v_investor_account_id, -p_amount, NULL, 'fx',
+                                       NULL, p_commission_id, p_type || ' fee', p_created_at);
+    IF v_transaction_id IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    BEGIN
+        v_master_amount := round(fn_fx_convert(p_amount, v_investor_currency, v_master_currency, p_created_at), 2);
+    EXCEPTION WHEN SQLSTATE 'FX404' THEN
+        RAISE WARNING 'commission % stays on fx clearing: %', p_commission_id, SQLERRM;
+        RETURN v_transaction_id;
+    END;
+
+    IF v_master_amount <> 0 THEN
+        INSERT INTO ledger_entries (transaction_id, system_account, amount, created_at)
+        VALUES (v_transaction_id, 'fx', -v_master_amount, p_created_at);
+
+        INSERT INTO ledger_entries (transaction_id, account_id, amount, created_at)
+        VALUES (v_transaction_id, v_master_account_id, v_master_amount, p_created_at);
+    END IF;
+
+    RETURN v_transaction_id;
+END;
+$$ LANGUAGE plpgsql;
+
+
+-- Комиссия списывается со счёта инвестора и зачисляется на счёт мастера стратегии
+CREATE OR REPLACE FUNCTION trg_commissions_ledger()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM fn_ledger_post_commission(NEW.id, NEW.subscription_id, NEW.type, NEW.amount, NEW.created_at);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER commissions_ledger_trg
+AFTER INSERT ON commissions
+FOR EACH ROW EXECUTE FUNCTION trg_commissions_ledger();
+
+
+-- Перенос истории начисленных комиссий
+SELECT fn_ledger_post_commission(c.id, c.subscription_id, c.type, c.amount, c.created_at)
+FROM commissions c
+WHERE c.amount > 0
+  AND NOT EXISTS (SELECT 1 FROM ledger_transactions t WHERE t.commission_id = c.id)
+ORDER BY c.created_at, c.id;
@@ -0,0 +1,34 @@
+-- У инвестора не больше одной открытой подписки на оффер. Лишние открытые подписки
+-- архивируются: остаётся активная, а среди равных — самая ранняя.
+SELECT set_config('app.status_reason', 'duplicate_subscription', true);
+
+WITH archived AS (
+    UPDATE subscriptions s
+    SET status = 'archived',
+        updated_at = now()
+    FROM (
+        SELECT id,
+               row_number() OVER (
+                   PARTITION BY investor_user_id, offer_id
+                   ORDER BY status = 'active' DESC, id
+               ) AS rn
+        FROM subscriptions
+        WHERE status IN ('preparing', 'active', 'suspended')
+    ) dup
+    WHERE s.id = dup.id
+      AND dup.rn > 1
+    RETURNING s.id
+)
+-- Как и при архивации через API, открытые копии архивированных подписок закрываются
+-- с прибылью сделки мастера, пропорциональной объёму копии
+UPDATE copied_trades ct
+SET close_time = now(),
+    profit = ROUND((COALESCE(t.profit, 0) * ct.volume_lots / t.volume_lots)::NUMERIC, 2)
+FROM archived a, trades t
+WHERE ct.subscription_id = a.id
+  AND t.id = ct.trade_id
+  AND ct.close_time IS NULL;
+
+CREATE UNIQUE INDEX uq_subscriptions_open_offer
+    ON subscriptions (investor_user_id, offer_id)
+    WHERE status IN ('preparing', 'active', 'suspended');